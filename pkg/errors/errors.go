@@ -1,17 +1,250 @@
 package dserrors
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 
-var (
-	// ErrorBadDatasource is only returned when the plugin instance's type could not be asserted
-	ErrorBadDatasource = errors.New("instance from plugin context is not a Github Datasource")
-
-	// ErrorQueryTypeUnimplemented is returned when the client sends an unrecognized querytype
-	ErrorQueryTypeUnimplemented = errors.New("the query type provided is not implemented")
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
 
-	// ErrorQueryTypeMissing is returned when the client does not send a query type
-	ErrorQueryTypeMissing = errors.New("the query type was not provided in the URL")
+// Kind classifies an error by what the user can do about it
+type Kind string
 
-	// ErrorTimeFieldNotSupported is returned when a time field sent is not supported / recognized. This can be returned when querying for any data that has multiple time fields, like Issues and Pull Requests
-	ErrorTimeFieldNotSupported = errors.New("the selected time field is not supported")
+const (
+	// KindAccessDenied is returned when the credentials of the datasource may not call an API
+	KindAccessDenied Kind = "access_denied"
+	// KindNotFound is returned when an asset, property, alias or model does not exist
+	KindNotFound Kind = "not_found"
+	// KindThrottled is returned when SiteWise rejected a request because of its rate limits
+	KindThrottled Kind = "throttled"
+	// KindInvalidRequest is returned when SiteWise rejected the parameters of a request
+	KindInvalidRequest Kind = "invalid_request"
+	// KindValidation is returned when a query is rejected before any request is sent
+	KindValidation Kind = "validation"
+	// KindTimeout is returned when a request did not complete in time
+	KindTimeout Kind = "timeout"
+	// KindUnknown is any other error
+	KindUnknown Kind = "unknown"
 )
+
+// Resource names the asset, property, alias or model of a request
+type Resource struct {
+	AssetId      string
+	PropertyId   string
+	Alias        string
+	AssetModelId string
+	// Entries is the number of entries of a batch request
+	Entries int
+}
+
+func (r Resource) String() string {
+	var parts []string
+	if r.AssetModelId != "" {
+		parts = append(parts, fmt.Sprintf("asset model %q", r.AssetModelId))
+	}
+	if r.AssetId != "" {
+		parts = append(parts, fmt.Sprintf("asset %q", r.AssetId))
+	}
+	if r.PropertyId != "" {
+		parts = append(parts, fmt.Sprintf("property %q", r.PropertyId))
+	}
+	if r.Alias != "" {
+		parts = append(parts, fmt.Sprintf("property alias %q", r.Alias))
+	}
+	if r.Entries > 0 {
+		parts = append(parts, fmt.Sprintf("%d entries", r.Entries))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// APIError is the error of a SiteWise API call, along with the resource the call was about
+type APIError struct {
+	Operation string
+	Resource  Resource
+	Err       error
+}
+
+func (e *APIError) Error() string {
+	operation := e.Operation
+	if operation == "" {
+		operation = "SiteWise request"
+	}
+	resource := e.Resource.String()
+	of := ""
+	if resource != "" {
+		of = " for " + resource
+	}
+
+	switch KindOf(e.Err) {
+	case KindAccessDenied:
+		return fmt.Sprintf("access denied to %s%s: check the IAM permissions of the datasource credentials (%s)", operation, of, awsMessage(e.Err))
+	case KindNotFound:
+		if resource == "" {
+			return fmt.Sprintf("%s: not found (%s)", operation, awsMessage(e.Err))
+		}
+		return fmt.Sprintf("%s was not found by %s: check the query for typos (%s)", resource, operation, awsMessage(e.Err))
+	case KindThrottled:
+		return fmt.Sprintf("%s%s was throttled by SiteWise: try again later or lower the request rate", operation, of)
+	case KindInvalidRequest:
+		return fmt.Sprintf("%s%s was rejected as invalid: %s", operation, of, awsMessage(e.Err))
+	default:
+		return fmt.Sprintf("%s%s failed: %s", operation, of, awsMessage(e.Err))
+	}
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned for queries that are rejected before any request is sent
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid query: " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validation marks an error as a problem of the query
+func Validation(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ValidationError{Err: err}
+}
+
+// Validationf returns a ValidationError with a formatted message
+func Validationf(format string, args ...any) error {
+	return &ValidationError{Err: fmt.Errorf(format, args...)}
+}
+
+// KindOf classifies an error
+func KindOf(err error) Kind {
+	if err == nil {
+		return ""
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return KindValidation
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindTimeout
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "AccessDeniedException", "UnauthorizedException", "UnrecognizedClientException",
+			"InvalidSignatureException", "ExpiredTokenException":
+			return KindAccessDenied
+		case "ResourceNotFoundException":
+			return KindNotFound
+		case "ThrottlingException", "TooManyRequestsException":
+			return KindThrottled
+		case "InvalidRequestException", "ValidationException":
+			return KindInvalidRequest
+		}
+	}
+
+	var respErr *smithyhttp.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.HTTPStatusCode() {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return KindAccessDenied
+		case http.StatusNotFound:
+			return KindNotFound
+		case http.StatusTooManyRequests:
+			return KindThrottled
+		case http.StatusBadRequest:
+			return KindInvalidRequest
+		}
+	}
+	return KindUnknown
+}
+
+// Source tells whether an error was caused by SiteWise or by the plugin. Errors of queries that
+// the plugin rejected itself, and errors that are not of SiteWise, are plugin errors.
+func Source(err error) backend.ErrorSource {
+	if KindOf(err) == KindValidation {
+		return backend.ErrorSourcePlugin
+	}
+	if isAPIError(err) || backend.IsDownstreamError(err) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return backend.ErrorSourceDownstream
+	}
+	return backend.ErrorSourcePlugin
+}
+
+// Status returns the status of the response of a query that failed with an error
+func Status(err error) backend.Status {
+	switch KindOf(err) {
+	case KindAccessDenied:
+		return backend.StatusForbidden
+	case KindNotFound:
+		return backend.StatusNotFound
+	case KindThrottled:
+		return backend.StatusTooManyRequests
+	case KindInvalidRequest:
+		return backend.StatusBadRequest
+	case KindValidation:
+		return backend.StatusValidationFailed
+	case KindTimeout:
+		return backend.StatusTimeout
+	}
+	if Source(err) == backend.ErrorSourceDownstream {
+		return backend.StatusBadGateway
+	}
+	return backend.StatusInternal
+}
+
+// Response returns the response of a query that failed with an error, with a message that names
+// the resource of the failed request and the error source and status set
+func Response(err error) backend.DataResponse {
+	return backend.DataResponse{
+		Error:       Wrap(err),
+		ErrorSource: Source(err),
+		Status:      Status(err),
+	}
+}
+
+// Wrap returns the APIError of an error of a SiteWise API call, so that its message tells the
+// kind of the error without the operation error prefix of the AWS SDK
+func Wrap(err error) error {
+	var e *APIError
+	if errors.As(err, &e) {
+		return e
+	}
+	if !isAPIError(err) {
+		return err
+	}
+	e = &APIError{Err: err}
+	var opErr *smithy.OperationError
+	if errors.As(err, &opErr) {
+		e.Operation = opErr.Operation()
+	}
+	return e
+}
+
+func isAPIError(err error) bool {
+	var e *APIError
+	var apiErr smithy.APIError
+	var respErr *smithyhttp.ResponseError
+	return errors.As(err, &e) || errors.As(err, &apiErr) || errors.As(err, &respErr)
+}
+
+// awsMessage returns the message of an AWS error, without the operation and request id
+func awsMessage(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorMessage() != "" {
+		return apiErr.ErrorMessage()
+	}
+	return err.Error()
+}
@@ -0,0 +1,111 @@
+package dserrors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/require"
+)
+
+func responseError(status int, err error) error {
+	return &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+		Err:      err,
+	}
+}
+
+func TestResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		kind    Kind
+		source  backend.ErrorSource
+		status  backend.Status
+		message string
+	}{
+		{
+			name: "access denied",
+			err: &APIError{
+				Operation: "DescribeAsset",
+				Resource:  Resource{AssetId: "asset-1"},
+				Err:       responseError(http.StatusForbidden, &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not authorized"}),
+			},
+			kind:    KindAccessDenied,
+			source:  backend.ErrorSourceDownstream,
+			status:  backend.StatusForbidden,
+			message: `access denied to DescribeAsset for asset "asset-1": check the IAM permissions of the datasource credentials (not authorized)`,
+		},
+		{
+			name: "alias not found",
+			err: &APIError{
+				Operation: "DescribeTimeSeries",
+				Resource:  Resource{Alias: "/turbine/1/speed"},
+				Err:       responseError(http.StatusNotFound, &iotsitewisetypes.ResourceNotFoundException{Message: aws.String("Time series not found")}),
+			},
+			kind:    KindNotFound,
+			source:  backend.ErrorSourceDownstream,
+			status:  backend.StatusNotFound,
+			message: `property alias "/turbine/1/speed" was not found by DescribeTimeSeries: check the query for typos (Time series not found)`,
+		},
+		{
+			name: "throttled",
+			err: &APIError{
+				Operation: "BatchGetAssetPropertyValueHistory",
+				Resource:  Resource{Entries: 16},
+				Err:       &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"},
+			},
+			kind:    KindThrottled,
+			source:  backend.ErrorSourceDownstream,
+			status:  backend.StatusTooManyRequests,
+			message: "BatchGetAssetPropertyValueHistory for 16 entries was throttled by SiteWise: try again later or lower the request rate",
+		},
+		{
+			name:    "invalid request without an operation",
+			err:     &iotsitewisetypes.InvalidRequestException{Message: aws.String("Interval is too small")},
+			kind:    KindInvalidRequest,
+			source:  backend.ErrorSourceDownstream,
+			status:  backend.StatusBadRequest,
+			message: "SiteWise request was rejected as invalid: Interval is too small",
+		},
+		{
+			name:    "validation",
+			err:     Validationf("too many entries: %d", 200),
+			kind:    KindValidation,
+			source:  backend.ErrorSourcePlugin,
+			status:  backend.StatusBadRequest,
+			message: "invalid query: too many entries: 200",
+		},
+		{
+			name:    "timeout",
+			err:     context.DeadlineExceeded,
+			kind:    KindTimeout,
+			source:  backend.ErrorSourceDownstream,
+			status:  backend.StatusTimeout,
+			message: "context deadline exceeded",
+		},
+		{
+			name:    "plugin",
+			err:     errors.New("unexpected frame"),
+			kind:    KindUnknown,
+			source:  backend.ErrorSourcePlugin,
+			status:  backend.StatusInternal,
+			message: "unexpected frame",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.kind, KindOf(tt.err))
+			res := Response(tt.err)
+			require.Equal(t, tt.source, res.ErrorSource)
+			require.Equal(t, tt.status, res.Status)
+			require.EqualError(t, res.Error, tt.message)
+		})
+	}
+}
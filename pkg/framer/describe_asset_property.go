@@ -2,14 +2,118 @@ package framer
 
 import (
 	"context"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/resource"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 )
 
 type AssetProperty iotsitewise.DescribeAssetPropertyOutput
 
 func (ap AssetProperty) Frames(ctx context.Context, resources resource.ResourceProvider) (data.Frames, error) {
-	panic("implement me!!!")
+	return AssetPropertyDescriptions{ap}.Frames(ctx, resources)
+}
+
+// AssetPropertyDescriptions frames one row per described property.
+// Properties of composite models (components) include the composite model they belong to.
+type AssetPropertyDescriptions []AssetProperty
+
+type describeAssetPropertyFields struct {
+	AssetId            *data.Field
+	AssetName          *data.Field
+	Id                 *data.Field
+	Name               *data.Field
+	Alias              *data.Field
+	DataType           *data.Field
+	Unit               *data.Field
+	NotificationState  *data.Field
+	NotificationTopic  *data.Field
+	CompositeModelId   *data.Field
+	CompositeModelName *data.Field
+	Path               *data.Field
+}
+
+func (f *describeAssetPropertyFields) fields() data.Fields {
+	return data.Fields{
+		f.AssetId,
+		f.AssetName,
+		f.Id,
+		f.Name,
+		f.Alias,
+		f.DataType,
+		f.Unit,
+		f.NotificationState,
+		f.NotificationTopic,
+		f.CompositeModelId,
+		f.CompositeModelName,
+		f.Path,
+	}
+}
+
+func newDescribeAssetPropertyFields(length int) *describeAssetPropertyFields {
+	return &describeAssetPropertyFields{
+		AssetId:            fields.AssetIdField(length),
+		AssetName:          fields.AssetNameField(length),
+		Id:                 fields.IdField(length),
+		Name:               fields.NameField(length),
+		Alias:              fields.AliasField(length),
+		DataType:           fields.DataTypeField(length),
+		Unit:               fields.UnitField(length),
+		NotificationState:  fields.NotificationStateField(length),
+		NotificationTopic:  fields.NotificationTopicField(length),
+		CompositeModelId:   fields.CompositeModelIdField(length),
+		CompositeModelName: fields.CompositeModelNameField(length),
+		Path:               fields.PathField(length),
+	}
+}
+
+func (a AssetPropertyDescriptions) Frames(_ context.Context, _ resource.ResourceProvider) (data.Frames, error) {
+	propertyFields := newDescribeAssetPropertyFields(len(a))
+
+	for i, ap := range a {
+		output := iotsitewise.DescribeAssetPropertyOutput(ap)
+
+		var property *iotsitewisetypes.Property
+		if util.IsAssetProperty(&output) {
+			property = output.AssetProperty
+		} else if util.IsComponentProperty(&output) {
+			property = output.CompositeModel.AssetProperty
+			propertyFields.CompositeModelId.Set(i, util.Dereference(output.CompositeModel.Id))
+			propertyFields.CompositeModelName.Set(i, util.Dereference(output.CompositeModel.Name))
+		}
+
+		propertyFields.AssetId.Set(i, util.Dereference(ap.AssetId))
+		propertyFields.AssetName.Set(i, util.Dereference(ap.AssetName))
+		propertyFields.Name.Set(i, util.GetPropertyName(&output))
+		propertyFields.DataType.Set(i, string(util.GetPropertyDataType(&output)))
+		propertyFields.Unit.Set(i, util.GetPropertyUnit(&output))
+
+		if property == nil {
+			continue
+		}
+		propertyFields.Id.Set(i, util.Dereference(property.Id))
+		propertyFields.Alias.Set(i, util.Dereference(property.Alias))
+		propertyFields.Path.Set(i, getPropertyPath(property.Path))
+		if property.Notification != nil {
+			propertyFields.NotificationState.Set(i, string(property.Notification.State))
+			propertyFields.NotificationTopic.Set(i, util.Dereference(property.Notification.Topic))
+		}
+	}
+
+	frame := data.NewFrame("", propertyFields.fields()...)
+
+	return data.Frames{frame}, nil
+}
+
+// getPropertyPath joins the names of the path segments, e.g. Asset/Component/Property
+func getPropertyPath(path []iotsitewisetypes.AssetPropertyPathSegment) string {
+	names := make([]string, 0, len(path))
+	for _, segment := range path {
+		names = append(names, util.Dereference(segment.Name))
+	}
+	return strings.Join(names, "/")
 }
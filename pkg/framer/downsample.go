@@ -0,0 +1,169 @@
+package framer
+
+import (
+	"math"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+// Downsample reduces the rows of a frame of raw values to about target rows with the LTTB or
+// MINMAX algorithm, and returns the number of rows the frame had. The rows are picked by the
+// numeric fields of the frame, and a row that any of them picks is kept with all its fields, so
+// frames with several numeric fields may keep more rows. Frames without a time field or a numeric
+// field, or with no more than target rows, are left as they are. The first and last rows are
+// always kept.
+func Downsample(frame *data.Frame, algorithm string, target int) int {
+	rows := frame.Rows()
+	if target < 3 || rows <= target {
+		return rows
+	}
+
+	timeIndex := -1
+	for i, field := range frame.Fields {
+		if field.Type().Time() {
+			timeIndex = i
+			break
+		}
+	}
+	if timeIndex < 0 {
+		return rows
+	}
+	times := downsampleTimes(frame.Fields[timeIndex])
+
+	keep := make([]bool, rows)
+	numeric := false
+	for _, field := range frame.Fields {
+		if !field.Type().Numeric() {
+			continue
+		}
+		numeric = true
+		values := downsampleValues(field)
+		switch algorithm {
+		case models.DownsampleLTTB:
+			lttb(times, values, target, keep)
+		case models.DownsampleMinMax:
+			minMax(values, target, keep)
+		default:
+			return rows
+		}
+	}
+	if !numeric {
+		return rows
+	}
+	keep[0], keep[rows-1] = true, true
+
+	for i, field := range frame.Fields {
+		kept := data.NewFieldFromFieldType(field.Type(), 0)
+		kept.Name, kept.Labels, kept.Config = field.Name, field.Labels, field.Config
+		for row, ok := range keep {
+			if ok {
+				kept.Append(field.CopyAt(row))
+			}
+		}
+		frame.Fields[i] = kept
+	}
+	return rows
+}
+
+// downsampleTimes are the times of a time field in nanoseconds
+func downsampleTimes(field *data.Field) []float64 {
+	times := make([]float64, field.Len())
+	for i := range times {
+		switch t := field.At(i).(type) {
+		case time.Time:
+			times[i] = float64(t.UnixNano())
+		case *time.Time:
+			if t != nil {
+				times[i] = float64(t.UnixNano())
+			}
+		}
+	}
+	return times
+}
+
+// downsampleValues are the values of a numeric field, with NaN for null values
+func downsampleValues(field *data.Field) []float64 {
+	values := make([]float64, field.Len())
+	for i := range values {
+		v, err := field.NullableFloatAt(i)
+		if err != nil || v == nil {
+			values[i] = math.NaN()
+			continue
+		}
+		values[i] = *v
+	}
+	return values
+}
+
+// lttb marks the rows that the largest triangle three buckets algorithm keeps out of target rows.
+// The rows between the first and last are split into target-2 buckets, and each bucket keeps the
+// row that forms the largest triangle with the row kept before it and the average of the next
+// bucket.
+func lttb(times, values []float64, target int, keep []bool) {
+	rows := len(values)
+	every := float64(rows-2) / float64(target-2)
+	anchor := 0
+	for bucket := 0; bucket < target-2; bucket++ {
+		start, end := int(float64(bucket)*every)+1, int(float64(bucket+1)*every)+1
+		nextStart, nextEnd := end, min(int(float64(bucket+2)*every)+1, rows)
+
+		avgTime, avgValue, n := 0.0, 0.0, 0
+		for i := nextStart; i < nextEnd; i++ {
+			if !math.IsNaN(values[i]) {
+				avgTime, avgValue, n = avgTime+times[i], avgValue+values[i], n+1
+			}
+		}
+		if n == 0 {
+			avgTime, avgValue = times[rows-1], values[rows-1]
+		} else {
+			avgTime, avgValue = avgTime/float64(n), avgValue/float64(n)
+		}
+
+		picked, maxArea := -1, -1.0
+		for i := start; i < end; i++ {
+			if math.IsNaN(values[i]) {
+				continue
+			}
+			area := math.Abs((times[anchor]-avgTime)*(values[i]-values[anchor]) - (times[anchor]-times[i])*(avgValue-values[anchor]))
+			if math.IsNaN(area) {
+				area = 0
+			}
+			if area > maxArea {
+				picked, maxArea = i, area
+			}
+		}
+		if picked >= 0 {
+			keep[picked] = true
+			anchor = picked
+		}
+	}
+}
+
+// minMax marks the rows of the minimum and maximum of every bucket, two rows per bucket out of
+// target rows, so that the spikes of a series are kept
+func minMax(values []float64, target int, keep []bool) {
+	rows := len(values)
+	buckets := max((target-2)/2, 1)
+	every := float64(rows-2) / float64(buckets)
+	for bucket := 0; bucket < buckets; bucket++ {
+		start, end := int(float64(bucket)*every)+1, min(int(float64(bucket+1)*every)+1, rows-1)
+		lowest, highest := -1, -1
+		for i := start; i < end; i++ {
+			if math.IsNaN(values[i]) {
+				continue
+			}
+			if lowest < 0 || values[i] < values[lowest] {
+				lowest = i
+			}
+			if highest < 0 || values[i] > values[highest] {
+				highest = i
+			}
+		}
+		if lowest >= 0 {
+			keep[lowest], keep[highest] = true, true
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package framer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+// sineFrame is a frame of a sine wave of rows values with a spike at row spike
+func sineFrame(rows int, spike int) *data.Frame {
+	times := make([]time.Time, rows)
+	values := make([]float64, rows)
+	qualities := make([]string, rows)
+	for i := range rows {
+		times[i] = time.Unix(int64(i), 0)
+		values[i] = math.Sin(float64(i) / 50)
+		qualities[i] = "GOOD"
+	}
+	values[spike] = 100
+	return data.NewFrame("Turbine",
+		data.NewField("time", nil, times),
+		data.NewField("Speed", nil, values).SetConfig(&data.FieldConfig{Unit: "m/s"}),
+		data.NewField("quality", nil, qualities),
+	)
+}
+
+func TestDownsample(t *testing.T) {
+	for _, algorithm := range []string{models.DownsampleLTTB, models.DownsampleMinMax} {
+		t.Run(algorithm, func(t *testing.T) {
+			frame := sineFrame(10000, 4321)
+			require.Equal(t, 10000, Downsample(frame, algorithm, 500))
+			require.LessOrEqual(t, frame.Rows(), 500)
+			require.Greater(t, frame.Rows(), 400)
+
+			// the first and last rows and the spike are kept, with the other fields of their rows
+			require.Equal(t, time.Unix(0, 0), frame.Fields[0].At(0))
+			require.Equal(t, time.Unix(9999, 0), frame.Fields[0].At(frame.Rows()-1))
+			spike := false
+			for row := range frame.Rows() {
+				if frame.Fields[1].At(row) == 100.0 {
+					spike = true
+					require.Equal(t, time.Unix(4321, 0), frame.Fields[0].At(row))
+					require.Equal(t, "GOOD", frame.Fields[2].At(row))
+				}
+			}
+			require.True(t, spike)
+			require.Equal(t, "m/s", frame.Fields[1].Config.Unit)
+		})
+	}
+}
+
+func TestDownsampleLeavesFramesAsTheyAre(t *testing.T) {
+	t.Run("frames within the target", func(t *testing.T) {
+		frame := sineFrame(100, 50)
+		require.Equal(t, 100, Downsample(frame, models.DownsampleLTTB, 500))
+		require.Equal(t, 100, frame.Rows())
+	})
+
+	t.Run("frames without numeric fields", func(t *testing.T) {
+		times := make([]time.Time, 1000)
+		values := make([]string, 1000)
+		for i := range times {
+			times[i] = time.Unix(int64(i), 0)
+		}
+		frame := data.NewFrame("Turbine", data.NewField("time", nil, times), data.NewField("State", nil, values))
+		require.Equal(t, 1000, Downsample(frame, models.DownsampleMinMax, 100))
+		require.Equal(t, 1000, frame.Rows())
+	})
+}
+
+func TestDownsampleSkipsNullValues(t *testing.T) {
+	times := make([]time.Time, 1000)
+	values := make([]*float64, 1000)
+	for i := range times {
+		times[i] = time.Unix(int64(i), 0)
+		if i%2 == 0 {
+			values[i] = aws.Float64(float64(i))
+		}
+	}
+	frame := data.NewFrame("Turbine", data.NewField("time", nil, times), data.NewField("Speed", nil, values))
+	Downsample(frame, models.DownsampleMinMax, 100)
+	for row := 1; row < frame.Rows()-1; row++ {
+		require.NotNil(t, frame.Fields[1].At(row))
+	}
+}
@@ -0,0 +1,132 @@
+package framer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/aws/smithy-go"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// failedEntry is an entry of a batch request that returned no data
+type failedEntry struct {
+	entryId string
+	status  string
+	code    string
+	// message is the error message of an errored entry, or the reason an entry was skipped
+	message string
+}
+
+func errorEntry[C ~string](entryId *string, code C, message *string) failedEntry {
+	return failedEntry{
+		entryId: util.Dereference(entryId),
+		status:  models.EntryStatusError,
+		code:    string(code),
+		message: util.Dereference(message),
+	}
+}
+
+// apiErrorEntry is an entry whose request failed on its own
+func apiErrorEntry(entryId string, err error) failedEntry {
+	entry := failedEntry{entryId: entryId, status: models.EntryStatusError, message: err.Error()}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		entry.code = apiErr.ErrorCode()
+		entry.message = apiErr.ErrorMessage()
+	}
+	return entry
+}
+
+func skippedEntry[C ~string](entryId *string, completion iotsitewisetypes.BatchEntryCompletionStatus, code C) failedEntry {
+	entry := failedEntry{
+		entryId: util.Dereference(entryId),
+		status:  models.EntryStatusSkipped,
+		code:    string(code),
+	}
+	if entry.code != "" {
+		entry.message = fmt.Sprintf("skipped after the error %s", entry.code)
+	} else {
+		entry.message = fmt.Sprintf("skipped with the completion status %s", completion)
+	}
+	return entry
+}
+
+// batchEntries collects the outcome of the entries of batch responses
+type batchEntries struct {
+	succeeded map[string]bool
+	failed    []failedEntry
+}
+
+func newBatchEntries() *batchEntries {
+	return &batchEntries{succeeded: map[string]bool{}}
+}
+
+func (b *batchEntries) success(entryId *string) {
+	b.succeeded[util.Dereference(entryId)] = true
+}
+
+func (b *batchEntries) fail(entry failedEntry) {
+	b.failed = append(b.failed, entry)
+}
+
+// frames returns an empty frame with an error notice for each entry that returned no data. When
+// any entry returned no data, the summary of all entries is added to the first frame. An entry is
+// skipped on the pages after it completed, so the skipped entries that returned data are left out.
+func (b *batchEntries) frames(frames data.Frames, properties map[string]*iotsitewise.DescribeAssetPropertyOutput) data.Frames {
+	summary := &models.EntrySummary{Succeeded: len(b.succeeded)}
+	reported := map[string]bool{}
+	for _, e := range b.failed {
+		if b.succeeded[e.entryId] || reported[e.entryId] {
+			continue
+		}
+		reported[e.entryId] = true
+		if e.status == models.EntryStatusError {
+			summary.Errored++
+		} else {
+			summary.Skipped++
+		}
+		frames = append(frames, failedEntryFrame(properties[e.entryId], e))
+	}
+	summary.Entries = summary.Succeeded + summary.Errored + summary.Skipped
+	summary.Partial = summary.Succeeded > 0 && summary.Succeeded < summary.Entries
+
+	if summary.Entries > summary.Succeeded {
+		if frames[0].Meta == nil {
+			frames[0].Meta = &data.FrameMeta{}
+		}
+		meta, _ := frames[0].Meta.Custom.(models.SitewiseCustomMeta)
+		meta.Summary = summary
+		frames[0].Meta.Custom = meta
+	}
+	return frames
+}
+
+func failedEntryFrame(property *iotsitewise.DescribeAssetPropertyOutput, e failedEntry) *data.Frame {
+	name := e.entryId
+	if property != nil {
+		name = getFrameName(property)
+	}
+
+	text := fmt.Sprintf("%s: %s", name, e.message)
+	if e.status == models.EntryStatusError && e.code != "" {
+		text = fmt.Sprintf("%s: %s: %s", name, e.code, e.message)
+	}
+	severity := data.NoticeSeverityError
+	if e.status == models.EntryStatusSkipped {
+		severity = data.NoticeSeverityWarning
+	}
+
+	return data.NewFrame(name).SetMeta(&data.FrameMeta{
+		Custom: models.SitewiseCustomMeta{
+			EntryId:     e.entryId,
+			EntryStatus: e.status,
+			ErrorCode:   e.code,
+		},
+		Notices: []data.Notice{{Severity: severity, Text: text}},
+	})
+}
@@ -0,0 +1,86 @@
+package framer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/resource"
+)
+
+type propertiesProvider struct {
+	resource.ResourceProvider
+	properties map[string]*iotsitewise.DescribeAssetPropertyOutput
+}
+
+func (p propertiesProvider) Properties(context.Context) (map[string]*iotsitewise.DescribeAssetPropertyOutput, error) {
+	return p.properties, nil
+}
+
+func entryProperty(propertyName string) *iotsitewise.DescribeAssetPropertyOutput {
+	return &iotsitewise.DescribeAssetPropertyOutput{
+		AssetId:   aws.String("asset"),
+		AssetName: aws.String("Turbine"),
+		AssetProperty: &iotsitewisetypes.Property{
+			Id:       aws.String(propertyName),
+			Name:     aws.String(propertyName),
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+		},
+	}
+}
+
+func TestBatchFramersReportFailedEntries(t *testing.T) {
+	resources := propertiesProvider{properties: map[string]*iotsitewise.DescribeAssetPropertyOutput{
+		"speed":       entryProperty("Speed"),
+		"temperature": entryProperty("Temperature"),
+		"pressure":    entryProperty("Pressure"),
+	}}
+
+	batch := AssetPropertyValueHistoryBatch{
+		Responses: []*iotsitewise.BatchGetAssetPropertyValueHistoryOutput{{
+			SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+				EntryId: aws.String("speed"),
+				AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{{
+					Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(1)},
+					Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(1)},
+				}},
+			}},
+			ErrorEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistoryErrorEntry{{
+				EntryId:      aws.String("temperature"),
+				ErrorCode:    iotsitewisetypes.BatchGetAssetPropertyValueHistoryErrorCodeAccessDeniedException,
+				ErrorMessage: aws.String("not authorized"),
+			}},
+			SkippedEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySkippedEntry{
+				// completed on a previous page
+				{EntryId: aws.String("speed"), CompletionStatus: iotsitewisetypes.BatchEntryCompletionStatusSuccess},
+				{
+					EntryId:          aws.String("pressure"),
+					CompletionStatus: iotsitewisetypes.BatchEntryCompletionStatusError,
+					ErrorInfo: &iotsitewisetypes.BatchGetAssetPropertyValueHistoryErrorInfo{
+						ErrorCode: iotsitewisetypes.BatchGetAssetPropertyValueHistoryErrorCodeResourceNotFoundException,
+					},
+				},
+			},
+		}},
+	}
+
+	frames, err := batch.Frames(context.Background(), resources)
+	require.NoError(t, err)
+	require.Len(t, frames, 3)
+
+	require.Equal(t, &models.EntrySummary{Entries: 3, Succeeded: 1, Errored: 1, Skipped: 1, Partial: true}, frames[0].Meta.Custom.(models.SitewiseCustomMeta).Summary)
+
+	require.Equal(t, "Turbine Temperature", frames[1].Name)
+	require.Equal(t, models.SitewiseCustomMeta{EntryId: "temperature", EntryStatus: models.EntryStatusError, ErrorCode: "AccessDeniedException"}, frames[1].Meta.Custom)
+	require.Equal(t, []data.Notice{{Severity: data.NoticeSeverityError, Text: "Turbine Temperature: AccessDeniedException: not authorized"}}, frames[1].Meta.Notices)
+
+	require.Equal(t, "Turbine Pressure", frames[2].Name)
+	require.Equal(t, models.SitewiseCustomMeta{EntryId: "pressure", EntryStatus: models.EntryStatusSkipped, ErrorCode: "ResourceNotFoundException"}, frames[2].Meta.Custom)
+	require.Equal(t, []data.Notice{{Severity: data.NoticeSeverityWarning, Text: "Turbine Pressure: skipped after the error ResourceNotFoundException"}}, frames[2].Meta.Notices)
+}
@@ -27,4 +27,11 @@ const (
 	TimeSeriesId  	 = "timeSeriesId"
 	TimeSeriesCreationDate = "timeSeriesCreationDate"
 	TimeSeriesLastUpdateDate = "timeSeriesLastUpdateDate"
+	AssetName          = "asset_name"
+	Unit               = "unit"
+	NotificationState  = "notification_state"
+	NotificationTopic  = "notification_topic"
+	CompositeModelId   = "composite_model_id"
+	CompositeModelName = "composite_model_name"
+	Path               = "path"
 )
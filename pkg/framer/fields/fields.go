@@ -1,6 +1,8 @@
 package fields
 
 import (
+	"slices"
+
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
 	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
@@ -60,8 +62,54 @@ func TimeField(length int) *data.Field {
 	return NewFieldWithName(Time, data.FieldTypeTime, length)
 }
 
+var qualityColors = map[iotsitewisetypes.Quality]string{
+	iotsitewisetypes.QualityGood:      "green",
+	iotsitewisetypes.QualityBad:       "red",
+	iotsitewisetypes.QualityUncertain: "orange",
+}
+
+// QualityField is an enum of the qualities of models.AllQualities, so that panels can colour and
+// filter data points by their quality. Its values are set with QualityValue.
 func QualityField(length int) *data.Field {
-	return NewFieldWithName(Quality, data.FieldTypeString, length)
+	field := NewFieldWithName(Quality, data.FieldTypeEnum, length)
+	text := make([]string, len(models.AllQualities))
+	color := make([]string, len(models.AllQualities))
+	for i, quality := range models.AllQualities {
+		text[i] = string(quality)
+		color[i] = qualityColors[quality]
+	}
+	field.Config = &data.FieldConfig{
+		TypeConfig: &data.FieldTypeConfig{
+			Enum: &data.EnumFieldConfig{
+				Text:  text,
+				Color: color,
+			},
+		},
+	}
+	return field
+}
+
+// QualityValue is the value of a quality in a QualityField. SiteWise reports every data point with
+// a quality, so a point without one is reported as UNCERTAIN.
+func QualityValue(quality iotsitewisetypes.Quality) data.EnumItemIndex {
+	i := slices.Index(models.AllQualities, quality)
+	if i < 0 {
+		i = slices.Index(models.AllQualities, iotsitewisetypes.QualityUncertain)
+	}
+	return data.EnumItemIndex(i)
+}
+
+// QualityStringField converts a QualityField to a string field, for the conversions that only
+// take string fields as labels
+func QualityStringField(field *data.Field) *data.Field {
+	text := field.Config.TypeConfig.Enum.Text
+	quality := data.NewFieldFromFieldType(data.FieldTypeString, field.Len())
+	quality.Name = field.Name
+	quality.Labels = field.Labels
+	for i := 0; i < field.Len(); i++ {
+		quality.Set(i, text[field.At(i).(data.EnumItemIndex)])
+	}
+	return quality
 }
 
 func PropertiesField(length int) *data.Field {
@@ -154,3 +202,33 @@ func TimeSeriesCreationDateField(length int) *data.Field {
 func TimeSeriesLastUpdateDateField(length int) *data.Field {
 	return NewFieldWithName(TimeSeriesLastUpdateDate, data.FieldTypeTime, length)
 }
+
+// for asset property descriptions
+
+func AssetNameField(length int) *data.Field {
+	return NewFieldWithName(AssetName, data.FieldTypeString, length)
+}
+
+func UnitField(length int) *data.Field {
+	return NewFieldWithName(Unit, data.FieldTypeString, length)
+}
+
+func NotificationStateField(length int) *data.Field {
+	return NewFieldWithName(NotificationState, data.FieldTypeString, length)
+}
+
+func NotificationTopicField(length int) *data.Field {
+	return NewFieldWithName(NotificationTopic, data.FieldTypeString, length)
+}
+
+func CompositeModelIdField(length int) *data.Field {
+	return NewFieldWithName(CompositeModelId, data.FieldTypeString, length)
+}
+
+func CompositeModelNameField(length int) *data.Field {
+	return NewFieldWithName(CompositeModelName, data.FieldTypeString, length)
+}
+
+func PathField(length int) *data.Field {
+	return NewFieldWithName(Path, data.FieldTypeString, length)
+}
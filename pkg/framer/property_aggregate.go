@@ -2,7 +2,6 @@ package framer
 
 import (
 	"context"
-	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
 
@@ -10,26 +9,50 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/resource"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 )
 
+// AssetPropertyAggregates frames the per entry responses of the non-batch aggregates API, keyed by EntryId.
+// Every entry is requested with the resolution and aggregate types of Request.
 type AssetPropertyAggregates struct {
-	Request  iotsitewise.GetAssetPropertyAggregatesInput
-	Response iotsitewise.GetAssetPropertyAggregatesOutput
+	Request   iotsitewise.GetAssetPropertyAggregatesInput
+	Responses map[string]*iotsitewise.GetAssetPropertyAggregatesOutput
+	// ErrorEntries are the errors of the entries that failed on their own
+	ErrorEntries map[string]error
+	Query        models.AssetPropertyValueQuery
 }
 
 func (a AssetPropertyAggregates) Frames(ctx context.Context, resources resource.ResourceProvider) (data.Frames, error) {
-	resp := a.Response
-	length := len(resp.AggregatedValues)
-
-	if length < 1 {
-		return data.Frames{}, nil
-	}
-
-	property, err := resources.Property(ctx)
+	properties, err := resources.Properties(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	frames := make(data.Frames, 0, len(a.Responses))
+	entries := newBatchEntries()
+	for _, entry := range a.Query.AssetPropertyEntries {
+		entryId := *util.GetEntryIdFromAssetPropertyEntry(entry)
+		if err, failed := a.ErrorEntries[entryId]; failed {
+			entries.fail(apiErrorEntry(entryId, err))
+			continue
+		}
+		resp, ok := a.Responses[entryId]
+		if !ok {
+			continue
+		}
+		entries.success(&entryId)
+		if len(resp.AggregatedValues) < 1 {
+			continue
+		}
+		frames = append(frames, a.frame(entryId, properties[entryId], resp))
+	}
+
+	return entries.frames(frames, properties), nil
+}
+
+func (a AssetPropertyAggregates) frame(entryId string, property *iotsitewise.DescribeAssetPropertyOutput, resp *iotsitewise.GetAssetPropertyAggregatesOutput) *data.Frame {
+	length := len(resp.AggregatedValues)
+
 	timeField := fields.TimeField(length)
 	// this will enforce ordering
 	aggregateTypes, aggregateFields := getAggregationFields(length, resp.AggregatedValues[0].Value)
@@ -53,10 +76,11 @@ func (a AssetPropertyAggregates) Frames(ctx context.Context, resources resource.
 	frame.Meta = &data.FrameMeta{
 		Custom: models.SitewiseCustomMeta{
 			NextToken:  util.Dereference(resp.NextToken),
+			EntryId:    entryId,
 			Resolution: util.Dereference(a.Request.Resolution),
 			Aggregates: aggregateTypesToStrings(a.Request.AggregateTypes),
 		},
 	}
 
-	return data.Frames{frame}, nil
+	return frame
 }
@@ -104,9 +104,19 @@ func (a AssetPropertyAggregatesBatch) Frames(ctx context.Context, resources reso
 		return nil, err
 	}
 
-	for i, r := range a.Responses {
-		request := a.Requests[i]
-		for j, e := range r.SuccessEntries {
+	// success entries are matched to their request entry by id, the order is not guaranteed
+	requestEntries := map[string]iotsitewisetypes.BatchGetAssetPropertyAggregatesEntry{}
+	for _, request := range a.Requests {
+		for _, entry := range request.Entries {
+			requestEntries[util.Dereference(entry.EntryId)] = entry
+		}
+	}
+
+	entries := newBatchEntries()
+	for _, r := range a.Responses {
+		for _, e := range r.SuccessEntries {
+			entries.success(e.EntryId)
+			requestEntry := requestEntries[*e.EntryId]
 			property := properties[*e.EntryId]
 			frame, err := a.Frame(ctx, property, e.AggregatedValues)
 			if err != nil {
@@ -117,26 +127,26 @@ func (a AssetPropertyAggregatesBatch) Frames(ctx context.Context, resources reso
 				Custom: models.SitewiseCustomMeta{
 					NextToken:  util.Dereference(r.NextToken),
 					EntryId:    *e.EntryId,
-					Resolution: util.Dereference(request.Entries[j].Resolution),
-					Aggregates: aggregateTypesToStrings(request.Entries[j].AggregateTypes),
+					Resolution: util.Dereference(requestEntry.Resolution),
+					Aggregates: aggregateTypesToStrings(requestEntry.AggregateTypes),
 				},
 			}
 			frames = append(frames, frame)
 		}
 
 		for _, e := range r.ErrorEntries {
-			property := properties[*e.EntryId]
-			frame := data.NewFrame(getFrameName(property))
-			if e.ErrorMessage != nil {
-				frame.Meta = &data.FrameMeta{
-					Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: *e.ErrorMessage}},
-				}
+			entries.fail(errorEntry(e.EntryId, e.ErrorCode, e.ErrorMessage))
+		}
+		for _, e := range r.SkippedEntries {
+			var code iotsitewisetypes.BatchGetAssetPropertyAggregatesErrorCode
+			if e.ErrorInfo != nil {
+				code = e.ErrorInfo.ErrorCode
 			}
-			frames = append(frames, frame)
+			entries.fail(skippedEntry(e.EntryId, e.CompletionStatus, code))
 		}
 	}
 
-	return frames, nil
+	return entries.frames(frames, properties), nil
 }
 
 func (a AssetPropertyAggregatesBatch) Frame(ctx context.Context, property *iotsitewise.DescribeAssetPropertyOutput, v []iotsitewisetypes.AggregatedValue) (*data.Frame, error) {
@@ -15,7 +15,11 @@ import (
 
 type InterpolatedAssetPropertyValue struct {
 	Responses map[string]*iotsitewise.GetInterpolatedAssetPropertyValuesOutput
-	Query     models.AssetPropertyValueQuery
+	// ErrorEntries are the errors of the entries that failed on their own
+	ErrorEntries map[string]error
+	Query        models.AssetPropertyValueQuery
+	// Interpolations are the interpolations of the entries, recorded in their frame meta
+	Interpolations map[string]string
 }
 
 func (p InterpolatedAssetPropertyValue) Frames(ctx context.Context, resources resource.ResourceProvider) (data.Frames, error) {
@@ -26,8 +30,10 @@ func (p InterpolatedAssetPropertyValue) Frames(ctx context.Context, resources re
 	}
 
 	frames := data.Frames{}
+	entries := newBatchEntries()
 
 	for entryId, res := range p.Responses {
+		entries.success(&entryId)
 		property := properties[entryId]
 		if property == nil {
 			property = properties[*util.GetEntryId(p.Query.BaseQuery)]
@@ -39,8 +45,11 @@ func (p InterpolatedAssetPropertyValue) Frames(ctx context.Context, resources re
 
 		frames = append(frames, frame)
 	}
+	for entryId, err := range p.ErrorEntries {
+		entries.fail(apiErrorEntry(entryId, err))
+	}
 
-	return frames, nil
+	return entries.frames(frames, properties), nil
 }
 
 func (p InterpolatedAssetPropertyValue) Frame(ctx context.Context, property *iotsitewise.DescribeAssetPropertyOutput, v []iotsitewisetypes.InterpolatedAssetPropertyValue) (*data.Frame, error) {
@@ -69,9 +78,10 @@ func (p InterpolatedAssetPropertyValue) Frame(ctx context.Context, property *iot
 	}
 	frame.Meta = &data.FrameMeta{
 		Custom: models.SitewiseCustomMeta{
-			NextToken:  util.Dereference(p.Responses[entryId].NextToken),
-			EntryId:    entryId,
-			Resolution: p.Query.Resolution,
+			NextToken:     util.Dereference(p.Responses[entryId].NextToken),
+			EntryId:       entryId,
+			Resolution:    p.Query.Resolution,
+			Interpolation: p.Interpolations[entryId],
 		},
 	}
 
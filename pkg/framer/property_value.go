@@ -7,33 +7,66 @@ import (
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/resource"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 )
 
-type AssetPropertyValue iotsitewise.GetAssetPropertyValueOutput
+// AssetPropertyValue frames the per entry responses of the non-batch latest value API, keyed by EntryId
+type AssetPropertyValue struct {
+	Responses map[string]*iotsitewise.GetAssetPropertyValueOutput
+	// ErrorEntries are the errors of the entries that failed on their own
+	ErrorEntries map[string]error
+	Query        models.AssetPropertyValueQuery
+}
 
 func (p AssetPropertyValue) Frames(ctx context.Context, resources resource.ResourceProvider) (data.Frames, error) {
-	length := 0
-	if p.PropertyValue != nil {
-		length = 1
-	}
-
-	property, err := resources.Property(ctx)
+	properties, err := resources.Properties(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	frames := make(data.Frames, 0, len(p.Responses))
+	entries := newBatchEntries()
+	for _, entry := range p.Query.AssetPropertyEntries {
+		entryId := *util.GetEntryIdFromAssetPropertyEntry(entry)
+		if err, failed := p.ErrorEntries[entryId]; failed {
+			entries.fail(apiErrorEntry(entryId, err))
+			continue
+		}
+		resp, ok := p.Responses[entryId]
+		if !ok {
+			continue
+		}
+		entries.success(&entryId)
+		frames = append(frames, p.frame(entryId, properties[entryId], resp))
+	}
+
+	return entries.frames(frames, properties), nil
+}
+
+func (AssetPropertyValue) frame(entryId string, property *iotsitewise.DescribeAssetPropertyOutput, resp *iotsitewise.GetAssetPropertyValueOutput) *data.Frame {
+	length := 0
+	if resp.PropertyValue != nil {
+		length = 1
+	}
+
 	timeField := fields.TimeField(length)
 	valueField := fields.PropertyValueField(property, length)
 	qualityField := fields.QualityField(length)
 
 	frame := data.NewFrame(getFrameName(property), timeField, valueField, qualityField)
+	frame.Meta = &data.FrameMeta{
+		Custom: models.SitewiseCustomMeta{
+			EntryId: entryId,
+		},
+	}
 
-	if p.PropertyValue != nil && getPropertyVariantValue(p.PropertyValue.Value) != nil {
-		timeField.Set(0, getTime(p.PropertyValue.Timestamp))
-		valueField.Set(0, getPropertyVariantValue(p.PropertyValue.Value))
-		qualityField.Set(0, string(p.PropertyValue.Quality))
+	if resp.PropertyValue != nil && getPropertyVariantValue(resp.PropertyValue.Value) != nil {
+		timeField.Set(0, getTime(resp.PropertyValue.Timestamp))
+		valueField.Set(0, getPropertyVariantValue(resp.PropertyValue.Value))
+		qualityField.Set(0, fields.QualityValue(resp.PropertyValue.Quality))
 	}
 
-	return data.Frames{frame}, nil
+	return frame
 }
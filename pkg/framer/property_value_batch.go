@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
 	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
@@ -36,8 +37,10 @@ func (p AssetPropertyValueBatch) Frames(ctx context.Context, resources resource.
 		return nil, err
 	}
 
+	entries := newBatchEntries()
 	for _, r := range p.Responses {
 		for _, e := range r.SuccessEntries {
+			entries.success(e.EntryId)
 			property := properties[*e.EntryId]
 			if util.IsAssetProperty(property) && !isPropertyDataTypeDefined(property.AssetProperty.DataType) && e.AssetPropertyValue != nil {
 				property.AssetProperty.DataType = getPropertyVariantValueType(e.AssetPropertyValue.Value)
@@ -62,18 +65,18 @@ func (p AssetPropertyValueBatch) Frames(ctx context.Context, resources resource.
 		}
 
 		for _, e := range r.ErrorEntries {
-			property := properties[*e.EntryId]
-			frame := data.NewFrame(*property.AssetName)
-			if e.ErrorMessage != nil {
-				frame.Meta = &data.FrameMeta{
-					Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: *e.ErrorMessage}},
-				}
+			entries.fail(errorEntry(e.EntryId, e.ErrorCode, e.ErrorMessage))
+		}
+		for _, e := range r.SkippedEntries {
+			var code iotsitewisetypes.BatchGetAssetPropertyValueErrorCode
+			if e.ErrorInfo != nil {
+				code = e.ErrorInfo.ErrorCode
 			}
-			frames = append(frames, frame)
+			entries.fail(skippedEntry(e.EntryId, e.CompletionStatus, code))
 		}
 	}
 
-	return frames, nil
+	return entries.frames(frames, properties), nil
 }
 
 func (AssetPropertyValueBatch) framePropertyValue(property *iotsitewise.DescribeAssetPropertyOutput, assetPropertyValue *iotsitewisetypes.AssetPropertyValue) *data.Frame {
@@ -86,7 +89,7 @@ func (AssetPropertyValueBatch) framePropertyValue(property *iotsitewise.Describe
 	if assetPropertyValue != nil && getPropertyVariantValue(assetPropertyValue.Value) != nil {
 		timeField.Append(getTime(assetPropertyValue.Timestamp))
 		valueField.Append(getPropertyVariantValue(assetPropertyValue.Value))
-		qualityField.Append(string(assetPropertyValue.Quality))
+		qualityField.Append(fields.QualityValue(assetPropertyValue.Quality))
 	}
 	return frame
 }
@@ -118,10 +121,13 @@ func (p AssetPropertyValueBatch) frameL4ePropertyValue(ctx context.Context, prop
 	}
 
 	timeField.Append(getTime(assetPropertyValue.Timestamp))
-	qualityField.Append(string(assetPropertyValue.Quality))
+	qualityField.Append(fields.QualityValue(assetPropertyValue.Quality))
 	anomalyScoreField.Append(l4eAnomalyResult.AnomalyScore)
 	predictionReasonField.Append(l4eAnomalyResult.PredictionReason)
 
+	// Name diagnostic fields with human friendly names
+	ctx, span := util.StartSpan(ctx, "framer.l4eDiagnosticNames", attribute.Int("sitewise.diagnostics", len(l4eAnomalyResult.Diagnostics)))
+	defer span.End()
 	for _, diagnostics := range l4eAnomalyResult.Diagnostics {
 		propertyId := strings.Split(diagnostics.Name, "\\")[0]
 
@@ -11,22 +11,45 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 )
 
+// AssetPropertyValueHistory frames the per entry responses of the non-batch history API, keyed by EntryId
 type AssetPropertyValueHistory struct {
-	*iotsitewise.GetAssetPropertyValueHistoryOutput
-	Query models.AssetPropertyValueQuery
+	Responses map[string]*iotsitewise.GetAssetPropertyValueHistoryOutput
+	// ErrorEntries are the errors of the entries that failed on their own
+	ErrorEntries map[string]error
+	Query        models.AssetPropertyValueQuery
 }
 
 func (p AssetPropertyValueHistory) Frames(ctx context.Context, resources resource.ResourceProvider) (data.Frames, error) {
-
-	length := len(p.AssetPropertyValueHistory)
-	property, err := resources.Property(ctx)
+	properties, err := resources.Properties(ctx)
 	if err != nil {
 		return nil, err
 	}
+
+	frames := make(data.Frames, 0, len(p.Responses))
+	entries := newBatchEntries()
+	for _, entry := range p.Query.AssetPropertyEntries {
+		entryId := *util.GetEntryIdFromAssetPropertyEntry(entry)
+		if err, failed := p.ErrorEntries[entryId]; failed {
+			entries.fail(apiErrorEntry(entryId, err))
+			continue
+		}
+		resp, ok := p.Responses[entryId]
+		if !ok {
+			continue
+		}
+		entries.success(&entryId)
+		frames = append(frames, p.frame(entryId, properties[entryId], resp))
+	}
+
+	return entries.frames(frames, properties), nil
+}
+
+func (p AssetPropertyValueHistory) frame(entryId string, property *iotsitewise.DescribeAssetPropertyOutput, resp *iotsitewise.GetAssetPropertyValueHistoryOutput) *data.Frame {
+	length := len(resp.AssetPropertyValueHistory)
 	// TODO: make this work with the API instead of ad-hoc dataType inference
 	// https://github.com/grafana/iot-sitewise-datasource/issues/98#issuecomment-892947756
-	if util.IsAssetProperty(property) && !isPropertyDataTypeDefined(property.AssetProperty.DataType) {
-		property.AssetProperty.DataType = getPropertyVariantValueType(p.AssetPropertyValueHistory[0].Value)
+	if util.IsAssetProperty(property) && !isPropertyDataTypeDefined(property.AssetProperty.DataType) && length > 0 {
+		property.AssetProperty.DataType = getPropertyVariantValueType(resp.AssetPropertyValueHistory[0].Value)
 	}
 
 	timeField := fields.TimeField(length)
@@ -35,18 +58,19 @@ func (p AssetPropertyValueHistory) Frames(ctx context.Context, resources resourc
 	frame := data.NewFrame(getFrameName(property), timeField, valueField, qualityField)
 	frame.Meta = &data.FrameMeta{
 		Custom: models.SitewiseCustomMeta{
-			NextToken:  util.Dereference(p.NextToken),
+			NextToken:  util.Dereference(resp.NextToken),
+			EntryId:    entryId,
 			Resolution: models.PropertyQueryResolutionRaw,
 		},
 	}
 
-	for i, v := range p.AssetPropertyValueHistory {
+	for i, v := range resp.AssetPropertyValueHistory {
 		if v.Value != nil && getPropertyVariantValue(v.Value) != nil {
 			timeField.Set(i, getTime(v.Timestamp))
 			valueField.Set(i, getPropertyVariantValue(v.Value))
-			qualityField.Set(i, string(v.Quality))
+			qualityField.Set(i, fields.QualityValue(v.Quality))
 		}
 	}
 
-	return data.Frames{frame}, nil
+	return frame
 }
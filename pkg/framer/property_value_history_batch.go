@@ -9,6 +9,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
@@ -37,8 +38,10 @@ func (p AssetPropertyValueHistoryBatch) Frames(ctx context.Context, resources re
 		return frames, err
 	}
 
+	entries := newBatchEntries()
 	for _, r := range p.Responses {
 		for _, s := range r.SuccessEntries {
+			entries.success(s.EntryId)
 			frame, err := p.Frame(ctx, properties[*s.EntryId], s.AssetPropertyValueHistory)
 			frame.Meta = &data.FrameMeta{
 				Custom: models.SitewiseCustomMeta{
@@ -56,18 +59,18 @@ func (p AssetPropertyValueHistoryBatch) Frames(ctx context.Context, resources re
 		}
 
 		for _, e := range r.ErrorEntries {
-			property := properties[*e.EntryId]
-			frame := data.NewFrame(getFrameName(property))
-			if e.ErrorMessage != nil {
-				frame.Meta = &data.FrameMeta{
-					Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: *e.ErrorMessage}},
-				}
+			entries.fail(errorEntry(e.EntryId, e.ErrorCode, e.ErrorMessage))
+		}
+		for _, e := range r.SkippedEntries {
+			var code iotsitewisetypes.BatchGetAssetPropertyValueHistoryErrorCode
+			if e.ErrorInfo != nil {
+				code = e.ErrorInfo.ErrorCode
 			}
-			frames = append(frames, frame)
+			entries.fail(skippedEntry(e.EntryId, e.CompletionStatus, code))
 		}
 	}
 
-	return frames, nil
+	return entries.frames(frames, properties), nil
 }
 
 func (p AssetPropertyValueHistoryBatch) Frame(ctx context.Context, property *iotsitewise.DescribeAssetPropertyOutput, h []iotsitewisetypes.AssetPropertyValue) (*data.Frame, error) {
@@ -113,7 +116,7 @@ func (p AssetPropertyValueHistoryBatch) framePropertyValues(property *iotsitewis
 		if v.Value != nil && getPropertyVariantValue(v.Value) != nil {
 			timeField.Set(i, getTime(v.Timestamp))
 			valueField.Set(i, getPropertyVariantValue(v.Value))
-			qualityField.Set(i, string(v.Quality))
+			qualityField.Set(i, fields.QualityValue(v.Quality))
 		}
 	}
 
@@ -169,7 +172,7 @@ func (p AssetPropertyValueHistoryBatch) parseL4eFields(ctx context.Context, asse
 		}
 
 		timeField.Set(i, getTime(v.Timestamp))
-		qualityField.Set(i, string(v.Quality))
+		qualityField.Set(i, fields.QualityValue(v.Quality))
 		anomalyScoreField.Set(i, l4eAnomalyResult.AnomalyScore)
 		predictionReasonField.Set(i, l4eAnomalyResult.PredictionReason)
 
@@ -185,6 +188,8 @@ func (p AssetPropertyValueHistoryBatch) parseL4eFields(ctx context.Context, asse
 	}
 
 	// Rename diagnostic fields with human friendly names
+	ctx, span := util.StartSpan(ctx, "framer.l4eDiagnosticNames", attribute.Int("sitewise.diagnostics", len(diagnosticsMap)))
+	defer span.End()
 	for _, diagnosticsField := range diagnosticsMap {
 		propertyId := strings.Split(diagnosticsField.Name, "\\")[0]
 
@@ -0,0 +1,79 @@
+package framer
+
+import (
+	"context"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/resource"
+)
+
+// PlannedCalls are the calls of one operation that a query would make, with the same entries per call
+type PlannedCalls struct {
+	Operation      string
+	Calls          int
+	EntriesPerCall int
+	Resolution     string
+	// EstimatedPages and ExpectedPoints are nil when they depend on the data, such as the number
+	// of raw values in a time range
+	EstimatedPages *int64
+	ExpectedPoints *int64
+}
+
+// QueryPlan is the result of a dry run: the calls a query would make and their estimated cost
+type QueryPlan struct {
+	Calls []PlannedCalls
+	// Statement is the SQL of an ExecuteQuery query after its macros are interpolated
+	Statement string
+	// Notices explain the parts of the cost that could not be estimated
+	Notices []string
+}
+
+func (p QueryPlan) Frames(_ context.Context, _ resource.ResourceProvider) (data.Frames, error) {
+	length := len(p.Calls)
+	operation := data.NewFieldFromFieldType(data.FieldTypeString, length)
+	operation.Name = "operation"
+	calls := data.NewFieldFromFieldType(data.FieldTypeInt64, length)
+	calls.Name = "calls"
+	entries := data.NewFieldFromFieldType(data.FieldTypeInt64, length)
+	entries.Name = "entries_per_call"
+	resolution := data.NewFieldFromFieldType(data.FieldTypeString, length)
+	resolution.Name = "resolution"
+	pages := data.NewFieldFromFieldType(data.FieldTypeNullableInt64, length)
+	pages.Name = "estimated_pages"
+	points := data.NewFieldFromFieldType(data.FieldTypeNullableInt64, length)
+	points.Name = "expected_points"
+
+	var totalCalls, totalPages, totalPoints int64
+	for i, c := range p.Calls {
+		operation.Set(i, c.Operation)
+		calls.Set(i, int64(c.Calls))
+		entries.Set(i, int64(c.EntriesPerCall))
+		resolution.Set(i, c.Resolution)
+		pages.Set(i, c.EstimatedPages)
+		points.Set(i, c.ExpectedPoints)
+
+		totalCalls += int64(c.Calls)
+		if c.EstimatedPages != nil {
+			totalPages += *c.EstimatedPages
+		}
+		if c.ExpectedPoints != nil {
+			totalPoints += *c.ExpectedPoints
+		}
+	}
+
+	meta := &data.FrameMeta{
+		ExecutedQueryString: p.Statement,
+		Stats: []data.QueryStat{
+			{FieldConfig: data.FieldConfig{DisplayName: "Planned calls"}, Value: float64(totalCalls)},
+			{FieldConfig: data.FieldConfig{DisplayName: "Estimated pages"}, Value: float64(totalPages)},
+			{FieldConfig: data.FieldConfig{DisplayName: "Expected points"}, Value: float64(totalPoints)},
+		},
+	}
+	for _, text := range p.Notices {
+		meta.Notices = append(meta.Notices, data.Notice{Severity: data.NoticeSeverityInfo, Text: text})
+	}
+
+	frame := data.NewFrame("Dry run", operation, calls, entries, resolution, pages, points).SetMeta(meta)
+	return data.Frames{frame}, nil
+}
@@ -0,0 +1,112 @@
+// Package metrics holds the Prometheus metrics of the SiteWise API usage of the plugin. They are
+// registered with the default registry, which the plugin SDK exposes on its metrics endpoint.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	namespace = "grafana_plugin"
+	subsystem = "sitewise"
+)
+
+var (
+	APICalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "api_calls_total",
+		Help:      "Number of SiteWise API calls by operation",
+	}, []string{"datasource", "region", "operation"})
+
+	APICallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "api_call_duration_seconds",
+		Help:      "Duration of SiteWise API calls by operation, including retries",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"datasource", "region", "operation"})
+
+	APIErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "api_errors_total",
+		Help:      "Number of failed SiteWise API calls by operation and AWS error code",
+	}, []string{"datasource", "region", "operation", "code"})
+
+	APIThrottles = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "api_throttles_total",
+		Help:      "Number of SiteWise API attempts rejected by the SiteWise rate limits",
+	}, []string{"datasource", "region", "operation"})
+
+	PagesFetched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "pages_fetched_total",
+		Help:      "Number of pages fetched while paginating SiteWise API results",
+	}, []string{"datasource", "region", "operation"})
+
+	BatchEntries = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "batch_entries",
+		Help:      "Number of entries per SiteWise batch API call",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 8),
+	}, []string{"datasource", "region", "operation"})
+
+	MetadataCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "metadata_cache_requests_total",
+		Help:      "Number of metadata cache lookups by kind of metadata and result (hit, miss, or shared with a concurrent miss)",
+	}, []string{"datasource", "region", "kind", "result"})
+)
+
+// Labels identify the datasource instance and region of a SiteWise client
+type Labels struct {
+	DatasourceUID string
+	Region        string
+}
+
+func (l Labels) values(extra ...string) []string {
+	return append([]string{l.DatasourceUID, l.Region}, extra...)
+}
+
+func (l Labels) APICall(operation string) {
+	APICalls.WithLabelValues(l.values(operation)...).Inc()
+}
+
+func (l Labels) APICallDuration(operation string, seconds float64) {
+	APICallDuration.WithLabelValues(l.values(operation)...).Observe(seconds)
+}
+
+func (l Labels) APIError(operation string, code string) {
+	APIErrors.WithLabelValues(l.values(operation, code)...).Inc()
+}
+
+func (l Labels) APIThrottle(operation string) {
+	APIThrottles.WithLabelValues(l.values(operation)...).Inc()
+}
+
+func (l Labels) PageFetched(operation string) {
+	PagesFetched.WithLabelValues(l.values(operation)...).Inc()
+}
+
+func (l Labels) BatchEntries(operation string, entries int) {
+	BatchEntries.WithLabelValues(l.values(operation)...).Observe(float64(entries))
+}
+
+// Results of metadata cache lookups
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+	// CacheShared is a lookup that waited for the load of a concurrent miss of the same key
+	CacheShared = "shared"
+)
+
+func (l Labels) MetadataCacheRequest(kind string, result string) {
+	MetadataCacheRequests.WithLabelValues(l.values(kind, result)...).Inc()
+}
@@ -51,6 +51,21 @@ func GetDescribeAssetQuery(dq *backend.DataQuery) (*DescribeAssetQuery, error) {
 	return query, nil
 }
 
+func GetDescribeAssetPropertyQuery(dq *backend.DataQuery) (*DescribeAssetPropertyQuery, error) {
+	query := &DescribeAssetPropertyQuery{}
+	if err := json.Unmarshal(dq.JSON, query); err != nil {
+		return nil, err
+	}
+
+	// AssetId <--> AssetIds backward compatibility
+	query.MigrateAssetProperty()
+
+	// add on the DataQuery params
+	query.QueryType = dq.QueryType
+
+	return query, nil
+}
+
 func GetListAssetPropertiesQuery(dq *backend.DataQuery) (*ListAssetPropertiesQuery, error) {
 	query := &ListAssetPropertiesQuery{}
 	if err := json.Unmarshal(dq.JSON, query); err != nil {
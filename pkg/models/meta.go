@@ -1,9 +1,44 @@
 package models
 
+// Status of the frame of a batch entry that returned no data
+const (
+	EntryStatusError   = "error"
+	EntryStatusSkipped = "skipped"
+)
+
 // SitewiseCustomMeta is the standard metadata
 type SitewiseCustomMeta struct {
 	NextToken  string   `json:"nextToken,omitempty"`
 	EntryId    string   `json:"entryId,omitempty"`
 	Resolution string   `json:"resolution,omitempty"`
 	Aggregates []string `json:"aggregates,omitempty"`
+	// Interpolation is the interpolation type of the entry of an interpolated query, with its
+	// interval window when set
+	Interpolation string `json:"interpolation,omitempty"`
+	// Quality is the quality of the frames of a query that is fetched once per quality
+	Quality string `json:"quality,omitempty"`
+	// Downsample is the algorithm the raw values of the frame were downsampled with, and
+	// DownsampleRatio the number of values per value kept
+	Downsample      string  `json:"downsample,omitempty"`
+	DownsampleRatio float64 `json:"downsampleRatio,omitempty"`
+	// Truncated is set on the frames of entries whose remaining pages were not fetched because
+	// the fetchAll budget was spent
+	Truncated bool `json:"truncated,omitempty"`
+	// EntryStatus is set on the frames of entries that errored or were skipped
+	EntryStatus string `json:"entryStatus,omitempty"`
+	// ErrorCode is the error code of an entry that errored, or that was skipped after an error
+	ErrorCode string `json:"errorCode,omitempty"`
+	// Summary counts the entries of a query by their outcome. It is set on the first frame when
+	// any entry errored or was skipped.
+	Summary *EntrySummary `json:"summary,omitempty"`
+}
+
+// EntrySummary counts the entries of a query by their outcome, so that partial data can be told
+// from no data
+type EntrySummary struct {
+	Entries   int  `json:"entries"`
+	Succeeded int  `json:"succeeded"`
+	Errored   int  `json:"errored"`
+	Skipped   int  `json:"skipped"`
+	Partial   bool `json:"partial"`
 }
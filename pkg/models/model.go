@@ -19,6 +19,8 @@ type DescribeAssetModelQuery struct {
 type ExecuteQuery struct {
 	BaseQuery
 	sqlutil.Query
+	// DryRun returns the statement of the query after its macros are interpolated, without running it
+	DryRun bool `json:"dryRun,omitempty"`
 }
 
 func GetListAssetModelsQuery(dq *backend.DataQuery) (*ListAssetModelsQuery, error) {
@@ -2,15 +2,49 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
 	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
 )
 
 const (
 	PropertyQueryResolutionRaw = "RAW"
 )
 
+// Interpolation types of PropertyInterpolated queries. LOCF, last observation carried forward,
+// keeps step-like signals flat and is the only type that supports string and boolean properties.
+const (
+	InterpolationTypeLinear = "LINEAR"
+	InterpolationTypeLOCF   = "LOCF"
+)
+
+// Downsampling algorithms of the raw values of PropertyValueHistory queries. LTTB, largest
+// triangle three buckets, keeps the visual shape of a series, while MINMAX keeps the minimum and
+// maximum of every bucket, so no spike is lost.
+const (
+	DownsampleLTTB   = "LTTB"
+	DownsampleMinMax = "MINMAX"
+)
+
+// DefaultLastObservationLookback is how far before the time range lastObservation looks for the
+// previous observation of each entry
+const DefaultLastObservationLookback = 8760 * time.Hour // 1 year
+
+// QualityAny requests the data points of every quality
+const QualityAny iotsitewisetypes.Quality = "ANY"
+
+// AllQualities are the qualities of SiteWise data points
+var AllQualities = []iotsitewisetypes.Quality{
+	iotsitewisetypes.QualityGood,
+	iotsitewisetypes.QualityBad,
+	iotsitewisetypes.QualityUncertain,
+}
+
 type ListAssetPropertiesQuery struct {
 	BaseQuery
 }
@@ -26,6 +60,29 @@ type AssetPropertyValueQuery struct {
 	LastObservation bool                             `json:"lastObservation,omitempty"`
 	TimeOrdering    iotsitewisetypes.TimeOrdering    `json:"timeOrdering,omitempty"`
 	FlattenL4e      bool                             `json:"flattenL4e,omitempty"`
+	// Qualities requests the data points of several qualities. It takes precedence over Quality.
+	Qualities []iotsitewisetypes.Quality `json:"qualities,omitempty"`
+	// Streaming subscribes PropertyValue queries to live updates over Grafana Live
+	Streaming bool `json:"streaming,omitempty"`
+	// FetchAll follows every next token in the backend, within the datasource's FetchAllBudget
+	FetchAll bool `json:"fetchAll,omitempty"`
+	// DryRun plans the calls of the query and estimates their cost, without fetching any data
+	DryRun bool `json:"dryRun,omitempty"`
+	// InterpolationType of PropertyInterpolated queries. Unset picks LOCF for string and boolean
+	// properties and LINEAR for the others.
+	InterpolationType string `json:"interpolationType,omitempty"`
+	// IntervalWindow is the window of data points around each interval that LINEAR interpolation
+	// uses, such as "1h". Unset uses the default window of SiteWise.
+	IntervalWindow string `json:"intervalWindow,omitempty"`
+	// LastObservationLookback is how far before the time range lastObservation looks for the
+	// previous observation of each entry, such as "30d". Unset uses DefaultLastObservationLookback.
+	LastObservationLookback string `json:"lastObservationLookback,omitempty"`
+	// LastObservationAtRangeStart stamps the previous observation of each entry at the start of the
+	// time range, the value the property had then, like PropertyValue queries stamp the latest value
+	LastObservationAtRangeStart bool `json:"lastObservationAtRangeStart,omitempty"`
+	// Downsample reduces the raw values of PropertyValueHistory queries to about MaxDataPoints
+	// per series with LTTB or MINMAX. Unset returns every value.
+	Downsample string `json:"downsample,omitempty"`
 }
 
 // Track the assetId, propertyId, and property alias of a data stream
@@ -36,6 +93,70 @@ type AssetPropertyEntry struct {
 	PropertyAlias string `json:"propertyAlias,omitempty"`
 }
 
+// GetQualities returns the qualities the query requests, in the order of AllQualities. ANY
+// stands for every quality, and a query without a quality requests GOOD data points only.
+func (q AssetPropertyValueQuery) GetQualities() []iotsitewisetypes.Quality {
+	requested := q.Qualities
+	if len(requested) == 0 {
+		requested = []iotsitewisetypes.Quality{q.Quality}
+	}
+
+	qualities := []iotsitewisetypes.Quality{}
+	for _, quality := range AllQualities {
+		if slices.Contains(requested, quality) || slices.Contains(requested, QualityAny) {
+			qualities = append(qualities, quality)
+		}
+	}
+	if len(qualities) == 0 {
+		return []iotsitewisetypes.Quality{iotsitewisetypes.QualityGood}
+	}
+	return qualities
+}
+
+// QualityEntryId qualifies the EntryId of an entry that is fetched once per quality, so that the
+// next token of each quality continues that quality only
+func QualityEntryId(entryId string, quality iotsitewisetypes.Quality) string {
+	return entryId + "-" + string(quality)
+}
+
+// GetLastObservationLookback returns how far before the time range lastObservation looks for the
+// previous observation of each entry
+func (q AssetPropertyValueQuery) GetLastObservationLookback() time.Duration {
+	lookback, err := gtime.ParseDuration(q.LastObservationLookback)
+	if err != nil || lookback <= 0 {
+		return DefaultLastObservationLookback
+	}
+	return lookback
+}
+
+func validateLastObservationLookback(query *AssetPropertyValueQuery) error {
+	if query.LastObservationLookback == "" {
+		return nil
+	}
+	lookback, err := gtime.ParseDuration(query.LastObservationLookback)
+	if err != nil || lookback <= 0 {
+		return fmt.Errorf("invalid last observation lookback %q: use a duration such as 30d", query.LastObservationLookback)
+	}
+	return nil
+}
+
+func validateDownsample(query *AssetPropertyValueQuery) error {
+	switch query.Downsample {
+	case "", DownsampleLTTB, DownsampleMinMax:
+		return nil
+	}
+	return fmt.Errorf("unknown downsample algorithm %q: use LTTB or MINMAX", query.Downsample)
+}
+
+func validateQualities(query *AssetPropertyValueQuery) error {
+	for _, quality := range append([]iotsitewisetypes.Quality{query.Quality}, query.Qualities...) {
+		if quality != "" && quality != QualityAny && !slices.Contains(AllQualities, quality) {
+			return fmt.Errorf("unknown quality %q: use GOOD, BAD, UNCERTAIN or ANY", quality)
+		}
+	}
+	return nil
+}
+
 func GetAssetPropertyValueQuery(dq *backend.DataQuery) (*AssetPropertyValueQuery, error) {
 
 	query := &AssetPropertyValueQuery{}
@@ -43,6 +164,18 @@ func GetAssetPropertyValueQuery(dq *backend.DataQuery) (*AssetPropertyValueQuery
 		return nil, err
 	}
 
+	if err := validateQualities(query); err != nil {
+		return nil, err
+	}
+
+	if err := validateLastObservationLookback(query); err != nil {
+		return nil, err
+	}
+
+	if err := validateDownsample(query); err != nil {
+		return nil, err
+	}
+
 	// Backward compatibility for asset, property, and property alias string --> list
 	query.MigrateAssetProperty()
 
@@ -7,18 +7,19 @@ import (
 )
 
 const (
-	QueryTypePropertyValueHistory = "PropertyValueHistory"
-	QueryTypePropertyValue        = "PropertyValue"
-	QueryTypePropertyAggregate    = "PropertyAggregate"
-	QueryTypePropertyInterpolated = "PropertyInterpolated"
-	QueryTypeListAssetModels      = "ListAssetModels"
-	QueryTypeListAssets           = "ListAssets"
-	QueryTypeListAssociatedAssets = "ListAssociatedAssets"
-	QueryTypeDescribeAsset        = "DescribeAsset"
-	QueryTypeDescribeAssetModel   = "DescribeAssetModel"
-	QueryTypeListAssetProperties  = "ListAssetProperties"
-	QueryTypeListTimeSeries       = "ListTimeSeries"
-	QueryTypeExecuteQuery         = "ExecuteQuery"
+	QueryTypePropertyValueHistory  = "PropertyValueHistory"
+	QueryTypePropertyValue         = "PropertyValue"
+	QueryTypePropertyAggregate     = "PropertyAggregate"
+	QueryTypePropertyInterpolated  = "PropertyInterpolated"
+	QueryTypeListAssetModels       = "ListAssetModels"
+	QueryTypeListAssets            = "ListAssets"
+	QueryTypeListAssociatedAssets  = "ListAssociatedAssets"
+	QueryTypeDescribeAsset         = "DescribeAsset"
+	QueryTypeDescribeAssetProperty = "DescribeAssetProperty"
+	QueryTypeDescribeAssetModel    = "DescribeAssetModel"
+	QueryTypeListAssetProperties   = "ListAssetProperties"
+	QueryTypeListTimeSeries        = "ListTimeSeries"
+	QueryTypeExecuteQuery          = "ExecuteQuery"
 )
 
 const (
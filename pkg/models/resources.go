@@ -0,0 +1,53 @@
+package models
+
+// ResourcePage is a single page of a listing returned by the resource API.
+// A non-empty NextToken can be passed back as the nextToken parameter to fetch the next page.
+type ResourcePage[T any] struct {
+	Items     []T    `json:"items"`
+	NextToken string `json:"nextToken,omitempty"`
+}
+
+type AssetHierarchy struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type AssetSummary struct {
+	Id          string           `json:"id"`
+	Name        string           `json:"name"`
+	Arn         string           `json:"arn,omitempty"`
+	ModelId     string           `json:"modelId,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Hierarchies []AssetHierarchy `json:"hierarchies,omitempty"`
+}
+
+type AssetModelSummary struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Arn         string `json:"arn,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type PropertySummary struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Alias    string `json:"alias,omitempty"`
+	DataType string `json:"dataType,omitempty"`
+	Unit     string `json:"unit,omitempty"`
+	// Set for properties of composite models (components)
+	CompositeModelId   string `json:"compositeModelId,omitempty"`
+	CompositeModelName string `json:"compositeModelName,omitempty"`
+}
+
+type AssetDetails struct {
+	AssetSummary
+	Properties []PropertySummary `json:"properties"`
+}
+
+type TimeSeriesSummary struct {
+	Id         string `json:"id"`
+	Alias      string `json:"alias,omitempty"`
+	AssetId    string `json:"assetId,omitempty"`
+	PropertyId string `json:"propertyId,omitempty"`
+	DataType   string `json:"dataType,omitempty"`
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -13,12 +14,94 @@ const EDGE_AUTH_MODE_DEFAULT string = "default"
 const EDGE_AUTH_MODE_LDAP string = "ldap"
 const EDGE_AUTH_MODE_LINUX string = "linux"
 
+// Defaults for the budget of queries with fetchAll
+const (
+	DefaultFetchAllMaxRounds  = 100
+	DefaultFetchAllMaxPoints  = 1_000_000
+	DefaultFetchAllTimeoutSec = 30
+)
+
+// DefaultEdgeAuthRenewalMarginSec is how long before expiry the edge credentials are renewed
+const DefaultEdgeAuthRenewalMarginSec = 60
+
+// Defaults for the metadata cache of a datasource instance
+const (
+	DefaultMetadataCacheTTLSec     = 300
+	DefaultMetadataCacheMaxEntries = 10_000
+)
+
+// Defaults for the client side rate limits, in requests per second, and the retries of
+// throttled pages
+const (
+	DefaultDataPlaneRateLimit    = 50
+	DefaultControlPlaneRateLimit = 20
+	DefaultThrottleMaxRetries    = 3
+)
+
+// DefaultMaxConcurrentQueries is the number of queries of a request that run at the same time
+const DefaultMaxConcurrentQueries = 8
+
 type AWSSiteWiseDataSourceSetting struct {
 	awsds.AWSDatasourceSettings
 	Cert         string `json:"-"`
 	EdgeAuthMode string `json:"edgeAuthMode"`
 	EdgeAuthUser string `json:"edgeAuthUser"`
 	EdgeAuthPass string `json:"-"`
+	// Seconds before expiry that the edge credentials are renewed, zero falls back to the default
+	EdgeAuthRenewalMarginSec int `json:"edgeAuthRenewalMarginSec,omitempty"`
+
+	// Budget for queries with fetchAll, zero values fall back to the defaults
+	FetchAllMaxRounds  int `json:"fetchAllMaxRounds,omitempty"`
+	FetchAllMaxPoints  int `json:"fetchAllMaxPoints,omitempty"`
+	FetchAllTimeoutSec int `json:"fetchAllTimeoutSec,omitempty"`
+
+	// Number of queries of a request that run at the same time, zero falls back to the default
+	MaxConcurrentQueries int `json:"maxConcurrentQueries,omitempty"`
+
+	// Limits of every query, zero values leave queries unlimited
+	MaxEntriesPerQuery   int `json:"maxEntriesPerQuery,omitempty"`
+	MaxRawRangeHours     int `json:"maxRawRangeHours,omitempty"`
+	MaxPagesPerEntry     int `json:"maxPagesPerEntry,omitempty"`
+	MaxPointsPerResponse int `json:"maxPointsPerResponse,omitempty"`
+	QueryTimeoutSec      int `json:"queryTimeoutSec,omitempty"`
+
+	// Metadata cache of assets, models and properties, zero values fall back to the defaults
+	MetadataCacheTTLSec     int `json:"metadataCacheTTLSec,omitempty"`
+	MetadataCacheMaxEntries int `json:"metadataCacheMaxEntries,omitempty"`
+
+	// Requests per second of property value and metadata requests, and how often throttled pages
+	// are retried. Zero values fall back to the defaults. The datasources of the same account and
+	// region share their limits, set by the first of them that makes a request.
+	DataPlaneRateLimit    float64 `json:"dataPlaneRateLimit,omitempty"`
+	ControlPlaneRateLimit float64 `json:"controlPlaneRateLimit,omitempty"`
+	ThrottleMaxRetries    int     `json:"throttleMaxRetries,omitempty"`
+}
+
+// FetchAllBudget limits how much data a query with fetchAll may load by following next tokens
+type FetchAllBudget struct {
+	// MaxRounds is the number of times the next tokens of a query are followed. Each round
+	// fetches up to the MaxPageAggregations of the query for every entry, so it is not a number
+	// of SiteWise pages.
+	MaxRounds int
+	MaxPoints int
+	// Timeout is the time a query may take, including the queries it adds such as lastObservation
+	Timeout time.Duration
+}
+
+// QueryLimits keep a single query from loading more than a datasource allows, whoever sent it.
+// Queries over MaxEntries or MaxRawRange are rejected, and the pages and points of the others
+// are lowered to the limits. A zero limit is no limit.
+type QueryLimits struct {
+	// MaxEntries is the number of asset properties or aliases of a query
+	MaxEntries int
+	// MaxRawRange is the time range of a query of raw values
+	MaxRawRange time.Duration
+	// MaxPagesPerEntry is the number of pages fetched for each entry, or batch of entries
+	MaxPagesPerEntry int
+	// MaxPoints is the number of rows of the frames of a response
+	MaxPoints int
+	// Timeout is the time a query may take, including the queries it adds such as lastObservation
+	Timeout time.Duration
 }
 
 func (s *AWSSiteWiseDataSourceSetting) Load(config backend.DataSourceInstanceSettings) error {
@@ -73,6 +156,83 @@ func (s *AWSSiteWiseDataSourceSetting) Validate() error {
 	return nil
 }
 
+func (s *AWSSiteWiseDataSourceSetting) GetFetchAllBudget() FetchAllBudget {
+	budget := FetchAllBudget{
+		MaxRounds: DefaultFetchAllMaxRounds,
+		MaxPoints: DefaultFetchAllMaxPoints,
+		Timeout:   DefaultFetchAllTimeoutSec * time.Second,
+	}
+	if s.FetchAllMaxRounds > 0 {
+		budget.MaxRounds = s.FetchAllMaxRounds
+	}
+	if s.FetchAllMaxPoints > 0 {
+		budget.MaxPoints = s.FetchAllMaxPoints
+	}
+	if s.FetchAllTimeoutSec > 0 {
+		budget.Timeout = time.Duration(s.FetchAllTimeoutSec) * time.Second
+	}
+	return budget
+}
+
+func (s *AWSSiteWiseDataSourceSetting) GetQueryLimits() QueryLimits {
+	return QueryLimits{
+		MaxEntries:       s.MaxEntriesPerQuery,
+		MaxRawRange:      time.Duration(s.MaxRawRangeHours) * time.Hour,
+		MaxPagesPerEntry: s.MaxPagesPerEntry,
+		MaxPoints:        s.MaxPointsPerResponse,
+		Timeout:          time.Duration(s.QueryTimeoutSec) * time.Second,
+	}
+}
+
+func (s *AWSSiteWiseDataSourceSetting) GetMaxConcurrentQueries() int {
+	if s.MaxConcurrentQueries > 0 {
+		return s.MaxConcurrentQueries
+	}
+	return DefaultMaxConcurrentQueries
+}
+
+func (s *AWSSiteWiseDataSourceSetting) GetMetadataCacheTTL() time.Duration {
+	if s.MetadataCacheTTLSec > 0 {
+		return time.Duration(s.MetadataCacheTTLSec) * time.Second
+	}
+	return DefaultMetadataCacheTTLSec * time.Second
+}
+
+func (s *AWSSiteWiseDataSourceSetting) GetMetadataCacheMaxEntries() int {
+	if s.MetadataCacheMaxEntries > 0 {
+		return s.MetadataCacheMaxEntries
+	}
+	return DefaultMetadataCacheMaxEntries
+}
+
+func (s *AWSSiteWiseDataSourceSetting) GetDataPlaneRateLimit() float64 {
+	if s.DataPlaneRateLimit > 0 {
+		return s.DataPlaneRateLimit
+	}
+	return DefaultDataPlaneRateLimit
+}
+
+func (s *AWSSiteWiseDataSourceSetting) GetControlPlaneRateLimit() float64 {
+	if s.ControlPlaneRateLimit > 0 {
+		return s.ControlPlaneRateLimit
+	}
+	return DefaultControlPlaneRateLimit
+}
+
+func (s *AWSSiteWiseDataSourceSetting) GetThrottleMaxRetries() int {
+	if s.ThrottleMaxRetries > 0 {
+		return s.ThrottleMaxRetries
+	}
+	return DefaultThrottleMaxRetries
+}
+
+func (s *AWSSiteWiseDataSourceSetting) GetEdgeAuthRenewalMargin() time.Duration {
+	if s.EdgeAuthRenewalMarginSec > 0 {
+		return time.Duration(s.EdgeAuthRenewalMarginSec) * time.Second
+	}
+	return DefaultEdgeAuthRenewalMarginSec * time.Second
+}
+
 func (s *AWSSiteWiseDataSourceSetting) ToAWSDatasourceSettings() awsds.AWSDatasourceSettings {
 	cfg := awsds.AWSDatasourceSettings{
 		Profile:       s.Profile,
@@ -6,15 +6,14 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
-	"github.com/patrickmn/go-cache"
 )
 
 type cachingResourceProvider struct {
 	resources *SitewiseResources
-	cache     *cache.Cache
+	cache     Cache
 }
 
-func NewCachingResourceProvider(resources *SitewiseResources, c *cache.Cache) *cachingResourceProvider {
+func NewCachingResourceProvider(resources *SitewiseResources, c Cache) *cachingResourceProvider {
 	return &cachingResourceProvider{
 		resources: resources,
 		cache:     c,
@@ -22,7 +21,7 @@ func NewCachingResourceProvider(resources *SitewiseResources, c *cache.Cache) *c
 }
 
 func (cp *cachingResourceProvider) Asset(ctx context.Context, assetId string) (*iotsitewise.DescribeAssetOutput, error) {
-	val, ok := cp.cache.Get(assetId)
+	val, ok := cp.cache.Get("asset/" + assetId)
 	if ok {
 		a, ok := val.(iotsitewise.DescribeAssetOutput)
 		if ok {
@@ -34,14 +33,14 @@ func (cp *cachingResourceProvider) Asset(ctx context.Context, assetId string) (*
 	if err != nil {
 		return nil, err
 	}
-	cp.cache.Set(assetId, *a, -1)
+	cp.cache.Set("asset/"+assetId, *a)
 	return a, nil
 }
 
 func (cp *cachingResourceProvider) Property(ctx context.Context, assetId string, propertyId string, propertyAlias string) (*iotsitewise.DescribeAssetPropertyOutput, error) {
-	key := assetId + "/" + propertyId
+	key := "property/" + assetId + "/" + propertyId
 	if propertyAlias != "" {
-		key = propertyAlias
+		key = "alias/" + propertyAlias
 	}
 	val, ok := cp.cache.Get(key)
 	if ok {
@@ -58,12 +57,12 @@ func (cp *cachingResourceProvider) Property(ctx context.Context, assetId string,
 	if err != nil {
 		return nil, err
 	}
-	cp.cache.Set(key, *a, -1)
+	cp.cache.Set(key, *a)
 	return a, nil
 }
 
 func (cp *cachingResourceProvider) AssetModel(ctx context.Context, modelId string) (*iotsitewise.DescribeAssetModelOutput, error) {
-	val, ok := cp.cache.Get(modelId)
+	val, ok := cp.cache.Get("model/" + modelId)
 	if ok {
 		a, ok := val.(iotsitewise.DescribeAssetModelOutput)
 		if ok {
@@ -75,6 +74,6 @@ func (cp *cachingResourceProvider) AssetModel(ctx context.Context, modelId strin
 	if err != nil {
 		return nil, err
 	}
-	cp.cache.Set(modelId, *a, -1)
+	cp.cache.Set("model/"+modelId, *a)
 	return a, nil
 }
@@ -3,11 +3,10 @@ package resource
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
 	"github.com/grafana/iot-sitewise-datasource/pkg/testdata"
-
-	"github.com/patrickmn/go-cache"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -18,7 +17,7 @@ func tdpath(filename string) string {
 
 func setupMocks() (*mocks.SitewiseAPIClient, *cachingResourceProvider) {
 	client := &mocks.SitewiseAPIClient{}
-	c := cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	c := NewMetadataCache(time.Minute, 100).Region("us-east-1")
 	return client, NewCachingResourceProvider(&SitewiseResources{client}, c)
 }
 
@@ -0,0 +1,191 @@
+package resource
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/metrics"
+)
+
+// Cache stores SiteWise metadata by key
+type Cache interface {
+	Get(key string) (any, bool)
+	Set(key string, value any)
+	// Load returns the cached value of the key, or stores the value returned by fn. Concurrent
+	// loads of the same key share a single call of fn.
+	Load(key string, fn func() (any, error)) (any, error)
+}
+
+// MetadataCache is a size bounded LRU cache of SiteWise metadata. Entries expire after the TTL.
+// A datasource instance owns its cache and reads it through a Region view, so metadata of
+// different accounts or regions never mixes.
+type MetadataCache struct {
+	// DatasourceUID labels the hit and miss metrics of the cache
+	DatasourceUID string
+
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	lru     *list.List
+	items   map[string]*list.Element
+	now     func() time.Time
+	group   singleflight.Group
+}
+
+type metadataCacheItem struct {
+	key     string
+	value   any
+	expires time.Time
+}
+
+func NewMetadataCache(ttl time.Duration, maxSize int) *MetadataCache {
+	return &MetadataCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		lru:     list.New(),
+		items:   map[string]*list.Element{},
+		now:     time.Now,
+	}
+}
+
+func (c *MetadataCache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := el.Value.(*metadataCacheItem)
+	if !c.now().Before(item.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return item.value, true
+}
+
+func (c *MetadataCache) Set(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		item := el.Value.(*metadataCacheItem)
+		item.value = value
+		item.expires = expires
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(&metadataCacheItem{key: key, value: value, expires: expires})
+	for c.maxSize > 0 && c.lru.Len() > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *MetadataCache) Load(key string, fn func() (any, error)) (any, error) {
+	value, _, err := c.load(key, fn)
+	return value, err
+}
+
+// load is Load, and also returns whether the value was cached (hit), loaded with fn (miss), or
+// shared with a concurrent load of the key (shared)
+func (c *MetadataCache) load(key string, fn func() (any, error)) (any, string, error) {
+	if value, ok := c.Get(key); ok {
+		return value, metrics.CacheHit, nil
+	}
+	result := metrics.CacheShared
+	value, err, _ := c.group.Do(key, func() (any, error) {
+		if value, ok := c.Get(key); ok {
+			result = metrics.CacheHit
+			return value, nil
+		}
+		result = metrics.CacheMiss
+		value, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		c.Set(key, value)
+		return value, nil
+	})
+	return value, result, err
+}
+
+// Invalidate removes the entries of a region, or every entry if region is empty
+func (c *MetadataCache) Invalidate(region string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if region == "" {
+		c.lru.Init()
+		c.items = map[string]*list.Element{}
+		return
+	}
+	prefix := regionKeyPrefix(region)
+	for key, el := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(el)
+		}
+	}
+}
+
+func (c *MetadataCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *MetadataCache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.items, el.Value.(*metadataCacheItem).key)
+}
+
+// Region returns a view of the cache with the keys scoped to a region
+func (c *MetadataCache) Region(region string) Cache {
+	return &regionCache{
+		cache:   c,
+		prefix:  regionKeyPrefix(region),
+		metrics: metrics.Labels{DatasourceUID: c.DatasourceUID, Region: region},
+	}
+}
+
+func regionKeyPrefix(region string) string {
+	return region + "|"
+}
+
+type regionCache struct {
+	cache   *MetadataCache
+	prefix  string
+	metrics metrics.Labels
+}
+
+func (rc *regionCache) Get(key string) (any, bool) {
+	value, ok := rc.cache.Get(rc.prefix + key)
+	result := metrics.CacheMiss
+	if ok {
+		result = metrics.CacheHit
+	}
+	rc.metrics.MetadataCacheRequest(cacheKeyKind(key), result)
+	return value, ok
+}
+
+func (rc *regionCache) Set(key string, value any) {
+	rc.cache.Set(rc.prefix+key, value)
+}
+
+func (rc *regionCache) Load(key string, fn func() (any, error)) (any, error) {
+	value, result, err := rc.cache.load(rc.prefix+key, fn)
+	rc.metrics.MetadataCacheRequest(cacheKeyKind(key), result)
+	return value, err
+}
+
+// cacheKeyKind returns the kind of metadata of a key, such as asset or model
+func cacheKeyKind(key string) string {
+	kind, _, _ := strings.Cut(key, "/")
+	return kind
+}
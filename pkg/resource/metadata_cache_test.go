@@ -0,0 +1,128 @@
+package resource
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/metrics"
+)
+
+func TestMetadataCache(t *testing.T) {
+	t.Run("evicts the least recently used entry", func(t *testing.T) {
+		cache := NewMetadataCache(time.Minute, 2)
+		cache.Set("a", 1)
+		cache.Set("b", 2)
+		_, _ = cache.Get("a")
+		cache.Set("c", 3)
+
+		require.Equal(t, 2, cache.Len())
+		_, ok := cache.Get("b")
+		require.False(t, ok)
+		v, ok := cache.Get("a")
+		require.True(t, ok)
+		require.Equal(t, 1, v)
+	})
+
+	t.Run("entries expire after the ttl", func(t *testing.T) {
+		now := time.Unix(0, 0)
+		cache := NewMetadataCache(time.Minute, 10)
+		cache.now = func() time.Time { return now }
+		cache.Set("a", 1)
+
+		now = now.Add(59 * time.Second)
+		_, ok := cache.Get("a")
+		require.True(t, ok)
+
+		now = now.Add(time.Second)
+		_, ok = cache.Get("a")
+		require.False(t, ok)
+		require.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("regions do not share entries", func(t *testing.T) {
+		cache := NewMetadataCache(time.Minute, 10)
+		cache.Region("us-east-1").Set("asset/1", "east")
+		cache.Region("eu-west-1").Set("asset/1", "west")
+
+		v, _ := cache.Region("us-east-1").Get("asset/1")
+		require.Equal(t, "east", v)
+		v, _ = cache.Region("eu-west-1").Get("asset/1")
+		require.Equal(t, "west", v)
+	})
+
+	t.Run("invalidate drops a region or everything", func(t *testing.T) {
+		cache := NewMetadataCache(time.Minute, 10)
+		cache.Region("us-east-1").Set("asset/1", "east")
+		cache.Region("eu-west-1").Set("asset/1", "west")
+
+		cache.Invalidate("us-east-1")
+		_, ok := cache.Region("us-east-1").Get("asset/1")
+		require.False(t, ok)
+		_, ok = cache.Region("eu-west-1").Get("asset/1")
+		require.True(t, ok)
+
+		cache.Invalidate("")
+		require.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("lookups are counted by kind", func(t *testing.T) {
+		cache := NewMetadataCache(time.Minute, 10)
+		cache.DatasourceUID = "cache-metrics-test"
+		region := cache.Region("us-east-1")
+
+		_, _ = region.Get("asset/1")
+		region.Set("asset/1", "asset")
+		_, _ = region.Get("asset/1")
+		_, _ = region.Load("model/1", func() (any, error) { return "model", nil })
+
+		requests := func(kind, result string) float64 {
+			return testutil.ToFloat64(metrics.MetadataCacheRequests.WithLabelValues("cache-metrics-test", "us-east-1", kind, result))
+		}
+		require.Equal(t, float64(1), requests("asset", "hit"))
+		require.Equal(t, float64(1), requests("asset", "miss"))
+		require.Equal(t, float64(1), requests("model", "miss"))
+	})
+
+	t.Run("loads that wait for a concurrent load are counted as shared", func(t *testing.T) {
+		cache := NewMetadataCache(time.Minute, 10)
+		cache.DatasourceUID = "cache-shared-metrics-test"
+		region := cache.Region("us-east-1")
+
+		started, release := make(chan struct{}), make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = region.Load("asset/1", func() (any, error) {
+				close(started)
+				<-release
+				return "asset", nil
+			})
+		}()
+		<-started
+
+		waiters := sync.WaitGroup{}
+		for range 3 {
+			waiters.Add(1)
+			go func() {
+				defer waiters.Done()
+				_, _ = region.Load("asset/1", func() (any, error) { return "asset", nil })
+			}()
+		}
+		requests := func(result string) float64 {
+			return testutil.ToFloat64(metrics.MetadataCacheRequests.WithLabelValues("cache-shared-metrics-test", "us-east-1", "asset", result))
+		}
+		// give the waiters time to join the load in progress
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		<-done
+		waiters.Wait()
+
+		require.Equal(t, float64(1), requests("miss"))
+		require.Equal(t, float64(3), requests("shared"))
+		require.Equal(t, float64(0), requests("hit"))
+	})
+}
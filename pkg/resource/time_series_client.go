@@ -0,0 +1,70 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+)
+
+// describeTimeSeriesTimeout bounds the DescribeTimeSeries call of an alias, which is shared by
+// every query that loads the alias at the same time
+const describeTimeSeriesTimeout = 30 * time.Second
+
+// timeSeriesCachingClient resolves property aliases through the metadata cache, so that the
+// alias of a query is described once per cache lifetime instead of on every execution
+type timeSeriesCachingClient struct {
+	client.SitewiseAPIClient
+	cache Cache
+}
+
+// timeSeriesNotFound remembers an alias without a time series
+type timeSeriesNotFound struct {
+	err error
+}
+
+// NewTimeSeriesCachingClient returns a client that caches the DescribeTimeSeries result of
+// every property alias, including aliases of disassociated streams and unknown aliases.
+func NewTimeSeriesCachingClient(sw client.SitewiseAPIClient, cache Cache) client.SitewiseAPIClient {
+	return &timeSeriesCachingClient{
+		SitewiseAPIClient: sw,
+		cache:             cache,
+	}
+}
+
+func (c *timeSeriesCachingClient) DescribeTimeSeries(ctx context.Context, params *iotsitewise.DescribeTimeSeriesInput, optFns ...func(*iotsitewise.Options)) (*iotsitewise.DescribeTimeSeriesOutput, error) {
+	if params == nil || params.Alias == nil || params.AssetId != nil || params.PropertyId != nil {
+		return c.SitewiseAPIClient.DescribeTimeSeries(ctx, params, optFns...)
+	}
+
+	val, err := c.cache.Load("timeseries/"+*params.Alias, func() (any, error) {
+		// the call is not canceled with the query that started it, since the others wait for it
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), describeTimeSeriesTimeout)
+		defer cancel()
+		resp, err := c.SitewiseAPIClient.DescribeTimeSeries(ctx, params, optFns...)
+		if err != nil {
+			var notFound *iotsitewisetypes.ResourceNotFoundException
+			if errors.As(err, &notFound) {
+				return timeSeriesNotFound{err: err}, nil
+			}
+			return nil, err
+		}
+		return *resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := val.(type) {
+	case iotsitewise.DescribeTimeSeriesOutput:
+		return &v, nil
+	case timeSeriesNotFound:
+		return nil, v.err
+	default:
+		return c.SitewiseAPIClient.DescribeTimeSeries(ctx, params, optFns...)
+	}
+}
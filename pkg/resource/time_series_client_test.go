@@ -0,0 +1,114 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+)
+
+func describeAlias(alias string) *iotsitewise.DescribeTimeSeriesInput {
+	return &iotsitewise.DescribeTimeSeriesInput{Alias: aws.String(alias)}
+}
+
+func TestTimeSeriesCachingClient(t *testing.T) {
+	t.Run("concurrent lookups of an alias share one call", func(t *testing.T) {
+		mockSw := &mocks.SitewiseAPIClient{}
+		release := make(chan time.Time)
+		mockSw.On("DescribeTimeSeries", mock.Anything, describeAlias("/plant/temp")).
+			WaitUntil(release).
+			Return(&iotsitewise.DescribeTimeSeriesOutput{
+				Alias:      aws.String("/plant/temp"),
+				AssetId:    aws.String("asset-1"),
+				PropertyId: aws.String("prop-1"),
+				DataType:   iotsitewisetypes.PropertyDataTypeDouble,
+			}, nil).Once()
+
+		sw := NewTimeSeriesCachingClient(mockSw, NewMetadataCache(time.Minute, 10).Region("us-east-1"))
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := sw.DescribeTimeSeries(context.Background(), describeAlias("/plant/temp"))
+				if assert.NoError(t, err) {
+					assert.Equal(t, "asset-1", *resp.AssetId)
+				}
+			}()
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		resp, err := sw.DescribeTimeSeries(context.Background(), describeAlias("/plant/temp"))
+		require.NoError(t, err)
+		require.Equal(t, iotsitewisetypes.PropertyDataTypeDouble, resp.DataType)
+		mockSw.AssertNumberOfCalls(t, "DescribeTimeSeries", 1)
+	})
+
+	t.Run("the shared call is not canceled with the first caller", func(t *testing.T) {
+		mockSw := &mocks.SitewiseAPIClient{}
+		mockSw.On("DescribeTimeSeries", mock.Anything, describeAlias("/plant/speed")).Return(func(ctx context.Context, _ *iotsitewise.DescribeTimeSeriesInput, _ ...func(*iotsitewise.Options)) (*iotsitewise.DescribeTimeSeriesOutput, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if _, ok := ctx.Deadline(); !ok {
+				return nil, errors.New("the call has no timeout")
+			}
+			return &iotsitewise.DescribeTimeSeriesOutput{Alias: aws.String("/plant/speed")}, nil
+		}).Once()
+
+		sw := NewTimeSeriesCachingClient(mockSw, NewMetadataCache(time.Minute, 10).Region("us-east-1"))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		resp, err := sw.DescribeTimeSeries(ctx, describeAlias("/plant/speed"))
+		require.NoError(t, err)
+		require.Equal(t, "/plant/speed", *resp.Alias)
+	})
+
+	t.Run("disassociated streams and unknown aliases are remembered", func(t *testing.T) {
+		mockSw := &mocks.SitewiseAPIClient{}
+		mockSw.On("DescribeTimeSeries", mock.Anything, describeAlias("/plant/raw")).
+			Return(&iotsitewise.DescribeTimeSeriesOutput{Alias: aws.String("/plant/raw")}, nil).Once()
+		mockSw.On("DescribeTimeSeries", mock.Anything, describeAlias("/plant/unknown")).
+			Return(nil, &iotsitewisetypes.ResourceNotFoundException{Message: aws.String("not found")}).Once()
+
+		sw := NewTimeSeriesCachingClient(mockSw, NewMetadataCache(time.Minute, 10).Region("us-east-1"))
+
+		for range 2 {
+			resp, err := sw.DescribeTimeSeries(context.Background(), describeAlias("/plant/raw"))
+			require.NoError(t, err)
+			require.Nil(t, resp.AssetId)
+
+			_, err = sw.DescribeTimeSeries(context.Background(), describeAlias("/plant/unknown"))
+			var notFound *iotsitewisetypes.ResourceNotFoundException
+			require.ErrorAs(t, err, &notFound)
+		}
+		mockSw.AssertExpectations(t)
+	})
+
+	t.Run("other errors are not cached", func(t *testing.T) {
+		mockSw := &mocks.SitewiseAPIClient{}
+		mockSw.On("DescribeTimeSeries", mock.Anything, describeAlias("/plant/temp")).
+			Return(nil, errors.New("throttled")).Twice()
+
+		sw := NewTimeSeriesCachingClient(mockSw, NewMetadataCache(time.Minute, 10).Region("us-east-1"))
+
+		for range 2 {
+			_, err := sw.DescribeTimeSeries(context.Background(), describeAlias("/plant/temp"))
+			require.EqualError(t, err, "throttled")
+		}
+		mockSw.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"math"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+)
+
+type batchedFramesKey struct{}
+
+// batchedQueries are the frames of the queries of a batch plan, and the execution of the plan
+type batchedQueries struct {
+	frames    map[string]data.Frames
+	execution *client.Execution
+}
+
+// planBatches fetches the entries of the queries of the request together, so that queries across
+// RefIDs share batch API calls. The frames of each planned query are kept in the returned context
+// and picked up by batchedFrames. If the plan fails every query is run on its own.
+func (s *Server) planBatches(ctx context.Context, req *backend.QueryDataRequest, queryType string) context.Context {
+	limits := s.Datasource.Cfg.GetQueryLimits()
+	queries := map[string]*models.AssetPropertyValueQuery{}
+	for _, q := range req.Queries {
+		query, err := models.GetAssetPropertyValueQuery(&q)
+		if err != nil || !sitewise.IsBatchPlannable(query) {
+			continue
+		}
+		if queryType != models.QueryTypePropertyValue {
+			applyExpressionLimits(req, query)
+		}
+		// queries over the limits are rejected by their own handler
+		if _, err := applyQueryLimits(query, limits); err != nil {
+			continue
+		}
+		queries[q.RefID] = query
+	}
+	if len(queries) < 2 {
+		return ctx
+	}
+
+	planCtx := ctx
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		planCtx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+	planCtx, execution := client.WithExecution(planCtx)
+	frames, err := s.Datasource.HandleBatchedAssetPropertyValueQueries(planCtx, queryType, queries)
+	if err != nil {
+		log.DefaultLogger.Debug("failed to fetch batched queries, running them on their own", "error", err.Error())
+		return ctx
+	}
+	return context.WithValue(ctx, batchedFramesKey{}, batchedQueries{frames: frames, execution: execution})
+}
+
+// batchedFrames returns the frames of a query fetched by planBatches, or runs fetch for queries
+// that were not part of the plan. The calls of the plan are added to the execution of the query.
+func batchedFrames(ctx context.Context, refID string, fetch func() (data.Frames, error)) (data.Frames, error) {
+	if batched, ok := ctx.Value(batchedFramesKey{}).(batchedQueries); ok {
+		if frames, ok := batched.frames[refID]; ok {
+			client.ExecutionFrom(ctx).Merge(batched.execution)
+			return frames, nil
+		}
+	}
+	return fetch()
+}
+
+// Expressions need to run synchronously so we set MaxPageAggregations
+// and MaxDataPoints to infinity to ensure that the query is not paginated.
+func applyExpressionLimits(req *backend.QueryDataRequest, query *models.AssetPropertyValueQuery) {
+	if isFromExpression(req) {
+		query.MaxPageAggregations = math.MaxInt32
+		query.MaxDataPoints = math.MaxInt32
+	}
+}
+
+// isFromExpression reports whether a request comes from an expression or an alert rule
+func isFromExpression(req *backend.QueryDataRequest) bool {
+	_, isFromExpression := req.Headers["http_X-Grafana-From-Expr"]
+	_, isFromAlert := req.Headers["FromAlert"]
+	return isFromAlert || isFromExpression
+}
@@ -7,17 +7,22 @@ import (
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
 )
 
 type Datasource interface {
-	HealthCheck(ctx context.Context, req *backend.CheckHealthRequest) error
+	HealthCheck(ctx context.Context, req *backend.CheckHealthRequest) (*sitewise.HealthReport, error)
+	EdgeTokenState() (sitewise.EdgeTokenState, bool)
 	HandleInterpolatedPropertyValueQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.AssetPropertyValueQuery) (data.Frames, error)
 	HandleGetAssetPropertyValueHistoryQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error)
 	HandleGetAssetPropertyAggregateQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error)
 	HandleGetAssetPropertyValueQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error)
+	HandleDryRunQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error)
+	HandleBatchedAssetPropertyValueQueries(ctx context.Context, queryType string, queries map[string]*models.AssetPropertyValueQuery) (map[string]data.Frames, error)
 	HandleListAssetModelsQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.ListAssetModelsQuery) (data.Frames, error)
 	HandleListAssetsQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.ListAssetsQuery) (data.Frames, error)
 	HandleDescribeAssetQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.DescribeAssetQuery) (data.Frames, error)
+	HandleDescribeAssetPropertyQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.DescribeAssetPropertyQuery) (data.Frames, error)
 	HandleListAssociatedAssetsQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.ListAssociatedAssetsQuery) (data.Frames, error)
 	HandleDescribeAssetModelQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.DescribeAssetModelQuery) (data.Frames, error)
 	HandleListTimeSeriesQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.ListTimeSeriesQuery) (data.Frames, error)
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+// downsample reduces the raw values of the frames of the queries with a downsample algorithm to
+// about the MaxDataPoints of each query, after fetchAll merged their pages, and notes the ratio of
+// the reduction in their meta. Requests of expressions and alert rules are not downsampled, so
+// that they see every value.
+func (s *Server) downsample(h handler) handler {
+	return func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+		resp, err := h(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if isFromExpression(req) {
+			return resp, nil
+		}
+
+		for _, q := range req.Queries {
+			res, ok := resp.Responses[q.RefID]
+			if !ok || res.Error != nil {
+				continue
+			}
+
+			query, err := models.GetAssetPropertyValueQuery(&q)
+			if err != nil || query.Downsample == "" || query.DryRun {
+				continue
+			}
+
+			for _, frame := range res.Frames {
+				rows := framer.Downsample(frame, query.Downsample, int(q.MaxDataPoints))
+				if rows > frame.Rows() {
+					addDownsampleMeta(frame, query.Downsample, rows)
+				}
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+// addDownsampleMeta notes the algorithm and the ratio of the reduction of a frame that had rows
+// rows in its meta, and in its execution meta if the query recorded its execution
+func addDownsampleMeta(frame *data.Frame, algorithm string, rows int) {
+	if frame.Meta == nil {
+		frame.Meta = &data.FrameMeta{}
+	}
+	meta, _ := customMeta(frame)
+	meta.Downsample = algorithm
+	meta.DownsampleRatio = float64(rows) / float64(frame.Rows())
+	frame.Meta.Custom = meta
+
+	if frame.Meta.ExecutedQueryString != "" {
+		frame.Meta.ExecutedQueryString += fmt.Sprintf("\nDownsample: %d values reduced to %d with %s (%.1fx)", rows, frame.Rows(), algorithm, meta.DownsampleRatio)
+	}
+}
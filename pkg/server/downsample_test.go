@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+func TestDownsample(t *testing.T) {
+	values := make([]iotsitewisetypes.AssetPropertyValue, 10000)
+	for i := range values {
+		values[i] = iotsitewisetypes.AssetPropertyValue{
+			Quality:   iotsitewisetypes.QualityGood,
+			Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(int64(1000 + i))},
+			Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(float64(i % 100))},
+		}
+	}
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetPropertyOutput{
+		AssetId:   aws.String("asset-1"),
+		AssetName: aws.String("Asset 1"),
+		AssetProperty: &iotsitewisetypes.Property{
+			Id:       aws.String("prop-1"),
+			Name:     aws.String("Temperature"),
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+		},
+	}, nil)
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(func(context.Context, *iotsitewise.BatchGetAssetPropertyValueHistoryInput, int, int) *iotsitewise.BatchGetAssetPropertyValueHistoryOutput {
+		return &iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+			SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+				EntryId:                   util.GetEntryIdFromAssetProperty("asset-1", "prop-1"),
+				AssetPropertyValueHistory: append([]iotsitewisetypes.AssetPropertyValue{}, values...),
+			}},
+		}
+	}, nil)
+
+	srvr := &Server{
+		Datasource: &sitewise.Datasource{
+			Cfg: models.AWSSiteWiseDataSourceSetting{
+				AWSDatasourceSettings: awsds.AWSDatasourceSettings{Region: "us-west-2"},
+			},
+			GetClient: func(context.Context, string) (client.SitewiseAPIClient, error) {
+				return mockSw, nil
+			},
+		},
+	}
+	srvr.queryMux = getQueryHandlers(srvr)
+
+	query := func(algorithm string) *backend.QueryDataRequest {
+		return &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{{
+				RefID:         "A",
+				QueryType:     models.QueryTypePropertyValueHistory,
+				MaxDataPoints: 500,
+				TimeRange:     backend.TimeRange{From: time.Unix(1000, 0), To: time.Unix(11000, 0)},
+				JSON:          []byte(fmt.Sprintf(`{"region":"us-west-2","assetIds":["asset-1"],"propertyIds":["prop-1"],"downsample":%q}`, algorithm)),
+			}},
+		}
+	}
+
+	t.Run("frames are downsampled with the ratio in their meta", func(t *testing.T) {
+		qdr, err := srvr.QueryData(context.Background(), query(models.DownsampleMinMax))
+		require.NoError(t, err)
+		res := qdr.Responses["A"]
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+		require.LessOrEqual(t, res.Frames[0].Rows(), 500)
+
+		meta, ok := customMeta(res.Frames[0])
+		require.True(t, ok)
+		require.Equal(t, models.DownsampleMinMax, meta.Downsample)
+		require.Equal(t, float64(10000)/float64(res.Frames[0].Rows()), meta.DownsampleRatio)
+	})
+
+	t.Run("alert rules see every value", func(t *testing.T) {
+		req := query(models.DownsampleLTTB)
+		req.Headers = map[string]string{"FromAlert": "true"}
+		qdr, err := srvr.QueryData(context.Background(), req)
+		require.NoError(t, err)
+		require.NoError(t, qdr.Responses["A"].Error)
+		require.Equal(t, 10000, qdr.Responses["A"].Frames[0].Rows())
+	})
+
+	t.Run("an unknown algorithm fails the query", func(t *testing.T) {
+		qdr, err := srvr.QueryData(context.Background(), query("AVERAGE"))
+		require.NoError(t, err)
+		require.ErrorContains(t, qdr.Responses["A"].Error, `unknown downsample algorithm "AVERAGE": use LTTB or MINMAX`)
+		require.Equal(t, backend.StatusValidationFailed, qdr.Responses["A"].Status)
+	})
+}
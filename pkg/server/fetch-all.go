@@ -0,0 +1,280 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"golang.org/x/sync/errgroup"
+)
+
+// fetchAll follows the next tokens of queries with fetchAll set until every entry is complete
+// or the datasource's FetchAllBudget is spent. Each round runs the query once with the next
+// tokens of the previous one. Other queries are passed through unchanged. The timeout and points
+// of the datasource's QueryLimits apply to all the rounds of a query, not only to each of them as
+// processQueries does.
+func (s *Server) fetchAll(h handler) handler {
+	return func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+		var paged, other []backend.DataQuery
+		for _, q := range req.Queries {
+			if query, err := models.GetAssetPropertyValueQuery(&q); err == nil && query.FetchAll {
+				paged = append(paged, q)
+			} else {
+				other = append(other, q)
+			}
+		}
+		if len(paged) == 0 {
+			return h(ctx, req)
+		}
+
+		resp := backend.NewQueryDataResponse()
+		if len(other) > 0 {
+			otherReq := *req
+			otherReq.Queries = other
+			otherResp, err := h(ctx, &otherReq)
+			if err != nil {
+				return nil, err
+			}
+			for refID, res := range otherResp.Responses {
+				resp.Responses[refID] = res
+			}
+		}
+
+		budget, limits := s.Datasource.Cfg.GetFetchAllBudget(), s.Datasource.Cfg.GetQueryLimits()
+		var mu sync.Mutex
+		eg, ectx := errgroup.WithContext(ctx)
+		eg.SetLimit(s.Datasource.Cfg.GetMaxConcurrentQueries())
+		for _, q := range paged {
+			eg.Go(func() error {
+				qctx := ectx
+				if limits.Timeout > 0 {
+					var cancel context.CancelFunc
+					qctx, cancel = context.WithTimeout(ectx, limits.Timeout)
+					defer cancel()
+				}
+				res, err := fetchAllPages(qctx, req, q, h, budget)
+				if err != nil {
+					return err
+				}
+				truncateResponse(&res, limits.MaxPoints)
+				mu.Lock()
+				resp.Responses[q.RefID] = res
+				mu.Unlock()
+				return nil
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			return nil, err
+		}
+
+		return resp, nil
+	}
+}
+
+func fetchAllPages(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery, h handler, budget models.FetchAllBudget) (backend.DataResponse, error) {
+	var (
+		start  = time.Now()
+		rounds = 0
+		points = 0
+		frames data.Frames
+		// entries that still have a next token, nil before the first page
+		pending map[string]bool
+	)
+
+	for {
+		pageReq := *req
+		pageReq.Queries = []backend.DataQuery{q}
+		pageResp, err := h(ctx, &pageReq)
+		if err != nil {
+			return backend.DataResponse{}, err
+		}
+		res := pageResp.Responses[q.RefID]
+		rounds++
+
+		// Batches without a next token are requested from the start again, so only
+		// the frames of entries that were pending belong to this page
+		page := data.Frames{}
+		for _, frame := range res.Frames {
+			if pending == nil || pending[frameEntryId(frame)] {
+				page = append(page, frame)
+				points += frame.Rows()
+			}
+		}
+		frames = appendMatchingFrames(frames, page)
+
+		if res.Error != nil {
+			res.Frames = frames
+			return res, nil
+		}
+
+		nextToken, nextTokens := getNextTokens(page)
+		if nextToken == "" {
+			return backend.DataResponse{Frames: frames}, nil
+		}
+
+		var exceeded []string
+		if rounds >= budget.MaxRounds {
+			exceeded = append(exceeded, fmt.Sprintf("%d rounds", budget.MaxRounds))
+		}
+		if points >= budget.MaxPoints {
+			exceeded = append(exceeded, fmt.Sprintf("%d points", budget.MaxPoints))
+		}
+		if time.Since(start) >= budget.Timeout {
+			exceeded = append(exceeded, budget.Timeout.String())
+		}
+		if len(exceeded) > 0 {
+			addNotice(frames, data.Notice{
+				Severity: data.NoticeSeverityWarning,
+				Text:     fmt.Sprintf("Results are incomplete: fetching all pages stopped after %d rounds and %d points, the datasource limit of %s was reached", rounds, points, strings.Join(exceeded, ", ")),
+			})
+			clearNextTokens(frames)
+			return backend.DataResponse{Frames: frames}, nil
+		}
+
+		q, err = withNextTokens(q, nextToken, nextTokens)
+		if err != nil {
+			return DataResponseErrorUnmarshal(err), nil
+		}
+		pending = make(map[string]bool, len(nextTokens))
+		for entryId := range nextTokens {
+			pending[entryId] = true
+		}
+		if len(nextTokens) == 0 {
+			pending[""] = true
+		}
+	}
+}
+
+func frameEntryId(frame *data.Frame) string {
+	meta, _ := customMeta(frame)
+	return meta.EntryId
+}
+
+// getNextTokens collects the next tokens of the frames the same way the frontend paginator does
+func getNextTokens(frames data.Frames) (string, map[string]string) {
+	nextToken := ""
+	nextTokens := map[string]string{}
+	for _, frame := range frames {
+		meta, ok := customMeta(frame)
+		if !ok || meta.NextToken == "" {
+			continue
+		}
+		if nextToken == "" {
+			nextToken = meta.NextToken
+		}
+		if meta.EntryId != "" {
+			nextTokens[meta.EntryId] = meta.NextToken
+		}
+	}
+	return nextToken, nextTokens
+}
+
+// clearNextTokens marks the frames with a next token as truncated and drops the token, so that
+// the frontend paginator does not fetch the pages the budget left out
+func clearNextTokens(frames data.Frames) {
+	for _, frame := range frames {
+		if meta, ok := customMeta(frame); ok && meta.NextToken != "" {
+			markTruncated(frame)
+		}
+	}
+}
+
+func withNextTokens(q backend.DataQuery, nextToken string, nextTokens map[string]string) (backend.DataQuery, error) {
+	model := map[string]any{}
+	if err := json.Unmarshal(q.JSON, &model); err != nil {
+		return q, err
+	}
+	model["nextToken"] = nextToken
+	model["nextTokens"] = nextTokens
+
+	var err error
+	q.JSON, err = json.Marshal(model)
+	return q, err
+}
+
+// appendMatchingFrames appends the rows of each frame in b to the frame in frames with the same
+// entry and schema, and adds the others as new frames. The custom meta of the latest page is kept,
+// and the execution stats of the pages are added up.
+func appendMatchingFrames(frames data.Frames, b data.Frames) data.Frames {
+	byKey := make(map[string]*data.Frame, len(frames))
+	for _, frame := range frames {
+		byKey[frameSchemaKey(frame)] = frame
+	}
+
+	for _, frame := range b {
+		existing, ok := byKey[frameSchemaKey(frame)]
+		if !ok {
+			frames = append(frames, frame)
+			byKey[frameSchemaKey(frame)] = frame
+			continue
+		}
+
+		for row := 0; row < frame.Rows(); row++ {
+			for i, field := range frame.Fields {
+				existing.Fields[i].Append(field.At(row))
+			}
+		}
+		if frame.Meta != nil {
+			if existing.Meta == nil {
+				existing.Meta = &data.FrameMeta{}
+			}
+			existing.Meta.Custom = frame.Meta.Custom
+			mergeExecutionMeta(existing.Meta, frame.Meta)
+		}
+	}
+
+	return frames
+}
+
+// mergeExecutionMeta adds up the stats of the pages of a frame, and lists the execution of each page
+func mergeExecutionMeta(meta *data.FrameMeta, page *data.FrameMeta) {
+	for _, stat := range page.Stats {
+		i := slices.IndexFunc(meta.Stats, func(s data.QueryStat) bool {
+			return s.DisplayName == stat.DisplayName
+		})
+		if i < 0 {
+			meta.Stats = append(meta.Stats, stat)
+			continue
+		}
+		meta.Stats[i].Value += stat.Value
+	}
+	switch {
+	case page.ExecutedQueryString == "":
+	case meta.ExecutedQueryString == "":
+		meta.ExecutedQueryString = page.ExecutedQueryString
+	default:
+		meta.ExecutedQueryString += "\n\n" + page.ExecutedQueryString
+	}
+}
+
+func frameSchemaKey(frame *data.Frame) string {
+	var sb strings.Builder
+	sb.WriteString(frameEntryId(frame))
+	sb.WriteString("/")
+	sb.WriteString(frame.Name)
+	for _, field := range frame.Fields {
+		sb.WriteString("|")
+		sb.WriteString(field.Name)
+		sb.WriteString(":")
+		sb.WriteString(field.Type().String())
+		sb.WriteString(field.Labels.String())
+	}
+	return sb.String()
+}
+
+func addNotice(frames data.Frames, notice data.Notice) {
+	if len(frames) == 0 {
+		return
+	}
+	if frames[0].Meta == nil {
+		frames[0].Meta = &data.FrameMeta{}
+	}
+	frames[0].Meta.Notices = append(frames[0].Meta.Notices, notice)
+}
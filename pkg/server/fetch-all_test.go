@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+func mockHistoryPage(mockSw *mocks.SitewiseAPIClient, token string, ts int64, nextToken *string) {
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.MatchedBy(func(req *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+		return util.Dereference(req.NextToken) == token
+	}), mock.Anything, mock.Anything).Return(&iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+		SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+			EntryId: util.GetEntryIdFromAssetProperty("asset-1", "prop-1"),
+			AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{{
+				Quality:   iotsitewisetypes.QualityGood,
+				Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(ts)},
+				Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(float64(ts))},
+			}},
+		}},
+		NextToken: nextToken,
+	}, nil)
+}
+
+func TestFetchAll(t *testing.T) {
+	property := &iotsitewise.DescribeAssetPropertyOutput{
+		AssetId:   aws.String("asset-1"),
+		AssetName: aws.String("Asset 1"),
+		AssetProperty: &iotsitewisetypes.Property{
+			Id:       aws.String("prop-1"),
+			Name:     aws.String("Temperature"),
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+		},
+	}
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(property, nil)
+	mockHistoryPage(mockSw, "", 1000, aws.String("page-2"))
+	mockHistoryPage(mockSw, "page-2", 1001, aws.String("page-3"))
+	mockHistoryPage(mockSw, "page-3", 1002, nil)
+
+	newServer := func(maxRounds int) *Server {
+		return &Server{
+			Datasource: &sitewise.Datasource{
+				Cfg: models.AWSSiteWiseDataSourceSetting{
+					AWSDatasourceSettings: awsds.AWSDatasourceSettings{Region: "us-west-2"},
+					FetchAllMaxRounds:     maxRounds,
+				},
+				GetClient: func(context.Context, string) (client.SitewiseAPIClient, error) {
+					return mockSw, nil
+				},
+			},
+		}
+	}
+
+	req := &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID:     "A",
+			QueryType: models.QueryTypePropertyValueHistory,
+			TimeRange: backend.TimeRange{From: time.Unix(900, 0), To: time.Unix(1100, 0)},
+			JSON:      []byte(`{"region":"us-west-2","assetIds":["asset-1"],"propertyIds":["prop-1"],"fetchAll":true}`),
+		}},
+	}
+
+	t.Run("all pages are merged into one frame", func(t *testing.T) {
+		srvr := newServer(0)
+		qdr, err := srvr.fetchAll(srvr.HandlePropertyValueHistory)(context.Background(), req)
+		require.NoError(t, err)
+
+		res := qdr.Responses["A"]
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+		require.Equal(t, 3, res.Frames[0].Rows())
+		require.Empty(t, res.Frames[0].Meta.Notices)
+
+		meta, ok := customMeta(res.Frames[0])
+		require.True(t, ok)
+		require.Empty(t, meta.NextToken)
+	})
+
+	t.Run("budget truncates the result with a notice", func(t *testing.T) {
+		srvr := newServer(2)
+		qdr, err := srvr.fetchAll(srvr.HandlePropertyValueHistory)(context.Background(), req)
+		require.NoError(t, err)
+
+		res := qdr.Responses["A"]
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+		require.Equal(t, 2, res.Frames[0].Rows())
+		require.Len(t, res.Frames[0].Meta.Notices, 1)
+		require.Equal(t, data.NoticeSeverityWarning, res.Frames[0].Meta.Notices[0].Severity)
+		require.Contains(t, res.Frames[0].Meta.Notices[0].Text, "2 rounds")
+
+		meta, ok := customMeta(res.Frames[0])
+		require.True(t, ok)
+		// the pages left out are not fetched by the frontend either
+		require.Empty(t, meta.NextToken)
+		require.True(t, meta.Truncated)
+	})
+
+	t.Run("the points limit applies to all the pages", func(t *testing.T) {
+		srvr := newServer(0)
+		srvr.Datasource.Cfg.MaxPointsPerResponse = 2
+		srvr.queryMux = getQueryHandlers(srvr)
+		qdr, err := srvr.QueryData(context.Background(), req)
+		require.NoError(t, err)
+
+		res := qdr.Responses["A"]
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+		require.Equal(t, 2, res.Frames[0].Rows())
+		require.Equal(t, "Results are incomplete: the response was truncated from 3 to 2 points, the limit of the datasource", res.Frames[0].Meta.Notices[0].Text)
+
+		meta, ok := customMeta(res.Frames[0])
+		require.True(t, ok)
+		require.True(t, meta.Truncated)
+	})
+
+	t.Run("the timeout applies to all the pages", func(t *testing.T) {
+		// each page takes less than the timeout, and all of them more
+		slowSw := &mocks.SitewiseAPIClient{}
+		slowSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(property, nil)
+		slowSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+			func(ctx context.Context, req *iotsitewise.BatchGetAssetPropertyValueHistoryInput, _ int, _ int) (*iotsitewise.BatchGetAssetPropertyValueHistoryOutput, error) {
+				select {
+				case <-time.After(400 * time.Millisecond):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+				return &iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+					SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{
+						EntryId: req.Entries[0].EntryId,
+						AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{{
+							Quality:   iotsitewisetypes.QualityGood,
+							Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(1000)},
+							Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(1)},
+						}},
+					}},
+					NextToken: aws.String("next"),
+				}, nil
+			})
+		srvr := newServer(0)
+		srvr.Datasource.Cfg.QueryTimeoutSec = 1
+		srvr.Datasource.GetClient = func(context.Context, string) (client.SitewiseAPIClient, error) {
+			return slowSw, nil
+		}
+		qdr, err := srvr.fetchAll(srvr.HandlePropertyValueHistory)(context.Background(), req)
+		require.NoError(t, err)
+
+		res := qdr.Responses["A"]
+		require.ErrorContains(t, res.Error, "the query did not complete within 1s, the limit of the datasource")
+		require.Equal(t, backend.StatusTimeout, res.Status)
+	})
+}
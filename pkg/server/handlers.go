@@ -2,20 +2,75 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"math"
+	"runtime/debug"
+	"slices"
+	"sync"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+	dserrors "github.com/grafana/iot-sitewise-datasource/pkg/errors"
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
 )
 
-func processQueries(ctx context.Context, req *backend.QueryDataRequest, handler QueryHandlerFunc) *backend.QueryDataResponse {
-	res := backend.Responses{}
+// processQueries runs the queries of the request concurrently, up to the datasource's
+// MaxConcurrentQueries at a time, each within the datasource's QueryLimits.
+// Each query gets its own response, also when it panics, and its frames carry
+// how it was executed, see client.Execution.
+func (s *Server) processQueries(ctx context.Context, req *backend.QueryDataRequest, handler QueryHandlerFunc) *backend.QueryDataResponse {
+	var (
+		mu  sync.Mutex
+		res = make(backend.Responses, len(req.Queries))
+		eg  errgroup.Group
+	)
+	eg.SetLimit(s.Datasource.Cfg.GetMaxConcurrentQueries())
+	limits := s.Datasource.Cfg.GetQueryLimits()
+
 	for _, v := range req.Queries {
-		res[v.RefID] = handler(ctx, req, v)
+		q := v
+		eg.Go(func() error {
+			var dr backend.DataResponse
+			qctx, span := util.StartSpan(ctx, "sitewise.query",
+				attribute.String("sitewise.ref_id", q.RefID),
+				attribute.String("sitewise.query_type", q.QueryType),
+			)
+			defer func() {
+				if r := recover(); r != nil {
+					log.DefaultLogger.Error("query panicked", "refID", q.RefID, "panic", r, "stack", string(debug.Stack()))
+					dr = DataResponseErrorRequestFailed(fmt.Errorf("the query failed unexpectedly: %v", r))
+				}
+				util.EndSpan(span, dr.Error)
+				mu.Lock()
+				res[q.RefID] = dr
+				mu.Unlock()
+			}()
+			if limits.Timeout > 0 {
+				var cancel context.CancelFunc
+				qctx, cancel = context.WithTimeout(qctx, limits.Timeout)
+				defer cancel()
+			}
+			qctx, partial := client.WithPartialResults(qctx)
+			qctx, execution := client.WithExecution(qctx)
+			dr = timeoutResponse(qctx, handler(qctx, req, q), limits)
+			truncateResponse(&dr, limits.MaxPoints)
+			execution.Annotate(dr.Frames)
+			for _, text := range partial.Notices() {
+				addNotice(dr.Frames, data.Notice{Severity: data.NoticeSeverityWarning, Text: text})
+			}
+			return nil
+		})
 	}
+	_ = eg.Wait()
 
 	return &backend.QueryDataResponse{
 		Responses: res,
@@ -23,51 +78,58 @@ func processQueries(ctx context.Context, req *backend.QueryDataRequest, handler
 }
 
 func (s *Server) HandleInterpolatedPropertyValue(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleInterpolatedPropertyValueQuery), nil
+	return s.processQueries(ctx, req, s.handleInterpolatedPropertyValueQuery), nil
 }
 
 func (s *Server) HandlePropertyValueHistory(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handlePropertyValueHistoryQuery), nil
+	ctx = s.planBatches(ctx, req, models.QueryTypePropertyValueHistory)
+	return s.processQueries(ctx, req, s.handlePropertyValueHistoryQuery), nil
 }
 
 func (s *Server) HandlePropertyAggregate(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handlePropertyAggregateQuery), nil
+	ctx = s.planBatches(ctx, req, models.QueryTypePropertyAggregate)
+	return s.processQueries(ctx, req, s.handlePropertyAggregateQuery), nil
 }
 
 func (s *Server) HandlePropertyValue(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handlePropertyValueQuery), nil
+	ctx = s.planBatches(ctx, req, models.QueryTypePropertyValue)
+	return s.processQueries(ctx, req, s.handlePropertyValueQuery), nil
 }
 
 func (s *Server) HandleListAssetModels(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleListAssetModelsQuery), nil
+	return s.processQueries(ctx, req, s.handleListAssetModelsQuery), nil
 }
 
 func (s *Server) HandleListAssets(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleListAssetsQuery), nil
+	return s.processQueries(ctx, req, s.handleListAssetsQuery), nil
 }
 
 func (s *Server) HandleDescribeAsset(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleDescribeAssetQuery), nil
+	return s.processQueries(ctx, req, s.handleDescribeAssetQuery), nil
+}
+
+func (s *Server) HandleDescribeAssetProperty(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	return s.processQueries(ctx, req, s.handleDescribeAssetPropertyQuery), nil
 }
 
 func (s *Server) HandleListTimeSeries(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleListTimeSeriesQuery), nil
+	return s.processQueries(ctx, req, s.handleListTimeSeriesQuery), nil
 }
 
 func (s *Server) HandleListAssetProperties(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleListAssetPropertiesQuery), nil
+	return s.processQueries(ctx, req, s.handleListAssetPropertiesQuery), nil
 }
 
 func (s *Server) HandleListAssociatedAssets(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleListAssociatedAssetsQuery), nil
+	return s.processQueries(ctx, req, s.handleListAssociatedAssetsQuery), nil
 }
 
 func (s *Server) HandleDescribeAssetModel(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleDescribeAssetModelQuery), nil
+	return s.processQueries(ctx, req, s.handleDescribeAssetModelQuery), nil
 }
 
 func (s *Server) HandleExecuteQuery(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	return processQueries(ctx, req, s.handleExecuteQuery), nil
+	return s.processQueries(ctx, req, s.handleExecuteQuery), nil
 }
 
 func (s *Server) handleInterpolatedPropertyValueQuery(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
@@ -76,11 +138,22 @@ func (s *Server) handleInterpolatedPropertyValueQuery(ctx context.Context, req *
 		return DataResponseErrorUnmarshal(err)
 	}
 
+	lowered, err := applyQueryLimits(query, s.Datasource.Cfg.GetQueryLimits())
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
+	if query.DryRun {
+		return s.handleDryRunQuery(ctx, query)
+	}
+
 	frames, err := s.Datasource.HandleInterpolatedPropertyValueQuery(ctx, req, query)
 	if err != nil {
 		return DataResponseErrorRequestFailed(err)
 	}
 
+	lowered.addNotices(ctx, frames)
+
 	return backend.DataResponse{
 		Frames: frames,
 		Error:  nil,
@@ -94,29 +167,35 @@ func (s *Server) handlePropertyValueHistoryQuery(ctx context.Context, req *backe
 		return DataResponseErrorUnmarshal(err)
 	}
 
-	// Expressions need to run synchronously so we set MaxPageAggregations
-	// and MaxDataPoints to infinity to ensure that the query is not paginated.
-	_, isFromExpression := req.Headers["http_X-Grafana-From-Expr"]
-	_, isFromAlert := req.Headers["FromAlert"]
-	if isFromAlert || isFromExpression {
-		query.MaxPageAggregations = math.MaxInt32
-		query.MaxDataPoints = math.MaxInt32
+	applyExpressionLimits(req, query)
+
+	lowered, err := applyQueryLimits(query, s.Datasource.Cfg.GetQueryLimits())
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
+	if query.DryRun {
+		return s.handleDryRunQuery(ctx, query)
 	}
 
-	frames, err := s.Datasource.HandleGetAssetPropertyValueHistoryQuery(ctx, query)
+	frames, err := batchedFrames(ctx, q.RefID, func() (data.Frames, error) {
+		return s.Datasource.HandleGetAssetPropertyValueHistoryQuery(ctx, query)
+	})
 	if err != nil {
 		return DataResponseErrorRequestFailed(err)
 	}
 
 	if len(frames) > 0 && query.ResponseFormat == "timeseries" {
 		for i, frame := range frames {
-			wide, err := data.LongToWide(frame, &data.FillMissing{Mode: data.FillModeNull, Value: math.NaN()})
+			wide, err := longToWide(frame)
 			if err == nil {
 				frames[i] = wide
 			}
 		}
 	}
 
+	lowered.addNotices(ctx, frames)
+
 	return backend.DataResponse{
 		Frames: frames,
 		Error:  nil,
@@ -130,29 +209,35 @@ func (s *Server) handlePropertyAggregateQuery(ctx context.Context, req *backend.
 		return DataResponseErrorUnmarshal(err)
 	}
 
-	// Expressions need to run synchronously so we set MaxPageAggregations
-	// and MaxDataPoints to infinity to ensure that the query is not paginated.
-	_, isFromExpression := req.Headers["http_X-Grafana-From-Expr"]
-	_, isFromAlert := req.Headers["FromAlert"]
-	if isFromAlert || isFromExpression {
-		query.MaxPageAggregations = math.MaxInt32
-		query.MaxDataPoints = math.MaxInt32
+	applyExpressionLimits(req, query)
+
+	lowered, err := applyQueryLimits(query, s.Datasource.Cfg.GetQueryLimits())
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
+	if query.DryRun {
+		return s.handleDryRunQuery(ctx, query)
 	}
 
-	frames, err := s.Datasource.HandleGetAssetPropertyAggregateQuery(ctx, query)
+	frames, err := batchedFrames(ctx, q.RefID, func() (data.Frames, error) {
+		return s.Datasource.HandleGetAssetPropertyAggregateQuery(ctx, query)
+	})
 	if err != nil {
 		return DataResponseErrorRequestFailed(err)
 	}
 
 	if len(frames) > 0 && query.ResponseFormat == "timeseries" {
 		for _, frame := range frames {
-			wide, err := data.LongToWide(frame, &data.FillMissing{Mode: data.FillModeNull, Value: math.NaN()})
+			wide, err := longToWide(frame)
 			if err == nil {
 				frames = []*data.Frame{wide}
 			}
 		}
 	}
 
+	lowered.addNotices(ctx, frames)
+
 	return backend.DataResponse{
 		Frames: frames,
 		Error:  nil,
@@ -166,20 +251,64 @@ func (s *Server) handlePropertyValueQuery(ctx context.Context, req *backend.Quer
 		return DataResponseErrorUnmarshal(err)
 	}
 
-	frames, err := s.Datasource.HandleGetAssetPropertyValueQuery(ctx, query)
+	lowered, err := applyQueryLimits(query, s.Datasource.Cfg.GetQueryLimits())
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
+	if query.DryRun {
+		return s.handleDryRunQuery(ctx, query)
+	}
+
+	// Batch API is not available at the edge, so streams are only supported in the cloud
+	if query.Streaming && query.AwsRegion != sitewise.EDGE_REGION {
+		return s.handlePropertyValueStreamQuery(ctx, query, lowered)
+	}
+
+	frames, err := batchedFrames(ctx, q.RefID, func() (data.Frames, error) {
+		return s.Datasource.HandleGetAssetPropertyValueQuery(ctx, query)
+	})
 	if err != nil {
 		return DataResponseErrorRequestFailed(err)
 	}
 
 	if len(frames) > 0 && query.ResponseFormat == "timeseries" {
 		for _, frame := range frames {
-			wide, err := data.LongToWide(frame, &data.FillMissing{Mode: data.FillModeNull, Value: math.NaN()})
+			wide, err := longToWide(frame)
 			if err == nil {
 				frames = []*data.Frame{wide}
 			}
 		}
 	}
 
+	lowered.addNotices(ctx, frames)
+
+	return backend.DataResponse{
+		Frames: frames,
+		Error:  nil,
+	}
+}
+
+// longToWide converts a frame to the wide time series format. A quality enum is converted to
+// strings first, so that it becomes a label of the values like in the other string fields.
+func longToWide(frame *data.Frame) (*data.Frame, error) {
+	long := frame
+	if i := slices.IndexFunc(frame.Fields, func(f *data.Field) bool {
+		return f.Name == fields.Quality && f.Type() == data.FieldTypeEnum
+	}); i >= 0 {
+		long = &data.Frame{Name: frame.Name, RefID: frame.RefID, Meta: frame.Meta, Fields: slices.Clone(frame.Fields)}
+		long.Fields[i] = fields.QualityStringField(frame.Fields[i])
+	}
+	return data.LongToWide(long, &data.FillMissing{Mode: data.FillModeNull, Value: math.NaN()})
+}
+
+// handleDryRunQuery returns the planned calls of a property query instead of its data
+func (s *Server) handleDryRunQuery(ctx context.Context, query *models.AssetPropertyValueQuery) backend.DataResponse {
+	frames, err := s.Datasource.HandleDryRunQuery(ctx, query)
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
 	return backend.DataResponse{
 		Frames: frames,
 		Error:  nil,
@@ -276,6 +405,23 @@ func (s *Server) handleDescribeAssetQuery(ctx context.Context, req *backend.Quer
 	}
 }
 
+func (s *Server) handleDescribeAssetPropertyQuery(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
+	query, err := models.GetDescribeAssetPropertyQuery(&q)
+	if err != nil {
+		return DataResponseErrorUnmarshal(err)
+	}
+
+	frames, err := s.Datasource.HandleDescribeAssetPropertyQuery(ctx, req, query)
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
+	return backend.DataResponse{
+		Frames: frames,
+		Error:  nil,
+	}
+}
+
 func (s *Server) handleListAssetPropertiesQuery(ctx context.Context, req *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
 	query, err := models.GetListAssetPropertiesQuery(&q)
 	if err != nil {
@@ -321,8 +467,9 @@ func (s *Server) handleExecuteQuery(ctx context.Context, req *backend.QueryDataR
 	query.RawSQL, err = sqlutil.Interpolate(&query.Query, s.Datasource.Macros())
 	if err != nil {
 		log.DefaultLogger.Warn("Error interpolating query", "error", err)
-		return backend.ErrDataResponse(backend.StatusBadRequest, "macro interpolate: "+err.Error())
+		return dserrors.Response(dserrors.Validation(errors.Wrap(err, "macro interpolate")))
 	}
+	client.ExecutionFrom(ctx).SetSQL(query.RawSQL)
 
 	frames, err := s.Datasource.HandleExecuteQuery(ctx, req, query)
 	if err != nil {
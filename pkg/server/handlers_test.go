@@ -2,14 +2,23 @@ package server
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 
 	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
@@ -17,6 +26,9 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestHandlerExecution(t *testing.T) {
@@ -119,3 +131,136 @@ func TestHandlerExecution(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessQueriesConcurrently(t *testing.T) {
+	server := Server{
+		Datasource: &sitewise.Datasource{
+			Cfg: models.AWSSiteWiseDataSourceSetting{MaxConcurrentQueries: 2},
+		},
+	}
+
+	var running, maxRunning atomic.Int32
+	handler := func(_ context.Context, _ *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if q.RefID == "B" {
+			return backend.ErrDataResponse(backend.StatusBadRequest, "bad query")
+		}
+		return backend.DataResponse{Frames: data.Frames{data.NewFrame(q.RefID)}}
+	}
+
+	req := &backend.QueryDataRequest{}
+	for _, refID := range []string{"A", "B", "C", "D", "E"} {
+		req.Queries = append(req.Queries, backend.DataQuery{RefID: refID})
+	}
+
+	res := server.processQueries(context.Background(), req, handler)
+
+	require.Len(t, res.Responses, 5)
+	require.Equal(t, int32(2), maxRunning.Load())
+	require.Error(t, res.Responses["B"].Error)
+	for _, refID := range []string{"A", "C", "D", "E"} {
+		require.NoError(t, res.Responses[refID].Error)
+		require.Equal(t, refID, res.Responses[refID].Frames[0].Name)
+	}
+}
+
+func TestProcessQueriesRecoversFromPanics(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := tracing.DefaultTracer()
+	tracing.InitDefaultTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test"))
+	t.Cleanup(func() { tracing.InitDefaultTracer(previous) })
+
+	server := Server{
+		Datasource: &sitewise.Datasource{},
+	}
+
+	handler := func(_ context.Context, _ *backend.QueryDataRequest, q backend.DataQuery) backend.DataResponse {
+		if q.RefID == "B" {
+			var frames data.Frames
+			return backend.DataResponse{Frames: data.Frames{frames[1]}}
+		}
+		return backend.DataResponse{Frames: data.Frames{data.NewFrame(q.RefID)}}
+	}
+
+	req := &backend.QueryDataRequest{Queries: []backend.DataQuery{{RefID: "A"}, {RefID: "B"}}}
+	res := server.processQueries(context.Background(), req, handler)
+
+	require.Len(t, res.Responses, 2)
+	require.ErrorContains(t, res.Responses["B"].Error, "the query failed unexpectedly")
+	require.Equal(t, backend.StatusInternal, res.Responses["B"].Status)
+	require.NoError(t, res.Responses["A"].Error)
+	require.Equal(t, "A", res.Responses["A"].Frames[0].Name)
+
+	// the span of the query that panicked is ended too
+	require.Len(t, recorder.Ended(), 2)
+	for _, span := range recorder.Ended() {
+		if span.Status().Code == codes.Error {
+			require.Contains(t, span.Status().Description, "the query failed unexpectedly")
+			return
+		}
+	}
+	t.Fatal("no span recorded the panic")
+}
+
+func TestExecuteQueryRecordsTheExecutedSQL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"columns":[{"name":"asset_id","type":{"scalarType":"STRING"}}],"rows":[{"data":[{"scalarValue":"asset-1"}]}]}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	sw := iotsitewise.New(iotsitewise.Options{
+		Region:       "us-west-2",
+		BaseEndpoint: aws.String(ts.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	}, client.WithExecutionStats(), func(o *iotsitewise.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("HostnameImmutable", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				return next.HandleInitialize(smithyhttp.SetHostnameImmutable(ctx, true), in)
+			}), middleware.Before)
+		})
+	})
+	server := Server{
+		Datasource: &sitewise.Datasource{
+			Cfg: models.AWSSiteWiseDataSourceSetting{
+				AWSDatasourceSettings: awsds.AWSDatasourceSettings{Region: "us-west-2"},
+			},
+			GetClient: func(context.Context, string) (client.SitewiseAPIClient, error) {
+				return &client.SitewiseClient{Client: sw}, nil
+			},
+		},
+	}
+
+	res, err := server.HandleExecuteQuery(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID:     "A",
+			TimeRange: backend.TimeRange{From: time.Unix(1700000000, 0), To: time.Unix(1700003600, 0)},
+			JSON:      []byte(`{"rawSQL": "SELECT asset_id FROM asset WHERE $__timeFilter(event_timestamp)"}`),
+		}},
+	})
+	require.NoError(t, err)
+	dr := res.Responses["A"]
+	require.NoError(t, dr.Error)
+	require.Len(t, dr.Frames, 1)
+
+	meta := dr.Frames[0].Meta
+	require.Contains(t, meta.ExecutedQueryString, "Operations: ExecuteQuery x1")
+	require.Contains(t, meta.ExecutedQueryString, "SQL: SELECT asset_id FROM asset WHERE event_timestamp >= TIMESTAMP '2023-11-14 22:13:20'")
+	require.NotContains(t, meta.ExecutedQueryString, "$__timeFilter")
+	stats := map[string]float64{}
+	for _, stat := range meta.Stats {
+		stats[stat.DisplayName] = stat.Value
+	}
+	require.Equal(t, float64(1), stats["ExecuteQuery calls"])
+	require.Contains(t, stats, "Phase fetch")
+	require.Contains(t, stats, "Phase frame")
+}
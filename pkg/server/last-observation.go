@@ -7,6 +7,7 @@ import (
 	"time"
 
 	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
@@ -15,58 +16,71 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api/propvals"
 )
 
+// boundaryQuery is a query with lastObservation, and the request of its boundary observations
+type boundaryQuery struct {
+	query      backend.DataQuery
+	assetQuery *models.AssetPropertyValueQuery
+	resolution string
+}
+
+// lastObservation adds the previous observation before the time range and the next observation
+// after it to the frame of every entry of the queries with lastObservation. The boundary
+// observations of all the queries of a request are fetched with one request per direction, so
+// the entries of the queries are batched together, and are matched to the frames by EntryId.
 func (s *Server) lastObservation(h handler) handler {
 	return func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-		resp := &backend.QueryDataResponse{
-			Responses: make(map[string]backend.DataResponse),
-		}
-
-		queries := make(map[string]backend.DataQuery, len(req.Queries))
-		for _, q := range req.Queries {
-			queries[q.RefID] = q
-		}
-
-		origResp, err := h(ctx, req)
+		resp, err := h(ctx, req)
 		if err != nil {
 			return nil, err
 		}
 
-		for refID, res := range origResp.Responses {
-			if res.Error != nil {
-				resp.Responses[refID] = res
+		queries := []boundaryQuery{}
+		for _, query := range req.Queries {
+			res, ok := resp.Responses[query.RefID]
+			if !ok || res.Error != nil {
 				continue
 			}
 
-			query := queries[refID]
-			resp.Responses[refID] = res
-
-			// ensure this is the last page of data
-			if len(res.Frames) > 0 {
-				if meta, ok := res.Frames[0].Meta.Custom.(models.SitewiseCustomMeta); ok && meta.NextToken != "" {
-					continue
-				}
-			}
-
-			// ensure that this is a supported query type, and that the user requested last observation
+			// ensure that this is a supported query type, and that the user requested last observation of data that was fetched
 			assetQuery, err := models.GetAssetPropertyValueQuery(&query)
-			if err != nil || !assetQuery.LastObservation {
+			if err != nil || !assetQuery.LastObservation || assetQuery.DryRun {
 				continue
 			}
+			queries = append(queries, boundaryQuery{query: query, assetQuery: assetQuery, resolution: boundaryResolution(assetQuery, res.Frames)})
+		}
+		if len(queries) == 0 {
+			return resp, nil
+		}
 
-			lastValueRes, err := s.lastValueQuery(ctx, query, iotsitewisetypes.TimeOrderingDescending)
-			if err != nil {
-				log.DefaultLogger.Debug("failed to fetch last observation", "error", err)
-			}
-			if r, ok := resp.Responses[refID]; err == nil && ok {
-				resp.Responses[refID] = mergeLastValueResponse(r, lastValueRes)
-			}
-
-			nextValueRes, err := s.lastValueQuery(ctx, query, iotsitewisetypes.TimeOrderingAscending)
-			if err != nil {
-				log.DefaultLogger.Debug("failed to fetch next observation", "error", err)
+		start := time.Now()
+		var previous, next map[string]data.Frames
+		eg, ectx := errgroup.WithContext(ctx)
+		eg.Go(func() error {
+			previous = s.boundaryObservations(ectx, req, queries, iotsitewisetypes.TimeOrderingDescending)
+			return nil
+		})
+		eg.Go(func() error {
+			next = s.boundaryObservations(ectx, req, queries, iotsitewisetypes.TimeOrderingAscending)
+			return nil
+		})
+		_ = eg.Wait()
+		took := time.Since(start)
+
+		for _, q := range queries {
+			var rangeStart *time.Time
+			if q.assetQuery.LastObservationAtRangeStart {
+				rangeStart = &q.query.TimeRange.From
 			}
-			if r, ok := resp.Responses[refID]; err == nil && ok {
-				resp.Responses[refID] = mergeLastValueResponse(r, nextValueRes)
+			for _, frame := range resp.Responses[q.query.RefID].Frames {
+				meta, ok := customMeta(frame)
+				// ensure this is the last page of data of the entry
+				if !ok || meta.EntryId == "" || meta.NextToken != "" || meta.Truncated {
+					continue
+				}
+				rows := frame.Rows()
+				mergePreviousObservation(frame, entryFrame(previous[q.query.RefID], meta.EntryId), rangeStart)
+				mergeNextObservation(frame, entryFrame(next[q.query.RefID], meta.EntryId))
+				addBoundaryPointsMeta(frame, rows, took)
 			}
 		}
 
@@ -74,105 +88,204 @@ func (s *Server) lastObservation(h handler) handler {
 	}
 }
 
-func (s *Server) lastValueQuery(ctx context.Context, query backend.DataQuery, timeOrdering iotsitewisetypes.TimeOrdering) (backend.DataResponse, error) {
+// boundaryResolution is the resolution of the boundary queries of a query, the resolution its
+// frames were fetched at, so that a boundary aggregate spans as much time as the others
+func boundaryResolution(query *models.AssetPropertyValueQuery, frames data.Frames) string {
+	if query.Resolution != "" && query.Resolution != "AUTO" {
+		return query.Resolution
+	}
+	if query.QueryType == models.QueryTypePropertyInterpolated {
+		return propvals.InterpolatedResolution(*query)
+	}
+	for _, frame := range frames {
+		if meta, ok := customMeta(frame); ok && meta.Resolution != "" {
+			return meta.Resolution
+		}
+	}
+	return propvals.ResolutionMinute
+}
+
+// boundaryLookback is how far from the time range a boundary query looks for an observation.
+// Interpolated queries have a value at every interval, so they only look one interval away.
+func boundaryLookback(q boundaryQuery) time.Duration {
+	if q.assetQuery.QueryType == models.QueryTypePropertyInterpolated {
+		return propvals.ResolutionToDuration(q.resolution)
+	}
+	return q.assetQuery.GetLastObservationLookback()
+}
+
+// boundaryObservations fetches the previous (descending) or next (ascending) observation of every
+// entry of the queries, and returns the frames of one row of each query by RefID
+func (s *Server) boundaryObservations(ctx context.Context, req *backend.QueryDataRequest, queries []boundaryQuery, timeOrdering iotsitewisetypes.TimeOrdering) map[string]data.Frames {
+	boundaryReq := &backend.QueryDataRequest{PluginContext: req.PluginContext, Headers: req.Headers}
+	for _, q := range queries {
+		query, ok, err := lastValueQuery(q, timeOrdering)
+		if err != nil {
+			log.DefaultLogger.Debug("failed to build last observation query", "error", err)
+		}
+		if ok {
+			boundaryReq.Queries = append(boundaryReq.Queries, query)
+		}
+	}
+	if len(boundaryReq.Queries) == 0 {
+		return nil
+	}
+
+	res, err := s.QueryData(ctx, boundaryReq)
+	if err != nil {
+		log.DefaultLogger.Debug("failed to fetch last observations", "timeOrdering", timeOrdering, "error", err)
+		return nil
+	}
+
+	observations := make(map[string]data.Frames, len(res.Responses))
+	for refID, dataRes := range res.Responses {
+		if dataRes.Error != nil {
+			log.DefaultLogger.Debug("failed to fetch last observations", "refID", refID, "timeOrdering", timeOrdering, "error", dataRes.Error)
+			continue
+		}
+		for _, frame := range dataRes.Frames {
+			if frame.Rows() == 0 {
+				continue
+			}
+			observation := emptyCopy(frame)
+			observation.AppendRow(frame.RowCopy(0)...)
+			observations[refID] = append(observations[refID], observation)
+		}
+	}
+	return observations
+}
+
+// lastValueQuery is the query of one data point of each entry before (descending) or after
+// (ascending) the time range of a query. It returns false when there is no time after the range.
+func lastValueQuery(q boundaryQuery, timeOrdering iotsitewisetypes.TimeOrdering) (backend.DataQuery, bool, error) {
+	query, lookback := q.query, boundaryLookback(q)
 	query.MaxDataPoints = 1
 	switch timeOrdering {
 	case iotsitewisetypes.TimeOrderingDescending:
 		query.TimeRange.To = query.TimeRange.From.Add(-1 * time.Second)
-		query.TimeRange.From = query.TimeRange.From.Add(-8760 * time.Hour) // 1 year ago
+		query.TimeRange.From = query.TimeRange.From.Add(-lookback)
 
 	case iotsitewisetypes.TimeOrderingAscending:
 		query.TimeRange.From = query.TimeRange.To.Add(time.Second)
-		query.TimeRange.To = time.Now()
-
-	}
-	assetQuery, err := models.GetAssetPropertyValueQuery(&query)
-	if err != nil {
-		return backend.DataResponse{}, err
+		query.TimeRange.To = query.TimeRange.To.Add(lookback)
+		if now := time.Now(); query.TimeRange.To.After(now) {
+			query.TimeRange.To = now
+		}
+		if !query.TimeRange.From.Before(query.TimeRange.To) {
+			return query, false, nil
+		}
 	}
+
+	assetQuery := *q.assetQuery
 	assetQuery.NextToken = ""
+	assetQuery.NextTokens = nil
 	assetQuery.TimeOrdering = timeOrdering
 	assetQuery.LastObservation = false
+	assetQuery.FetchAll = false
 	assetQuery.MaxDataPoints = 1
 	assetQuery.MaxPageAggregations = 1
 	assetQuery.TimeRange = query.TimeRange
-	assetQuery.Resolution = propvals.ResolutionMinute
+	assetQuery.Resolution = q.resolution
 
 	log.DefaultLogger.Debug("last observation query", "timeOrdering", timeOrdering, "timeRange", assetQuery.TimeRange)
+	var err error
 	query.JSON, err = json.Marshal(&assetQuery)
 	if err != nil {
-		return backend.DataResponse{}, err
+		return query, false, err
 	}
+	return query, true, nil
+}
 
-	res, err := s.QueryData(ctx, &backend.QueryDataRequest{Queries: []backend.DataQuery{query}})
-	if err != nil {
-		return backend.DataResponse{}, err
+// entryFrame is the frame of an entry, or nil if there is none
+func entryFrame(frames data.Frames, entryId string) *data.Frame {
+	for _, frame := range frames {
+		if meta, ok := customMeta(frame); ok && meta.EntryId == entryId {
+			return frame
+		}
 	}
+	return nil
+}
 
-	dataRes, ok := res.Responses[query.RefID]
-	if !ok || dataRes.Error != nil || len(dataRes.Frames) == 0 || dataRes.Frames[0].Rows() == 0 {
-		return backend.DataResponse{}, fmt.Errorf("no response for query %s", query.RefID)
+// mergePreviousObservation adds the observation before the time range to the frame of an entry.
+// With a range start, the observation is stamped at the start of the range, unless the frame
+// already has a value then.
+func mergePreviousObservation(frame, observation *data.Frame, rangeStart *time.Time) {
+	if !canMerge(frame, observation) {
+		return
 	}
-
-	log.DefaultLogger.Debug("last observation response", "timeOrdering", timeOrdering, "timeRange", assetQuery.TimeRange, "time", getFirstTime(dataRes))
-
-	frame := dataRes.Frames[0].EmptyCopy()
-	frame.AppendRow(dataRes.Frames[0].RowCopy(0)...)
-
-	return backend.DataResponse{Frames: []*data.Frame{frame}}, nil
+	row := observation.RowCopy(0)
+	if rangeStart != nil {
+		if frameHasTime(frame, *rangeStart) {
+			return
+		}
+		setRowTime(observation, row, *rangeStart)
+	}
+	if isDescending(frame) {
+		frame.AppendRow(row...)
+		return
+	}
+	frame.InsertRow(0, row...)
 }
 
-func mergeLastValueResponse(originalRes, lastValueRes backend.DataResponse) backend.DataResponse {
-	// always return the original response if either response has an error
-	if hasError(originalRes) || hasError(lastValueRes) {
-		log.DefaultLogger.Debug("has error", "original", hasError(originalRes), "lastValue", hasError(lastValueRes))
-		return originalRes
+// mergeNextObservation adds the observation after the time range to the frame of an entry
+func mergeNextObservation(frame, observation *data.Frame) {
+	if !canMerge(frame, observation) {
+		return
 	}
-
-	if isEmpty(lastValueRes) {
-		log.DefaultLogger.Debug("last value response is empty")
-		return originalRes
+	if isDescending(frame) {
+		frame.InsertRow(0, observation.RowCopy(0)...)
+		return
 	}
+	frame.AppendRow(observation.RowCopy(0)...)
+}
 
-	if isEmpty(originalRes) {
-		log.DefaultLogger.Debug("original response is empty")
-		return lastValueRes
+// canMerge reports whether the observation can be merged into the frame. A frame without rows
+// takes the fields of the observation, since its own fields may not know the type of the values.
+func canMerge(frame, observation *data.Frame) bool {
+	if observation == nil || observation.Rows() == 0 {
+		return false
 	}
-
-	if !fieldsMatch(originalRes, lastValueRes) {
-		log.DefaultLogger.Debug("fields do not match")
-		return originalRes
+	if frame.Rows() == 0 {
+		frame.Fields = emptyCopy(observation).Fields
 	}
-
-	originalTime := getFirstTime(originalRes)
-	lastValueTime := getFirstTime(lastValueRes)
-	if originalTime.After(lastValueTime) {
-		log.DefaultLogger.Debug("original time is after last value time")
-		originalRes.Frames[0].InsertRow(0, lastValueRes.Frames[0].RowCopy(0)...)
-		return originalRes
+	if !fieldsMatch(frame, observation) {
+		log.DefaultLogger.Debug("fields do not match")
+		return false
 	}
-
-	log.DefaultLogger.Debug("last value time is after original time")
-
-	originalRes.Frames[0].AppendRow(lastValueRes.Frames[0].RowCopy(0)...)
-	return originalRes
+	return true
 }
 
-func hasError(r backend.DataResponse) bool {
-	return r.Error != nil
+// emptyCopy is an empty copy of a frame that keeps its meta and the config of its fields
+func emptyCopy(frame *data.Frame) *data.Frame {
+	empty := frame.EmptyCopy()
+	empty.Meta = frame.Meta
+	for i, field := range frame.Fields {
+		empty.Fields[i].Config = field.Config
+	}
+	return empty
 }
 
-func isEmpty(r backend.DataResponse) bool {
-	return len(r.Frames) == 0 || r.Frames[0].Rows() == 0
+// addBoundaryPointsMeta adds the boundary points merged into a frame, which had rows rows before,
+// and the time spent fetching them to its execution meta, if the query recorded its execution
+func addBoundaryPointsMeta(frame *data.Frame, rows int, took time.Duration) {
+	if frame.Meta == nil || frame.Meta.ExecutedQueryString == "" {
+		return
+	}
+	meta, points := frame.Meta, max(frame.Rows()-rows, 0)
+	meta.Stats = append(meta.Stats,
+		data.QueryStat{FieldConfig: data.FieldConfig{DisplayName: "Boundary points added"}, Value: float64(points)},
+		data.QueryStat{FieldConfig: data.FieldConfig{DisplayName: "Phase lastObservation", Unit: "ms"}, Value: float64(took.Microseconds()) / 1000},
+	)
+	meta.ExecutedQueryString += fmt.Sprintf("\nLast observation: %d boundary points added in %s", points, took.Round(time.Microsecond))
 }
 
-func fieldsMatch(originalRes, lastValueRes backend.DataResponse) bool {
-	if len(originalRes.Frames[0].Fields) != len(lastValueRes.Frames[0].Fields) {
+func fieldsMatch(frame, observation *data.Frame) bool {
+	if len(frame.Fields) != len(observation.Fields) {
 		return false
 	}
 
-	for i := 0; i < len(originalRes.Frames[0].Fields); i++ {
-		if originalRes.Frames[0].Fields[i].Name != lastValueRes.Frames[0].Fields[i].Name {
+	for i := 0; i < len(frame.Fields); i++ {
+		if frame.Fields[i].Name != observation.Fields[i].Name || frame.Fields[i].Type() != observation.Fields[i].Type() {
 			return false
 		}
 	}
@@ -180,20 +293,55 @@ func fieldsMatch(originalRes, lastValueRes backend.DataResponse) bool {
 	return true
 }
 
-func getFirstTime(r backend.DataResponse) time.Time {
-	if len(r.Frames) > 0 && r.Frames[0].Rows() > 0 {
-		for _, f := range r.Frames[0].Fields {
-			if f.Type() == data.FieldTypeTime {
-				if t, ok := f.At(0).(time.Time); ok {
-					return t
-				}
-			}
-			if f.Type() == data.FieldTypeNullableTime {
-				if t, ok := f.At(0).(*time.Time); ok {
-					return *t
-				}
-			}
+// timeField is the index of the first time field of a frame, or -1 if there is none
+func timeField(frame *data.Frame) int {
+	for i, f := range frame.Fields {
+		if f.Type() == data.FieldTypeTime || f.Type() == data.FieldTypeNullableTime {
+			return i
+		}
+	}
+	return -1
+}
+
+func timeAt(frame *data.Frame, row int) time.Time {
+	i := timeField(frame)
+	if i < 0 || row >= frame.Rows() {
+		return time.Time{}
+	}
+	switch t := frame.Fields[i].At(row).(type) {
+	case time.Time:
+		return t
+	case *time.Time:
+		if t != nil {
+			return *t
 		}
 	}
 	return time.Time{}
 }
+
+// isDescending reports whether the rows of a frame are in descending time order
+func isDescending(frame *data.Frame) bool {
+	return frame.Rows() > 1 && timeAt(frame, 0).After(timeAt(frame, frame.Rows()-1))
+}
+
+func frameHasTime(frame *data.Frame, t time.Time) bool {
+	for row := 0; row < frame.Rows(); row++ {
+		if timeAt(frame, row).Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// setRowTime sets the time of a row copied from a frame
+func setRowTime(frame *data.Frame, row []any, t time.Time) {
+	i := timeField(frame)
+	if i < 0 {
+		return
+	}
+	if frame.Fields[i].Type() == data.FieldTypeNullableTime {
+		row[i] = &t
+		return
+	}
+	row[i] = t
+}
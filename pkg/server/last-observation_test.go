@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+)
+
+func TestLastObservation(t *testing.T) {
+	from := time.Unix(1700000000, 0)
+	to := from.Add(200 * time.Second)
+
+	var (
+		mu       sync.Mutex
+		requests []*iotsitewise.BatchGetAssetPropertyValueHistoryInput
+	)
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(func(_ context.Context, input *iotsitewise.DescribeAssetPropertyInput, _ ...func(*iotsitewise.Options)) *iotsitewise.DescribeAssetPropertyOutput {
+		return &iotsitewise.DescribeAssetPropertyOutput{
+			AssetId:   aws.String("asset-1"),
+			AssetName: aws.String("Asset 1"),
+			AssetProperty: &iotsitewisetypes.Property{
+				Id:       input.PropertyId,
+				Name:     input.PropertyId,
+				DataType: iotsitewisetypes.PropertyDataTypeDouble,
+			},
+		}
+	}, nil)
+	// each entry has a value within the time range, and one before and after it
+	mockSw.On("BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(func(_ context.Context, req *iotsitewise.BatchGetAssetPropertyValueHistoryInput, _ int, _ int) *iotsitewise.BatchGetAssetPropertyValueHistoryOutput {
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		out := &iotsitewise.BatchGetAssetPropertyValueHistoryOutput{}
+		for i, entry := range req.Entries {
+			ts := from.Unix() + 50
+			switch {
+			case entry.EndDate.Before(from):
+				ts = from.Unix() - 100 + int64(i)
+			case entry.StartDate.After(to):
+				ts = to.Unix() + 100 + int64(i)
+			}
+			out.SuccessEntries = append(out.SuccessEntries, iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{
+				EntryId: entry.EntryId,
+				AssetPropertyValueHistory: []iotsitewisetypes.AssetPropertyValue{{
+					Quality:   iotsitewisetypes.QualityGood,
+					Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(ts)},
+					Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(float64(ts))},
+				}},
+			})
+		}
+		return out
+	}, nil)
+
+	srvr := &Server{
+		Datasource: &sitewise.Datasource{
+			Cfg: models.AWSSiteWiseDataSourceSetting{
+				AWSDatasourceSettings: awsds.AWSDatasourceSettings{Region: "us-west-2"},
+			},
+			GetClient: func(context.Context, string) (client.SitewiseAPIClient, error) {
+				return mockSw, nil
+			},
+		},
+	}
+	srvr.queryMux = getQueryHandlers(srvr)
+
+	query := func(options string) *backend.QueryDataRequest {
+		return &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{{
+				RefID:     "A",
+				QueryType: models.QueryTypePropertyValueHistory,
+				TimeRange: backend.TimeRange{From: from, To: to},
+				JSON:      []byte(fmt.Sprintf(`{"region":"us-west-2","assetIds":["asset-1"],"propertyIds":["prop-1","prop-2"],"lastObservation":true%s}`, options)),
+			}},
+		}
+	}
+	frameTimes := func(t *testing.T, res backend.DataResponse) [][]time.Time {
+		t.Helper()
+		require.NoError(t, res.Error)
+		times := [][]time.Time{}
+		for _, frame := range res.Frames {
+			frameTimes := []time.Time{}
+			for row := 0; row < frame.Rows(); row++ {
+				frameTimes = append(frameTimes, timeAt(frame, row))
+			}
+			times = append(times, frameTimes)
+		}
+		return times
+	}
+
+	t.Run("every entry gets its previous and next observation", func(t *testing.T) {
+		requests = nil
+		qdr, err := srvr.QueryData(context.Background(), query(""))
+		require.NoError(t, err)
+		require.Equal(t, [][]time.Time{
+			{from.Add(-100 * time.Second), from.Add(50 * time.Second), to.Add(100 * time.Second)},
+			{from.Add(-99 * time.Second), from.Add(50 * time.Second), to.Add(101 * time.Second)},
+		}, frameTimes(t, qdr.Responses["A"]))
+
+		// the boundary observations of the entries are fetched with one batch per direction
+		require.Len(t, requests, 3)
+		for _, req := range requests {
+			require.Len(t, req.Entries, 2)
+		}
+	})
+
+	t.Run("the previous observation is stamped at the range start", func(t *testing.T) {
+		qdr, err := srvr.QueryData(context.Background(), query(`,"lastObservationAtRangeStart":true`))
+		require.NoError(t, err)
+		times := frameTimes(t, qdr.Responses["A"])
+		require.Equal(t, from, times[0][0])
+		require.Equal(t, from, times[1][0])
+	})
+
+	t.Run("the lookback limits the previous observation query", func(t *testing.T) {
+		requests = nil
+		_, err := srvr.QueryData(context.Background(), query(`,"lastObservationLookback":"1h"`))
+		require.NoError(t, err)
+		require.Contains(t, startDates(requests), from.Add(-time.Hour))
+	})
+
+	t.Run("an invalid lookback fails the query", func(t *testing.T) {
+		qdr, err := srvr.QueryData(context.Background(), query(`,"lastObservationLookback":"soon"`))
+		require.NoError(t, err)
+		require.ErrorContains(t, qdr.Responses["A"].Error, `invalid last observation lookback "soon"`)
+		require.Equal(t, backend.StatusValidationFailed, qdr.Responses["A"].Status)
+	})
+}
+
+func startDates(requests []*iotsitewise.BatchGetAssetPropertyValueHistoryInput) []time.Time {
+	dates := []time.Time{}
+	for _, req := range requests {
+		dates = append(dates, *req.Entries[0].StartDate)
+	}
+	return dates
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	dserrors "github.com/grafana/iot-sitewise-datasource/pkg/errors"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+)
+
+// loweredQuery records the limits that lowered the pages and points of a query, so that a notice
+// is only added when they cut the results short
+type loweredQuery struct {
+	maxPages  int
+	maxPoints int
+}
+
+// applyQueryLimits rejects a property query over the entry or raw time range limits of the
+// datasource, and lowers its pages and points to the limits. Zero limits are skipped.
+func applyQueryLimits(query *models.AssetPropertyValueQuery, limits models.QueryLimits) (loweredQuery, error) {
+	entries := len(query.PropertyAliases)
+	if entries == 0 {
+		entries = len(query.AssetIds) * len(query.PropertyIds)
+	}
+	if limits.MaxEntries > 0 && entries > limits.MaxEntries {
+		return loweredQuery{}, dserrors.Validationf("the query has %d entries, more than the limit of %d of the datasource: select fewer assets or properties", entries, limits.MaxEntries)
+	}
+	if limits.MaxRawRange > 0 && query.QueryType == models.QueryTypePropertyValueHistory && query.TimeRange.Duration() > limits.MaxRawRange {
+		return loweredQuery{}, dserrors.Validationf("the time range of %s is longer than the limit of %s of the datasource for raw values: narrow the time range or query aggregates", query.TimeRange.Duration(), limits.MaxRawRange)
+	}
+
+	var lowered loweredQuery
+	if limits.MaxPagesPerEntry > 0 && query.MaxPageAggregations > limits.MaxPagesPerEntry {
+		query.MaxPageAggregations = limits.MaxPagesPerEntry
+		// fetchAll follows the next tokens, within its own budget
+		if !query.FetchAll {
+			lowered.maxPages = limits.MaxPagesPerEntry
+		}
+	}
+	if limits.MaxPoints > 0 && int(query.MaxDataPoints) > limits.MaxPoints {
+		query.MaxDataPoints = int32(limits.MaxPoints)
+		lowered.maxPoints = limits.MaxPoints
+	}
+	return lowered, nil
+}
+
+// addNotices warns when the frames of a lowered query stopped at a limit with data left. The
+// pages limit only stopped the entries that fetched as many pages as the limit, the others
+// stopped at their points or because SiteWise throttled them.
+func (l loweredQuery) addNotices(ctx context.Context, frames data.Frames) {
+	if l.maxPages > 0 {
+		execution := client.ExecutionFrom(ctx)
+		for _, frame := range frames {
+			if meta, ok := customMeta(frame); ok && meta.NextToken != "" && execution.Pages(meta.EntryId) >= l.maxPages {
+				addNotice(frames, data.Notice{
+					Severity: data.NoticeSeverityWarning,
+					Text:     fmt.Sprintf("Results are incomplete: fetching stopped after %d pages per entry, the limit of the datasource", l.maxPages),
+				})
+				break
+			}
+		}
+	}
+	if l.maxPoints > 0 && countRows(frames) >= l.maxPoints {
+		addNotice(frames, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Results may be incomplete: the query was limited to %d points, the limit of the datasource", l.maxPoints),
+		})
+	}
+}
+
+// truncateResponse keeps the first maxPoints rows of the frames of a response, and drops the rest
+// with a notice. A zero maxPoints keeps every row. The frames that lost rows keep their meta and
+// field config, and are marked as truncated.
+func truncateResponse(dr *backend.DataResponse, maxPoints int) {
+	total := countRows(dr.Frames)
+	if maxPoints <= 0 || total <= maxPoints {
+		return
+	}
+
+	remaining := maxPoints
+	for i, frame := range dr.Frames {
+		rows := frame.Rows()
+		if rows <= remaining {
+			remaining -= rows
+			continue
+		}
+		truncated := emptyCopy(frame)
+		for row := range remaining {
+			truncated.AppendRow(frame.RowCopy(row)...)
+		}
+		markTruncated(truncated)
+		dr.Frames[i] = truncated
+		remaining = 0
+	}
+	addNotice(dr.Frames, data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text:     fmt.Sprintf("Results are incomplete: the response was truncated from %d to %d points, the limit of the datasource", total, maxPoints),
+	})
+}
+
+// markTruncated drops the next token of a frame that lost rows, so that the frontend paginator
+// does not continue after rows it never received
+func markTruncated(frame *data.Frame) {
+	meta, ok := customMeta(frame)
+	if !ok {
+		return
+	}
+	meta.NextToken = ""
+	meta.Truncated = true
+	frame.Meta.Custom = meta
+}
+
+// timeoutResponse replaces the response of a query that ran out of time with an error that names
+// the limit
+func timeoutResponse(ctx context.Context, dr backend.DataResponse, limits models.QueryLimits) backend.DataResponse {
+	if dr.Error == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return dr
+	}
+	return dserrors.Response(fmt.Errorf("the query did not complete within %s, the limit of the datasource: %w", limits.Timeout, context.DeadlineExceeded))
+}
+
+func countRows(frames data.Frames) int {
+	rows := 0
+	for _, frame := range frames {
+		rows += frame.Rows()
+	}
+	return rows
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+func limitedServer(cfg models.AWSSiteWiseDataSourceSetting) *Server {
+	cfg.AWSDatasourceSettings = awsds.AWSDatasourceSettings{Region: "us-west-2"}
+	return &Server{Datasource: &sitewise.Datasource{Cfg: cfg}}
+}
+
+func TestQueryLimitsRejectQueriesOverTheLimits(t *testing.T) {
+	srvr := limitedServer(models.AWSSiteWiseDataSourceSetting{MaxEntriesPerQuery: 2, MaxRawRangeHours: 24})
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		queryType string
+		timeRange backend.TimeRange
+		query     string
+		expected  string
+	}{
+		{
+			name:      "too many entries",
+			queryType: models.QueryTypePropertyAggregate,
+			timeRange: backend.TimeRange{From: now.Add(-time.Hour), To: now},
+			query:     `{"assetIds":["a"],"propertyIds":["p1","p2","p3"],"aggregates":["AVERAGE"]}`,
+			expected:  "the query has 3 entries, more than the limit of 2 of the datasource",
+		},
+		{
+			name:      "raw range too long",
+			queryType: models.QueryTypePropertyValueHistory,
+			timeRange: backend.TimeRange{From: now.Add(-48 * time.Hour), To: now},
+			query:     `{"assetIds":["a"],"propertyIds":["p1"]}`,
+			expected:  "the time range of 48h0m0s is longer than the limit of 24h0m0s of the datasource for raw values",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &backend.QueryDataRequest{Queries: []backend.DataQuery{{
+				RefID:     "A",
+				QueryType: tc.queryType,
+				TimeRange: tc.timeRange,
+				JSON:      []byte(tc.query),
+			}}}
+
+			var qdr *backend.QueryDataResponse
+			if tc.queryType == models.QueryTypePropertyValueHistory {
+				qdr, _ = srvr.HandlePropertyValueHistory(context.Background(), req)
+			} else {
+				qdr, _ = srvr.HandlePropertyAggregate(context.Background(), req)
+			}
+			res := qdr.Responses["A"]
+			require.ErrorContains(t, res.Error, tc.expected)
+			require.Equal(t, backend.StatusValidationFailed, res.Status)
+		})
+	}
+}
+
+func TestQueryLimitsTruncateTheResponse(t *testing.T) {
+	srvr := limitedServer(models.AWSSiteWiseDataSourceSetting{MaxPointsPerResponse: 3})
+	handler := func(context.Context, *backend.QueryDataRequest, backend.DataQuery) backend.DataResponse {
+		return backend.DataResponse{Frames: data.Frames{
+			data.NewFrame("a", data.NewField("value", nil, []float64{1, 2})),
+			data.NewFrame("b", data.NewField("value", nil, []float64{3, 4})),
+			data.NewFrame("c", data.NewField("value", nil, []float64{5})),
+		}}
+	}
+
+	res := srvr.processQueries(context.Background(), &backend.QueryDataRequest{Queries: []backend.DataQuery{{RefID: "A"}}}, handler)
+	frames := res.Responses["A"].Frames
+	require.Len(t, frames, 3)
+	require.Equal(t, []int{2, 1, 0}, []int{frames[0].Rows(), frames[1].Rows(), frames[2].Rows()})
+	require.Equal(t, "Results are incomplete: the response was truncated from 5 to 3 points, the limit of the datasource", frames[0].Meta.Notices[0].Text)
+}
+
+func TestQueryLimitsTruncationKeepsTheMetaOfTheFrames(t *testing.T) {
+	frame := func(name string, values []float64) *data.Frame {
+		return data.NewFrame(name,
+			data.NewField("time", nil, make([]time.Time, len(values))),
+			data.NewField("value", data.Labels{"asset": name}, values).SetConfig(&data.FieldConfig{Unit: "m/s"}),
+		).SetMeta(&data.FrameMeta{Custom: models.SitewiseCustomMeta{EntryId: "entry-" + name, NextToken: "next-" + name}})
+	}
+	dr := backend.DataResponse{Frames: data.Frames{frame("a", []float64{1, 2}), frame("b", []float64{3, 4}), frame("c", []float64{5})}}
+	truncateResponse(&dr, 3)
+
+	for i, name := range []string{"a", "b", "c"} {
+		meta, ok := customMeta(dr.Frames[i])
+		require.True(t, ok)
+		require.Equal(t, "entry-"+name, meta.EntryId)
+		require.Equal(t, "m/s", dr.Frames[i].Fields[1].Config.Unit)
+		require.Equal(t, data.Labels{"asset": name}, dr.Frames[i].Fields[1].Labels)
+		// the frames that lost rows are not continued by the paginator
+		if name == "a" {
+			require.Equal(t, "next-a", meta.NextToken)
+			require.False(t, meta.Truncated)
+			continue
+		}
+		require.Empty(t, meta.NextToken)
+		require.True(t, meta.Truncated)
+	}
+}
+
+func TestQueryLimitsTimeOutQueries(t *testing.T) {
+	srvr := limitedServer(models.AWSSiteWiseDataSourceSetting{QueryTimeoutSec: 1})
+	handler := func(ctx context.Context, _ *backend.QueryDataRequest, _ backend.DataQuery) backend.DataResponse {
+		<-ctx.Done()
+		return DataResponseErrorRequestFailed(fmt.Errorf("fetch: %w", ctx.Err()))
+	}
+
+	res := srvr.processQueries(context.Background(), &backend.QueryDataRequest{Queries: []backend.DataQuery{{RefID: "A"}}}, handler)
+	require.EqualError(t, res.Responses["A"].Error, "the query did not complete within 1s, the limit of the datasource: context deadline exceeded")
+	require.Equal(t, backend.StatusTimeout, res.Responses["A"].Status)
+}
+
+func TestQueryLimitsLowerPagesAndPoints(t *testing.T) {
+	limits := models.QueryLimits{MaxEntries: 10, MaxRawRange: time.Hour, MaxPagesPerEntry: 5, MaxPoints: 100}
+	query := &models.AssetPropertyValueQuery{}
+	query.MaxPageAggregations = 50
+	query.MaxDataPoints = 1000
+
+	lowered, err := applyQueryLimits(query, limits)
+	require.NoError(t, err)
+	require.Equal(t, 5, query.MaxPageAggregations)
+	require.Equal(t, int32(100), query.MaxDataPoints)
+
+	// no notice unless the results stopped at a limit
+	ctx, _ := client.WithExecution(context.Background())
+	entryId := *util.GetEntryIdFromAssetProperty("asset", "property")
+	frames := data.Frames{data.NewFrame("a", data.NewField("value", nil, []float64{1})).SetMeta(&data.FrameMeta{Custom: models.SitewiseCustomMeta{EntryId: entryId}})}
+	lowered.addNotices(ctx, frames)
+	require.Empty(t, frames[0].Meta.Notices)
+
+	// an entry with data left that stopped before the pages limit, at its points or throttled
+	frames[0].Meta.Custom = models.SitewiseCustomMeta{EntryId: entryId, NextToken: "next"}
+	lowered.addNotices(ctx, frames)
+	require.Empty(t, frames[0].Meta.Notices)
+
+	_, err = pagingClient(t).GetAssetPropertyValueHistoryPageAggregation(ctx, &iotsitewise.GetAssetPropertyValueHistoryInput{
+		AssetId:    aws.String("asset"),
+		PropertyId: aws.String("property"),
+	}, query.MaxPageAggregations, int(query.MaxDataPoints))
+	require.NoError(t, err)
+	lowered.addNotices(ctx, frames)
+	require.Equal(t, "Results are incomplete: fetching stopped after 5 pages per entry, the limit of the datasource", frames[0].Meta.Notices[0].Text)
+}
+
+func TestQueryLimitsAreOptIn(t *testing.T) {
+	query := &models.AssetPropertyValueQuery{}
+	query.QueryType = models.QueryTypePropertyValueHistory
+	query.TimeRange = backend.TimeRange{From: time.Now().Add(-10 * 366 * 24 * time.Hour), To: time.Now()}
+	query.AssetIds = make([]string, 100)
+	query.PropertyIds = make([]string, 1000)
+	query.MaxPageAggregations = math.MaxInt32
+	query.MaxDataPoints = math.MaxInt32
+
+	limits := (&models.AWSSiteWiseDataSourceSetting{}).GetQueryLimits()
+	require.Equal(t, models.QueryLimits{}, limits)
+	_, err := applyQueryLimits(query, limits)
+	require.NoError(t, err)
+	require.Equal(t, math.MaxInt32, query.MaxPageAggregations)
+	require.Equal(t, int32(math.MaxInt32), query.MaxDataPoints)
+
+	dr := backend.DataResponse{Frames: data.Frames{data.NewFrame("a", data.NewField("value", nil, []float64{1, 2}))}}
+	truncateResponse(&dr, limits.MaxPoints)
+	require.Equal(t, 2, dr.Frames[0].Rows())
+
+	srvr := limitedServer(models.AWSSiteWiseDataSourceSetting{})
+	res := srvr.processQueries(context.Background(), &backend.QueryDataRequest{Queries: []backend.DataQuery{{RefID: "A"}}}, func(ctx context.Context, _ *backend.QueryDataRequest, _ backend.DataQuery) backend.DataResponse {
+		_, ok := ctx.Deadline()
+		require.False(t, ok)
+		return backend.DataResponse{}
+	})
+	require.NoError(t, res.Responses["A"].Error)
+}
+
+// pagingClient is a client of a SiteWise API whose every page has a next token
+func pagingClient(t *testing.T) *client.SitewiseClient {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"assetPropertyValueHistory":[{"value":{"doubleValue":1},"timestamp":{"timeInSeconds":1}}],"nextToken":"next"}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	return &client.SitewiseClient{Client: iotsitewise.New(iotsitewise.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(ts.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	}, client.WithExecutionStats(), func(o *iotsitewise.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("HostnameImmutable", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				return next.HandleInitialize(smithyhttp.SetHostnameImmutable(ctx, true), in)
+			}), middleware.Before)
+		})
+	})}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+
+	dserrors "github.com/grafana/iot-sitewise-datasource/pkg/errors"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+// getResourceHandler creates the handler for the resource API used to browse SiteWise metadata.
+// Every endpoint accepts an optional region parameter. Listings return a models.ResourcePage and
+// accept the nextToken parameter returned by the previous page, except /associated, which is not
+// paginated and returns all the associated assets at once. Invalidating the metadata cache is
+// reserved to admins.
+func getResourceHandler(s *Server) backend.CallResourceHandler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /assets", s.handleAssetsResource)
+	mux.HandleFunc("GET /asset/{id}", s.handleAssetResource)
+	mux.HandleFunc("GET /associated", s.handleAssociatedAssetsResource)
+	mux.HandleFunc("GET /models", s.handleAssetModelsResource)
+	mux.HandleFunc("GET /model/{id}", s.handleAssetModelResource)
+	mux.HandleFunc("GET /properties", s.handlePropertiesResource)
+	mux.HandleFunc("GET /property", s.handlePropertyResource)
+	mux.HandleFunc("GET /timeseries", s.handleTimeSeriesResource)
+	mux.HandleFunc("POST /cache/invalidate", requireAdmin(s.handleInvalidateCacheResource))
+
+	return httpadapter.New(mux)
+}
+
+func writeResourceJSON(w http.ResponseWriter, r *http.Request, res any, err error) {
+	if err != nil {
+		log.DefaultLogger.FromContext(r.Context()).Warn("resource request failed", "path", r.URL.Path, "error", err)
+		writeResourceError(w, int(dserrors.Status(err)), dserrors.Wrap(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.DefaultLogger.FromContext(r.Context()).Error("failed to write resource response", "error", err)
+	}
+}
+
+func writeResourceError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// requireAdmin rejects the requests of users that are not admins of the organization
+func requireAdmin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if user := backend.UserFromContext(r.Context()); user == nil || user.Role != "Admin" {
+			writeResourceError(w, http.StatusForbidden, errors.New("only admins may invalidate the metadata cache"))
+			return
+		}
+		h(w, r)
+	}
+}
+
+func baseQueryFromRequest(r *http.Request) models.BaseQuery {
+	q := r.URL.Query()
+	return models.BaseQuery{
+		AwsRegion: q.Get("region"),
+		NextToken: q.Get("nextToken"),
+	}
+}
+
+func (s *Server) handleAssetsResource(w http.ResponseWriter, r *http.Request) {
+	query := models.ListAssetsQuery{
+		BaseQuery: baseQueryFromRequest(r),
+		ModelId:   r.URL.Query().Get("modelId"),
+		Filter:    iotsitewisetypes.ListAssetsFilter(r.URL.Query().Get("filter")),
+	}
+	res, err := s.Datasource.ListAssetsResource(r.Context(), query)
+	writeResourceJSON(w, r, res, err)
+}
+
+func (s *Server) handleAssetResource(w http.ResponseWriter, r *http.Request) {
+	res, err := s.Datasource.DescribeAssetResource(r.Context(), r.URL.Query().Get("region"), r.PathValue("id"))
+	writeResourceJSON(w, r, res, err)
+}
+
+func (s *Server) handleAssociatedAssetsResource(w http.ResponseWriter, r *http.Request) {
+	assetId := r.URL.Query().Get("assetId")
+	if assetId == "" {
+		writeResourceError(w, http.StatusBadRequest, errors.New("missing assetId"))
+		return
+	}
+
+	loadAllChildren, _ := strconv.ParseBool(r.URL.Query().Get("loadAllChildren"))
+	query := models.ListAssociatedAssetsQuery{
+		BaseQuery:       baseQueryFromRequest(r),
+		HierarchyId:     r.URL.Query().Get("hierarchyId"),
+		LoadAllChildren: loadAllChildren,
+	}
+	query.AssetIds = []string{assetId}
+
+	res, err := s.Datasource.ListAssociatedAssetsResource(r.Context(), query)
+	writeResourceJSON(w, r, res, err)
+}
+
+func (s *Server) handleAssetModelsResource(w http.ResponseWriter, r *http.Request) {
+	query := models.ListAssetModelsQuery{
+		BaseQuery: baseQueryFromRequest(r),
+	}
+	res, err := s.Datasource.ListAssetModelsResource(r.Context(), query)
+	writeResourceJSON(w, r, res, err)
+}
+
+func (s *Server) handleAssetModelResource(w http.ResponseWriter, r *http.Request) {
+	res, err := s.Datasource.DescribeAssetModelResource(r.Context(), r.URL.Query().Get("region"), r.PathValue("id"))
+	writeResourceJSON(w, r, res, err)
+}
+
+func (s *Server) handlePropertiesResource(w http.ResponseWriter, r *http.Request) {
+	assetId := r.URL.Query().Get("assetId")
+	if assetId == "" {
+		writeResourceError(w, http.StatusBadRequest, errors.New("missing assetId"))
+		return
+	}
+
+	res, err := s.Datasource.ListPropertiesResource(r.Context(), r.URL.Query().Get("region"), assetId)
+	writeResourceJSON(w, r, res, err)
+}
+
+func (s *Server) handlePropertyResource(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	assetId, propertyId, propertyAlias := q.Get("assetId"), q.Get("propertyId"), q.Get("propertyAlias")
+	if propertyAlias == "" && (assetId == "" || propertyId == "") {
+		writeResourceError(w, http.StatusBadRequest, errors.New("either propertyAlias or assetId and propertyId are required"))
+		return
+	}
+
+	res, err := s.Datasource.DescribePropertyResource(r.Context(), q.Get("region"), assetId, propertyId, propertyAlias)
+	writeResourceJSON(w, r, res, err)
+}
+
+func (s *Server) handleTimeSeriesResource(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := models.ListTimeSeriesQuery{
+		BaseQuery:      baseQueryFromRequest(r),
+		TimeSeriesType: iotsitewisetypes.ListTimeSeriesType(q.Get("timeSeriesType")),
+		AliasPrefix:    q.Get("aliasPrefix"),
+	}
+	if assetId := q.Get("assetId"); assetId != "" {
+		query.AssetIds = []string{assetId}
+	}
+
+	res, err := s.Datasource.ListTimeSeriesResource(r.Context(), query)
+	writeResourceJSON(w, r, res, err)
+}
+
+// handleInvalidateCacheResource drops the cached metadata of the region, or of every region if
+// no region is given, so that model or asset changes show up before the cache expires
+func (s *Server) handleInvalidateCacheResource(w http.ResponseWriter, r *http.Request) {
+	region := r.URL.Query().Get("region")
+	s.Datasource.InvalidateMetadataCache(region)
+	writeResourceJSON(w, r, map[string]string{"invalidated": region}, nil)
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/aws/smithy-go"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+)
+
+func callResource(t *testing.T, srvr *Server, url string) *backend.CallResourceResponse {
+	t.Helper()
+	return callResourceMethod(t, srvr, http.MethodGet, url)
+}
+
+func callResourceMethod(t *testing.T, srvr *Server, method string, url string) *backend.CallResourceResponse {
+	t.Helper()
+	return callResourceAs(t, srvr, &backend.User{Role: "Viewer"}, method, url)
+}
+
+func callResourceAs(t *testing.T, srvr *Server, user *backend.User, method string, url string) *backend.CallResourceResponse {
+	t.Helper()
+	var res *backend.CallResourceResponse
+	path, _, _ := strings.Cut(url, "?")
+	err := srvr.CallResource(context.Background(), &backend.CallResourceRequest{
+		PluginContext: backend.PluginContext{User: user},
+		Method:        method,
+		Path:          path,
+		URL:           "/api/datasources/uid/resources/" + url,
+	}, backend.CallResourceResponseSenderFunc(func(r *backend.CallResourceResponse) error {
+		res = r
+		return nil
+	}))
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	return res
+}
+
+func TestResourceAPI(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("ListAssets", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListAssetsInput) bool {
+		return *input.AssetModelId == "model-1" && *input.NextToken == "page-2"
+	})).Return(&iotsitewise.ListAssetsOutput{
+		AssetSummaries: []iotsitewisetypes.AssetSummary{{
+			Id:           aws.String("asset-1"),
+			Name:         aws.String("Asset 1"),
+			AssetModelId: aws.String("model-1"),
+		}},
+		NextToken: aws.String("page-3"),
+	}, nil)
+	mockSw.On("DescribeAsset", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetOutput{
+		AssetId:      aws.String("asset-1"),
+		AssetName:    aws.String("Asset 1"),
+		AssetModelId: aws.String("model-1"),
+		AssetProperties: []iotsitewisetypes.AssetProperty{{
+			Id:       aws.String("prop-1"),
+			Name:     aws.String("Temperature"),
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+			Unit:     aws.String("Celsius"),
+		}},
+		AssetCompositeModels: []iotsitewisetypes.AssetCompositeModel{{
+			Id:   aws.String("component-1"),
+			Name: aws.String("Motor"),
+			Properties: []iotsitewisetypes.AssetProperty{{
+				Id:       aws.String("prop-2"),
+				Name:     aws.String("Speed"),
+				DataType: iotsitewisetypes.PropertyDataTypeInteger,
+			}},
+		}},
+	}, nil).Once()
+
+	srvr := &Server{
+		Datasource: &sitewise.Datasource{
+			GetClient: func(context.Context, string) (client.SitewiseAPIClient, error) {
+				return mockSw, nil
+			},
+		},
+	}
+	srvr.resourceHandler = getResourceHandler(srvr)
+
+	t.Run("assets are paginated", func(t *testing.T) {
+		res := callResource(t, srvr, "assets?region=us-east-1&modelId=model-1&nextToken=page-2")
+		require.Equal(t, http.StatusOK, res.Status)
+
+		var page models.ResourcePage[models.AssetSummary]
+		require.NoError(t, json.Unmarshal(res.Body, &page))
+		require.Equal(t, "page-3", page.NextToken)
+		require.Equal(t, []models.AssetSummary{{Id: "asset-1", Name: "Asset 1", ModelId: "model-1"}}, page.Items)
+	})
+
+	t.Run("associated assets are returned in a single page", func(t *testing.T) {
+		mockSw.On("ListAssociatedAssets", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListAssociatedAssetsInput) bool {
+			return input.NextToken == nil
+		})).Return(&iotsitewise.ListAssociatedAssetsOutput{
+			AssetSummaries: []iotsitewisetypes.AssociatedAssetsSummary{{Id: aws.String("child-1"), Name: aws.String("Child 1"), AssetModelId: aws.String("model-2")}},
+			NextToken:      aws.String("page-2"),
+		}, nil).Once()
+		mockSw.On("ListAssociatedAssets", mock.Anything, mock.MatchedBy(func(input *iotsitewise.ListAssociatedAssetsInput) bool {
+			return input.NextToken != nil && *input.NextToken == "page-2"
+		})).Return(&iotsitewise.ListAssociatedAssetsOutput{
+			AssetSummaries: []iotsitewisetypes.AssociatedAssetsSummary{{Id: aws.String("child-2"), Name: aws.String("Child 2"), AssetModelId: aws.String("model-2")}},
+		}, nil).Once()
+
+		res := callResource(t, srvr, "associated?region=us-east-1&assetId=asset-1&hierarchyId=hierarchy-1")
+		require.Equal(t, http.StatusOK, res.Status)
+
+		var page models.ResourcePage[models.AssetSummary]
+		require.NoError(t, json.Unmarshal(res.Body, &page))
+		require.Empty(t, page.NextToken)
+		require.Equal(t, []models.AssetSummary{
+			{Id: "child-1", Name: "Child 1", ModelId: "model-2"},
+			{Id: "child-2", Name: "Child 2", ModelId: "model-2"},
+		}, page.Items)
+	})
+
+	t.Run("asset properties include components and are cached", func(t *testing.T) {
+		res := callResource(t, srvr, "asset/asset-1?region=us-east-1")
+		require.Equal(t, http.StatusOK, res.Status)
+
+		var asset models.AssetDetails
+		require.NoError(t, json.Unmarshal(res.Body, &asset))
+		require.Equal(t, "asset-1", asset.Id)
+		require.Len(t, asset.Properties, 2)
+
+		// served from the cache, DescribeAsset is only mocked once
+		res = callResource(t, srvr, "properties?region=us-east-1&assetId=asset-1")
+		require.Equal(t, http.StatusOK, res.Status)
+
+		var page models.ResourcePage[models.PropertySummary]
+		require.NoError(t, json.Unmarshal(res.Body, &page))
+		require.Equal(t, []models.PropertySummary{
+			{Id: "prop-1", Name: "Temperature", DataType: "DOUBLE", Unit: "Celsius"},
+			{Id: "prop-2", Name: "Speed", DataType: "INTEGER", CompositeModelId: "component-1", CompositeModelName: "Motor"},
+		}, page.Items)
+	})
+
+	t.Run("only admins may invalidate the cache", func(t *testing.T) {
+		res := callResourceMethod(t, srvr, http.MethodPost, "cache/invalidate?region=us-east-1")
+		require.Equal(t, http.StatusForbidden, res.Status)
+	})
+
+	t.Run("invalidating the cache describes the asset again", func(t *testing.T) {
+		res := callResourceAs(t, srvr, &backend.User{Role: "Admin"}, http.MethodPost, "cache/invalidate?region=us-east-1")
+		require.Equal(t, http.StatusOK, res.Status)
+
+		mockSw.On("DescribeAsset", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetOutput{
+			AssetId:      aws.String("asset-1"),
+			AssetName:    aws.String("Asset 1 renamed"),
+			AssetModelId: aws.String("model-1"),
+		}, nil).Once()
+
+		res = callResource(t, srvr, "asset/asset-1?region=us-east-1")
+		require.Equal(t, http.StatusOK, res.Status)
+
+		var asset models.AssetDetails
+		require.NoError(t, json.Unmarshal(res.Body, &asset))
+		require.Equal(t, "Asset 1 renamed", asset.Name)
+	})
+
+	t.Run("missing parameters are rejected", func(t *testing.T) {
+		res := callResource(t, srvr, "properties")
+		require.Equal(t, http.StatusBadRequest, res.Status)
+		require.JSONEq(t, `{"error":"missing assetId"}`, string(res.Body))
+
+		res = callResource(t, srvr, "property?assetId=asset-1")
+		require.Equal(t, http.StatusBadRequest, res.Status)
+	})
+
+	t.Run("errors of SiteWise keep their status", func(t *testing.T) {
+		mockSw.On("DescribeAssetModel", mock.Anything, mock.Anything).Return(nil, &smithy.GenericAPIError{Code: "ResourceNotFoundException", Message: "model not found"}).Once()
+		res := callResource(t, srvr, "model/unknown?region=us-east-1")
+		require.Equal(t, http.StatusNotFound, res.Status)
+
+		mockSw.On("ListAssetModels", mock.Anything, mock.Anything).Return(nil, &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "denied"}).Once()
+		res = callResource(t, srvr, "models?region=us-east-1")
+		require.Equal(t, http.StatusForbidden, res.Status)
+	})
+
+	t.Run("unknown resources are not found", func(t *testing.T) {
+		res := callResource(t, srvr, "unknown")
+		require.Equal(t, http.StatusNotFound, res.Status)
+	})
+}
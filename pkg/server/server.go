@@ -2,8 +2,12 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 
+	dserrors "github.com/grafana/iot-sitewise-datasource/pkg/errors"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
@@ -12,7 +16,9 @@ import (
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Server struct {
@@ -20,6 +26,11 @@ type Server struct {
 	channelPrefix string
 	closeCh       chan struct{}
 	queryMux      *datasource.QueryTypeMux
+
+	resourceHandler backend.CallResourceHandler
+
+	streamsOnce   sync.Once
+	streamManager *streamManager
 }
 
 // Make sure SampleDatasource implements required interfaces.
@@ -28,6 +39,8 @@ type Server struct {
 var (
 	_ backend.QueryDataHandler      = (*Server)(nil)
 	_ backend.CheckHealthHandler    = (*Server)(nil)
+	_ backend.CallResourceHandler   = (*Server)(nil)
+	_ backend.StreamHandler         = (*Server)(nil)
 	_ instancemgmt.InstanceDisposer = (*Server)(nil)
 )
 
@@ -36,17 +49,15 @@ var (
 // type QueryDataHandlerFunc func(ctx context.Context, req *QueryDataRequest) (*QueryDataResponse, error)
 type QueryHandlerFunc func(context.Context, *backend.QueryDataRequest, backend.DataQuery) backend.DataResponse
 
+// DataResponseErrorUnmarshal is the response of a query that could not be parsed
 func DataResponseErrorUnmarshal(err error) backend.DataResponse {
-	return backend.DataResponse{
-		Error: errors.Wrap(err, "failed to unmarshal JSON request into query"),
-	}
+	return dserrors.Response(dserrors.Validation(errors.Wrap(err, "failed to unmarshal JSON request into query")))
 }
 
+// DataResponseErrorRequestFailed is the response of a query that failed, with the error source
+// and status of the error
 func DataResponseErrorRequestFailed(err error) backend.DataResponse {
-	return backend.DataResponse{
-		Error:       errors.Wrap(err, "failed to fetch query data"),
-		ErrorSource: backend.ErrorSourceDownstream,
-	}
+	return dserrors.Response(err)
 }
 
 type handler func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error)
@@ -55,14 +66,15 @@ type handler func(ctx context.Context, req *backend.QueryDataRequest) (*backend.
 func getQueryHandlers(s *Server) *datasource.QueryTypeMux {
 	mux := datasource.NewQueryTypeMux()
 
-	mux.HandleFunc(models.QueryTypePropertyValueHistory, s.lastObservation(s.HandlePropertyValueHistory))
-	mux.HandleFunc(models.QueryTypePropertyAggregate, s.lastObservation(s.HandlePropertyAggregate))
-	mux.HandleFunc(models.QueryTypePropertyInterpolated, s.lastObservation(s.HandleInterpolatedPropertyValue))
+	mux.HandleFunc(models.QueryTypePropertyValueHistory, s.lastObservation(s.downsample(s.fetchAll(s.HandlePropertyValueHistory))))
+	mux.HandleFunc(models.QueryTypePropertyAggregate, s.lastObservation(s.fetchAll(s.HandlePropertyAggregate)))
+	mux.HandleFunc(models.QueryTypePropertyInterpolated, s.lastObservation(s.fetchAll(s.HandleInterpolatedPropertyValue)))
 	mux.HandleFunc(models.QueryTypePropertyValue, s.HandlePropertyValue)
 	mux.HandleFunc(models.QueryTypeListAssetModels, s.HandleListAssetModels)
 	mux.HandleFunc(models.QueryTypeListAssociatedAssets, s.HandleListAssociatedAssets)
 	mux.HandleFunc(models.QueryTypeListAssets, s.HandleListAssets)
 	mux.HandleFunc(models.QueryTypeDescribeAsset, s.HandleDescribeAsset)
+	mux.HandleFunc(models.QueryTypeDescribeAssetProperty, s.HandleDescribeAssetProperty)
 	mux.HandleFunc(models.QueryTypeListAssetProperties, s.HandleListAssetProperties)
 	mux.HandleFunc(models.QueryTypeListTimeSeries, s.HandleListTimeSeries)
 	mux.HandleFunc(models.QueryTypeExecuteQuery, s.HandleExecuteQuery)
@@ -77,10 +89,11 @@ func NewServerInstance(ctx context.Context, settings backend.DataSourceInstanceS
 	}
 	srvr := &Server{
 		Datasource:    ds,
-		channelPrefix: fmt.Sprintf("ds/%d/", settings.ID),
+		channelPrefix: fmt.Sprintf("ds/%s/", settings.UID),
 		closeCh:       make(chan struct{}),
 	}
 	srvr.queryMux = getQueryHandlers(srvr) // init once
+	srvr.resourceHandler = getResourceHandler(srvr)
 	return srvr, nil
 }
 
@@ -88,25 +101,50 @@ func NewServerInstance(ctx context.Context, settings backend.DataSourceInstanceS
 // req contains the queries []DataQuery (where each query contains RefID as a unique identifier).
 // The QueryDataResponse contains a map of RefID to the response for each query, and each response
 // contains Frames ([]*Frame).
-func (s *Server) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+func (s *Server) QueryData(ctx context.Context, req *backend.QueryDataRequest) (res *backend.QueryDataResponse, err error) {
+	ctx, span := util.StartSpan(ctx, "sitewise.QueryData", attribute.Int("sitewise.queries", len(req.Queries)))
+	defer func() { util.EndSpan(span, err) }()
 	return s.queryMux.QueryData(ctx, req)
 }
 
+// CallResource handles the resource API used by the query editor to browse SiteWise metadata.
+func (s *Server) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	return s.resourceHandler.CallResource(ctx, req, sender)
+}
+
 // CheckHealth handles health checks sent from Grafana to the plugin.
 // The main use case for these health checks is the test button on the
 // datasource configuration page which allows users to verify that
 // a datasource is working as expected.
 func (s *Server) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
-	if err := s.Datasource.HealthCheck(ctx, req); err != nil {
-		return &backend.CheckHealthResult{
-			Status:  backend.HealthStatusError,
-			Message: err.Error(),
-		}, nil
-	}
-	return &backend.CheckHealthResult{
+	result := &backend.CheckHealthResult{
 		Status:  backend.HealthStatusOk,
 		Message: backend.HealthStatusOk.String(),
-	}, nil
+	}
+	report, err := s.Datasource.HealthCheck(ctx, req)
+	if err != nil {
+		result.Status = backend.HealthStatusError
+		result.Message = err.Error()
+	} else if warnings := report.Warnings(); len(warnings) > 0 {
+		result.Message = fmt.Sprintf("%s, but %s", result.Message, strings.Join(warnings, "; "))
+	}
+
+	// report the permissions of each API, and the edge credentials, which helps to tell auth
+	// failures from other failures
+	details := healthDetails{HealthReport: report}
+	if state, ok := s.Datasource.EdgeTokenState(); ok {
+		details.EdgeAuth = &state
+	}
+	if result.JSONDetails, err = json.Marshal(details); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// healthDetails are the JSON details of the health check result
+type healthDetails struct {
+	*sitewise.HealthReport
+	EdgeAuth *sitewise.EdgeTokenState `json:"edgeAuth,omitempty"`
 }
 
 func (s *Server) Dispose() {
@@ -0,0 +1,410 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// streamPathPropertyValue is the first path segment of PropertyValue streams.
+// The full channel is ds/<uid>/property-value/<region>/<entryId>, followed by /timeseries for the
+// streams of queries with the timeseries response format.
+const streamPathPropertyValue = "property-value"
+
+// defaultStreamInterval is how often the subscribed entries of a region are polled
+const defaultStreamInterval = time.Second
+
+// defaultStreamIdleTimeout is how long a stream without subscribers is kept, so that a panel can
+// subscribe to the channel its query returned, or resubscribe after it reconnects
+const defaultStreamIdleTimeout = 10 * time.Minute
+
+type propertyValueStream struct {
+	region string
+	entry  models.AssetPropertyEntry
+	// format is the response format of the queries of the stream, its frames have the same shape
+	format string
+	// timestamp of the latest value already delivered, either by QueryData or by the stream
+	lastTime time.Time
+	// idleSince is when the stream was registered or lost its last subscriber, zero while it
+	// has subscribers
+	idleSince time.Time
+}
+
+// streamManager keeps track of the PropertyValue streams handed out by QueryData and
+// runs a single poller per region, shared by every subscriber of that region.
+type streamManager struct {
+	ds          *sitewise.Datasource
+	interval    time.Duration
+	idleTimeout time.Duration
+	closeCh     <-chan struct{}
+
+	mu      sync.Mutex
+	streams map[string]propertyValueStream
+	pollers map[string]*streamPoller
+}
+
+type streamPoller struct {
+	region      string
+	cancel      context.CancelFunc
+	subscribers map[string][]chan *data.Frame
+}
+
+func newStreamManager(ds *sitewise.Datasource, closeCh <-chan struct{}) *streamManager {
+	return &streamManager{
+		ds:          ds,
+		interval:    defaultStreamInterval,
+		idleTimeout: defaultStreamIdleTimeout,
+		closeCh:     closeCh,
+		streams:     map[string]propertyValueStream{},
+		pollers:     map[string]*streamPoller{},
+	}
+}
+
+func streamPath(region string, entryId string, format string) string {
+	if region == "" {
+		region = "default"
+	}
+	path := strings.Join([]string{streamPathPropertyValue, region, entryId}, "/")
+	if format == "timeseries" {
+		path += "/timeseries"
+	}
+	return path
+}
+
+// register makes the stream for an entry known so that it can be subscribed to, and returns its path.
+// lastTime is the timestamp of the value the caller already returned for the entry, in the
+// response format of the query. Streams that had no subscriber for longer than the idle timeout
+// are removed.
+func (m *streamManager) register(region string, entry models.AssetPropertyEntry, format string, lastTime time.Time) string {
+	path := streamPath(region, *util.GetEntryIdFromAssetPropertyEntry(entry), format)
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expireIdle(now)
+
+	stream, ok := m.streams[path]
+	if !ok || lastTime.After(stream.lastTime) {
+		stream.region, stream.entry, stream.format, stream.lastTime = region, entry, format, lastTime
+	}
+	if !m.subscribed(stream.region, path) {
+		stream.idleSince = now
+	}
+	m.streams[path] = stream
+	return path
+}
+
+// subscribed reports whether the stream at path has a subscriber. m.mu must be held.
+func (m *streamManager) subscribed(region string, path string) bool {
+	poller, ok := m.pollers[region]
+	return ok && len(poller.subscribers[path]) > 0
+}
+
+// expireIdle removes the streams that had no subscriber for longer than the idle timeout.
+// m.mu must be held.
+func (m *streamManager) expireIdle(now time.Time) {
+	for path, stream := range m.streams {
+		if !stream.idleSince.IsZero() && now.Sub(stream.idleSince) > m.idleTimeout && !m.subscribed(stream.region, path) {
+			delete(m.streams, path)
+		}
+	}
+}
+
+func (m *streamManager) exists(path string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.streams[path]
+	return ok
+}
+
+// subscribe adds a subscriber for the stream at path. Changed values are sent to the returned channel
+// until the returned unsubscribe function is called.
+func (m *streamManager) subscribe(path string) (<-chan *data.Frame, func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stream, ok := m.streams[path]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown stream: %s", path)
+	}
+
+	poller, ok := m.pollers[stream.region]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		poller = &streamPoller{
+			region:      stream.region,
+			cancel:      cancel,
+			subscribers: map[string][]chan *data.Frame{},
+		}
+		m.pollers[stream.region] = poller
+		go m.poll(ctx, poller)
+	}
+
+	ch := make(chan *data.Frame, 1)
+	poller.subscribers[path] = append(poller.subscribers[path], ch)
+	stream.idleSince = time.Time{}
+	m.streams[path] = stream
+
+	unsubscribe := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		subscribers := poller.subscribers[path]
+		for i, c := range subscribers {
+			if c == ch {
+				subscribers = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+		if len(subscribers) == 0 {
+			delete(poller.subscribers, path)
+			if stream, ok := m.streams[path]; ok {
+				stream.idleSince = time.Now()
+				m.streams[path] = stream
+			}
+		} else {
+			poller.subscribers[path] = subscribers
+		}
+
+		if len(poller.subscribers) == 0 {
+			poller.cancel()
+			delete(m.pollers, poller.region)
+		}
+	}
+
+	return ch, unsubscribe, nil
+}
+
+// subscribedStreams returns the streams that currently have at least one subscriber, by path
+func (m *streamManager) subscribedStreams(poller *streamPoller) map[string]propertyValueStream {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	streams := make(map[string]propertyValueStream, len(poller.subscribers))
+	for path := range poller.subscribers {
+		streams[path] = m.streams[path]
+	}
+	return streams
+}
+
+// publish sends a frame to every subscriber of path if its value is newer than the last one delivered.
+// A frame that was not consumed yet is replaced by the newer one.
+func (m *streamManager) publish(poller *streamPoller, path string, frame *data.Frame) {
+	ts := timeAt(frame, 0)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the stream may have expired while it was polled
+	stream, ok := m.streams[path]
+	if !ok || !ts.After(stream.lastTime) {
+		return
+	}
+	stream.lastTime = ts
+	m.streams[path] = stream
+
+	for _, ch := range poller.subscribers[path] {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- frame
+	}
+}
+
+func (m *streamManager) poll(ctx context.Context, poller *streamPoller) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.closeCh:
+			return
+		case <-ticker.C:
+		}
+
+		streams := m.subscribedStreams(poller)
+		if len(streams) == 0 {
+			continue
+		}
+		// the streams of an entry in both response formats share its request
+		entries := []models.AssetPropertyEntry{}
+		requested := map[string]bool{}
+		for _, stream := range streams {
+			if entryId := *util.GetEntryIdFromAssetPropertyEntry(stream.entry); !requested[entryId] {
+				requested[entryId] = true
+				entries = append(entries, stream.entry)
+			}
+		}
+
+		query := &models.AssetPropertyValueQuery{
+			BaseQuery: models.BaseQuery{
+				AwsRegion:            poller.region,
+				QueryType:            models.QueryTypePropertyValue,
+				AssetPropertyEntries: entries,
+			},
+		}
+		frames, err := m.ds.HandleGetLatestAssetPropertyValues(ctx, query)
+		if err != nil {
+			log.DefaultLogger.FromContext(ctx).Warn("failed to poll property values for stream", "region", poller.region, "error", err)
+			continue
+		}
+
+		entryFrames := map[string]*data.Frame{}
+		for _, frame := range frames {
+			meta, ok := customMeta(frame)
+			if !ok || meta.EntryId == "" || frame.Rows() == 0 {
+				continue
+			}
+			entryFrames[meta.EntryId] = frame
+		}
+
+		for path, stream := range streams {
+			frame, ok := entryFrames[*util.GetEntryIdFromAssetPropertyEntry(stream.entry)]
+			if !ok {
+				continue
+			}
+			if stream.format == "timeseries" {
+				if frame, err = wideStreamFrame(frame); err != nil {
+					log.DefaultLogger.FromContext(ctx).Warn("failed to convert stream frame to time series", "path", path, "error", err)
+					continue
+				}
+			}
+			m.publish(poller, path, frame)
+		}
+	}
+}
+
+// wideStreamFrame is the time series frame of a polled frame. The wide frame gets its own meta,
+// since the polled frame is also published to the streams of the long format.
+func wideStreamFrame(frame *data.Frame) (*data.Frame, error) {
+	long := &data.Frame{Name: frame.Name, RefID: frame.RefID, Fields: frame.Fields}
+	if frame.Meta != nil {
+		meta := *frame.Meta
+		long.Meta = &meta
+	}
+	return longToWide(long)
+}
+
+func customMeta(frame *data.Frame) (models.SitewiseCustomMeta, bool) {
+	if frame.Meta == nil {
+		return models.SitewiseCustomMeta{}, false
+	}
+	meta, ok := frame.Meta.Custom.(models.SitewiseCustomMeta)
+	return meta, ok
+}
+
+func (s *Server) streams() *streamManager {
+	s.streamsOnce.Do(func() {
+		s.streamManager = newStreamManager(s.Datasource, s.closeCh)
+	})
+	return s.streamManager
+}
+
+// handlePropertyValueStreamQuery returns the latest values of a PropertyValue query with a
+// Grafana Live channel attached to each frame, so that the panel receives subsequent changes.
+// The frames are in the response format of the query, like those of handlePropertyValueQuery.
+func (s *Server) handlePropertyValueStreamQuery(ctx context.Context, query *models.AssetPropertyValueQuery, lowered loweredQuery) backend.DataResponse {
+	entries, err := s.Datasource.ResolveAssetPropertyEntries(ctx, query)
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
+	query.AssetPropertyEntries = entries
+	frames, err := s.Datasource.HandleGetLatestAssetPropertyValues(ctx, query)
+	if err != nil {
+		return DataResponseErrorRequestFailed(err)
+	}
+
+	entriesById := make(map[string]models.AssetPropertyEntry, len(entries))
+	for _, entry := range entries {
+		entriesById[*util.GetEntryIdFromAssetPropertyEntry(entry)] = entry
+	}
+
+	for _, frame := range frames {
+		meta, ok := customMeta(frame)
+		if !ok {
+			continue
+		}
+		entry, ok := entriesById[meta.EntryId]
+		if !ok {
+			continue
+		}
+		lastTime := timeAt(frame, 0)
+		frame.Meta.Channel = s.channelPrefix + s.streams().register(query.AwsRegion, entry, query.ResponseFormat, lastTime)
+	}
+
+	// like in the history queries each frame is converted on its own, since it has its own channel.
+	// The wide frame keeps the meta, and so the channel, of its long frame.
+	if query.ResponseFormat == "timeseries" {
+		for i, frame := range frames {
+			wide, err := longToWide(frame)
+			if err == nil {
+				frames[i] = wide
+			}
+		}
+	}
+	lowered.addNotices(ctx, frames)
+
+	return backend.DataResponse{
+		Frames: frames,
+		Error:  nil,
+	}
+}
+
+// SubscribeStream is called when a client wants to connect to a stream.
+// Only streams handed out by a PropertyValue query can be subscribed to.
+func (s *Server) SubscribeStream(_ context.Context, req *backend.SubscribeStreamRequest) (*backend.SubscribeStreamResponse, error) {
+	if !strings.HasPrefix(req.Path, streamPathPropertyValue+"/") || !s.streams().exists(req.Path) {
+		return &backend.SubscribeStreamResponse{
+			Status: backend.SubscribeStreamStatusNotFound,
+		}, nil
+	}
+
+	return &backend.SubscribeStreamResponse{
+		Status: backend.SubscribeStreamStatusOK,
+	}, nil
+}
+
+// PublishStream is called when a client sends a message to the stream. SiteWise streams are read only.
+func (s *Server) PublishStream(context.Context, *backend.PublishStreamRequest) (*backend.PublishStreamResponse, error) {
+	return &backend.PublishStreamResponse{
+		Status: backend.PublishStreamStatusPermissionDenied,
+	}, nil
+}
+
+// RunStream is called once per channel by Grafana and pushes changed property values until
+// the last subscriber of the channel leaves.
+func (s *Server) RunStream(ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender) error {
+	updates, unsubscribe, err := s.streams().subscribe(req.Path)
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.closeCh:
+			return nil
+		case frame := <-updates:
+			if err := sender.SendFrame(frame, data.IncludeAll); err != nil {
+				log.DefaultLogger.FromContext(ctx).Warn("failed to send stream frame", "path", req.Path, "error", err)
+				return err
+			}
+		}
+	}
+}
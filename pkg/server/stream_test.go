@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+type testPacketSender struct {
+	packets chan *backend.StreamPacket
+}
+
+func (s *testPacketSender) Send(packet *backend.StreamPacket) error {
+	s.packets <- packet
+	return nil
+}
+
+func mockLatestValue(mockSw *mocks.SitewiseAPIClient, entryId *string, ts int64, value float64) {
+	mockSw.On("BatchGetAssetPropertyValue", mock.Anything, mock.Anything).Return(&iotsitewise.BatchGetAssetPropertyValueOutput{
+		SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueSuccessEntry{{
+			EntryId: entryId,
+			AssetPropertyValue: &iotsitewisetypes.AssetPropertyValue{
+				Quality:   iotsitewisetypes.QualityGood,
+				Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(ts)},
+				Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(value)},
+			},
+		}},
+	}, nil).Once()
+}
+
+func TestPropertyValueStream(t *testing.T) {
+	entryId := util.GetEntryIdFromAssetProperty("asset-1", "prop-1")
+
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetPropertyOutput{
+		AssetId:   aws.String("asset-1"),
+		AssetName: aws.String("Asset 1"),
+		AssetProperty: &iotsitewisetypes.Property{
+			Id:       aws.String("prop-1"),
+			Name:     aws.String("Temperature"),
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+		},
+	}, nil)
+	// initial query, an unchanged poll and a changed poll
+	mockLatestValue(mockSw, entryId, 1000, 1)
+	mockLatestValue(mockSw, entryId, 1000, 1)
+	mockLatestValue(mockSw, entryId, 1001, 2)
+	mockSw.On("BatchGetAssetPropertyValue", mock.Anything, mock.Anything).Return(&iotsitewise.BatchGetAssetPropertyValueOutput{}, nil)
+
+	srvr := &Server{
+		Datasource: &sitewise.Datasource{
+			Cfg: models.AWSSiteWiseDataSourceSetting{
+				AWSDatasourceSettings: awsds.AWSDatasourceSettings{Region: "us-west-2"},
+			},
+			GetClient: func(context.Context, string) (client.SitewiseAPIClient, error) {
+				return mockSw, nil
+			},
+		},
+		channelPrefix: "ds/uid/",
+		closeCh:       make(chan struct{}),
+	}
+	srvr.streams().interval = 10 * time.Millisecond
+
+	qdr, err := srvr.HandlePropertyValue(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID:     "A",
+			QueryType: models.QueryTypePropertyValue,
+			JSON:      []byte(`{"region":"us-west-2","assetIds":["asset-1"],"propertyIds":["prop-1"],"streaming":true}`),
+		}},
+	})
+	require.NoError(t, err)
+	res := qdr.Responses["A"]
+	require.NoError(t, res.Error)
+	require.Len(t, res.Frames, 1)
+	require.Equal(t, 1, res.Frames[0].Rows())
+
+	path := "property-value/us-west-2/" + *entryId
+	require.Equal(t, "ds/uid/"+path, res.Frames[0].Meta.Channel)
+
+	subscribeRes, err := srvr.SubscribeStream(context.Background(), &backend.SubscribeStreamRequest{Path: path})
+	require.NoError(t, err)
+	require.Equal(t, backend.SubscribeStreamStatusOK, subscribeRes.Status)
+
+	subscribeRes, err = srvr.SubscribeStream(context.Background(), &backend.SubscribeStreamRequest{Path: "property-value/us-west-2/unknown"})
+	require.NoError(t, err)
+	require.Equal(t, backend.SubscribeStreamStatusNotFound, subscribeRes.Status)
+
+	sender := &testPacketSender{packets: make(chan *backend.StreamPacket, 10)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- srvr.RunStream(ctx, &backend.RunStreamRequest{Path: path}, backend.NewStreamSender(sender))
+	}()
+
+	select {
+	case packet := <-sender.packets:
+		require.Contains(t, string(packet.Data), "1001")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for stream packet")
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+	require.Empty(t, sender.packets, "unchanged values should not be sent")
+}
+
+func TestTimeSeriesPropertyValueStream(t *testing.T) {
+	entryId := util.GetEntryIdFromAssetProperty("asset-1", "prop-1")
+
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetPropertyOutput{
+		AssetId:   aws.String("asset-1"),
+		AssetName: aws.String("Asset 1"),
+		AssetProperty: &iotsitewisetypes.Property{
+			Id:       aws.String("prop-1"),
+			Name:     aws.String("Temperature"),
+			DataType: iotsitewisetypes.PropertyDataTypeDouble,
+		},
+	}, nil)
+	mockLatestValue(mockSw, entryId, 1000, 1)
+	mockLatestValue(mockSw, entryId, 1001, 2)
+	mockSw.On("BatchGetAssetPropertyValue", mock.Anything, mock.Anything).Return(&iotsitewise.BatchGetAssetPropertyValueOutput{}, nil)
+
+	srvr := &Server{
+		Datasource: &sitewise.Datasource{
+			Cfg: models.AWSSiteWiseDataSourceSetting{
+				AWSDatasourceSettings: awsds.AWSDatasourceSettings{Region: "us-west-2"},
+			},
+			GetClient: func(context.Context, string) (client.SitewiseAPIClient, error) {
+				return mockSw, nil
+			},
+		},
+		channelPrefix: "ds/uid/",
+		closeCh:       make(chan struct{}),
+	}
+	srvr.streams().interval = 10 * time.Millisecond
+
+	qdr, err := srvr.HandlePropertyValue(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID:     "A",
+			QueryType: models.QueryTypePropertyValue,
+			JSON:      []byte(`{"region":"us-west-2","assetIds":["asset-1"],"propertyIds":["prop-1"],"streaming":true,"responseFormat":"timeseries"}`),
+		}},
+	})
+	require.NoError(t, err)
+	res := qdr.Responses["A"]
+	require.NoError(t, res.Error)
+	require.Len(t, res.Frames, 1)
+	require.Equal(t, data.FrameTypeTimeSeriesWide, res.Frames[0].Meta.Type)
+
+	path := "property-value/us-west-2/" + *entryId + "/timeseries"
+	require.Equal(t, "ds/uid/"+path, res.Frames[0].Meta.Channel)
+
+	sender := &testPacketSender{packets: make(chan *backend.StreamPacket, 10)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- srvr.RunStream(ctx, &backend.RunStreamRequest{Path: path}, backend.NewStreamSender(sender))
+	}()
+
+	select {
+	case packet := <-sender.packets:
+		frame := &data.Frame{}
+		require.NoError(t, frame.UnmarshalJSON(packet.Data))
+		require.Equal(t, data.FrameTypeTimeSeriesWide, frame.Meta.Type)
+		require.Equal(t, len(res.Frames[0].Fields), len(frame.Fields))
+		for i, field := range frame.Fields {
+			require.Equal(t, res.Frames[0].Fields[i].Name, field.Name)
+			require.Equal(t, res.Frames[0].Fields[i].Labels, field.Labels)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for stream packet")
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestPropertyValueStreamsExpireWhenIdle(t *testing.T) {
+	m := newStreamManager(&sitewise.Datasource{}, make(chan struct{}))
+	m.interval, m.idleTimeout = time.Hour, 10*time.Millisecond
+	entry := func(propertyId string) models.AssetPropertyEntry {
+		return models.AssetPropertyEntry{AssetId: "asset-1", PropertyId: propertyId}
+	}
+
+	unsubscribed := m.register("us-west-2", entry("prop-1"), "", time.Time{})
+	subscribed := m.register("us-west-2", entry("prop-2"), "", time.Time{})
+	_, unsubscribe, err := m.subscribe(subscribed)
+	require.NoError(t, err)
+
+	// streams without subscribers expire, the others are kept
+	time.Sleep(20 * time.Millisecond)
+	m.register("us-west-2", entry("prop-3"), "", time.Time{})
+	require.False(t, m.exists(unsubscribed))
+	require.True(t, m.exists(subscribed))
+
+	// a stream expires once its last subscriber left
+	unsubscribe()
+	time.Sleep(20 * time.Millisecond)
+	m.register("us-west-2", entry("prop-3"), "", time.Time{})
+	require.False(t, m.exists(subscribed))
+	require.Len(t, m.streams, 1)
+}
@@ -0,0 +1,165 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+const mockSecondPropertyId = "22propid-aaaa-2222-bbbb-3333cccc4444"
+
+func batchPlanHistoryRequest() *backend.QueryDataRequest {
+	query := func(refID string, propertyId string) backend.DataQuery {
+		return backend.DataQuery{
+			QueryType:     models.QueryTypePropertyValueHistory,
+			RefID:         refID,
+			MaxDataPoints: 100,
+			Interval:      1000,
+			TimeRange:     timeRange,
+			JSON: []byte(fmt.Sprintf(`{
+				"region":"us-west-2",
+				"assetIds":["%s"],
+				"propertyIds":["%s"]
+			}`, mockAssetId, propertyId)),
+		}
+	}
+	return &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{
+			query("A", mockPropertyId),
+			query("B", mockSecondPropertyId),
+			query("C", mockPropertyId),
+		},
+	}
+}
+
+func batchPlanFrameEntryIds(t *testing.T, res backend.DataResponse) []string {
+	t.Helper()
+	require.NoError(t, res.Error)
+	entryIds := []string{}
+	for _, frame := range res.Frames {
+		meta, ok := frame.Meta.Custom.(models.SitewiseCustomMeta)
+		require.True(t, ok)
+		entryIds = append(entryIds, meta.EntryId)
+	}
+	return entryIds
+}
+
+func Test_batch_plan_merges_history_queries_across_ref_ids(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	secondEntryId := util.GetEntryIdFromAssetProperty(mockAssetId, mockSecondPropertyId)
+
+	mockSw.On(
+		"BatchGetAssetPropertyValueHistoryPageAggregation",
+		mock.Anything,
+		mock.MatchedBy(func(req *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+			// the entry shared by A and C is only requested once
+			return len(req.Entries) == 2
+		}),
+		1,
+		100,
+	).Return(&iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+		SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{
+			mockBatchGetAssetPropertyValueHistorySuccessEntry(mockAssetPropertyEntryId, 0),
+			mockBatchGetAssetPropertyValueHistorySuccessEntry(secondEntryId, 1),
+		},
+	}, nil).Once()
+	mockDescribeAssetProperty(mockSw)
+	mockDescribeAsset(mockSw)
+	mockDescribeAssetModel(mockSw)
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), batchPlanHistoryRequest())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{*mockAssetPropertyEntryId}, batchPlanFrameEntryIds(t, qdr.Responses["A"]))
+	require.Equal(t, []string{*secondEntryId}, batchPlanFrameEntryIds(t, qdr.Responses["B"]))
+	require.Equal(t, []string{*mockAssetPropertyEntryId}, batchPlanFrameEntryIds(t, qdr.Responses["C"]))
+
+	mockSw.AssertExpectations(t)
+	mockSw.AssertNumberOfCalls(t, "BatchGetAssetPropertyValueHistoryPageAggregation", 1)
+}
+
+func Test_batch_plan_hands_the_next_token_of_a_merged_batch_to_every_query(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	secondEntryId := util.GetEntryIdFromAssetProperty(mockAssetId, mockSecondPropertyId)
+
+	mockSw.On(
+		"BatchGetAssetPropertyValueHistoryPageAggregation",
+		mock.Anything,
+		mock.MatchedBy(func(req *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+			return len(req.Entries) == 2 && req.NextToken == nil
+		}),
+		1,
+		100,
+	).Return(&iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+		NextToken: Pointer("merged"),
+		SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{
+			mockBatchGetAssetPropertyValueHistorySuccessEntry(mockAssetPropertyEntryId, 0),
+			mockBatchGetAssetPropertyValueHistorySuccessEntry(secondEntryId, 1),
+		},
+	}, nil).Once()
+	mockSw.On(
+		"BatchGetAssetPropertyValueHistoryPageAggregation",
+		mock.Anything,
+		mock.MatchedBy(func(req *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+			return len(req.Entries) == 2 && util.Dereference(req.NextToken) == "merged"
+		}),
+		1,
+		100,
+	).Return(&iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+		SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{
+			mockBatchGetAssetPropertyValueHistorySuccessEntry(mockAssetPropertyEntryId, 2),
+			mockBatchGetAssetPropertyValueHistorySuccessEntry(secondEntryId, 3),
+		},
+	}, nil).Once()
+	mockDescribeAssetProperty(mockSw)
+	mockDescribeAsset(mockSw)
+	mockDescribeAssetModel(mockSw)
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), batchPlanHistoryRequest())
+	require.NoError(t, err)
+
+	// the merged results are kept, and the next token of each query continues the merged batch
+	nextPage := batchPlanHistoryRequest()
+	for i, refID := range []string{"A", "B", "C"} {
+		frames := qdr.Responses[refID].Frames
+		require.Len(t, frames, 1)
+		meta := frames[0].Meta.Custom.(models.SitewiseCustomMeta)
+		require.Equal(t, "merged", meta.NextToken)
+
+		model := map[string]any{}
+		require.NoError(t, json.Unmarshal(nextPage.Queries[i].JSON, &model))
+		model["nextToken"] = meta.NextToken
+		model["nextTokens"] = map[string]string{meta.EntryId: meta.NextToken}
+		nextPage.Queries[i].JSON, err = json.Marshal(model)
+		require.NoError(t, err)
+	}
+
+	// the entries of the next page are merged into the same batch again
+	qdr, err = srvr.HandlePropertyValueHistory(context.Background(), nextPage)
+	require.NoError(t, err)
+	for _, refID := range []string{"A", "B", "C"} {
+		frames := qdr.Responses[refID].Frames
+		require.Len(t, frames, 1)
+		require.Empty(t, frames[0].Meta.Custom.(models.SitewiseCustomMeta).NextToken)
+	}
+
+	mockSw.AssertExpectations(t)
+	mockSw.AssertNumberOfCalls(t, "BatchGetAssetPropertyValueHistoryPageAggregation", 2)
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/testdata"
+)
+
+func TestHandleDescribeAssetProperty(t *testing.T) {
+	describeAssetPropertyHappyCase(t).run(t)
+}
+
+var describeAssetPropertyHappyCase testServerScenarioFn = func(t *testing.T) *testScenario {
+
+	mockSw := &mocks.SitewiseAPIClient{}
+
+	avgWindSpeed := testdata.GetIoTSitewiseAssetPropertyDescription(t, testDataRelativePath("describe-asset-property-avg-wind.json"))
+
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.MatchedBy(func(req *iotsitewise.DescribeAssetPropertyInput) bool {
+		return *req.AssetId == testdata.DemoTurbineAsset1 && *req.PropertyId == testdata.TurbinePropAvgWindSpeed
+	})).Return(&avgWindSpeed, nil)
+
+	mockSw.On("DescribeAssetProperty", mock.Anything, mock.MatchedBy(func(req *iotsitewise.DescribeAssetPropertyInput) bool {
+		return *req.AssetId == testdata.DemoTurbineAsset1 && *req.PropertyId == "component-property"
+	})).Return(&iotsitewise.DescribeAssetPropertyOutput{
+		AssetId:      Pointer(testdata.DemoTurbineAsset1),
+		AssetModelId: Pointer(testdata.DemoTurbineAssetModelId),
+		AssetName:    Pointer("Demo Turbine Asset 1"),
+		CompositeModel: &iotsitewisetypes.CompositeModelProperty{
+			Id:   Pointer("component-model"),
+			Name: Pointer("Generator"),
+			Type: Pointer("CUSTOM"),
+			AssetProperty: &iotsitewisetypes.Property{
+				Id:       Pointer("component-property"),
+				Name:     Pointer("Temperature"),
+				Alias:    Pointer("/amazon/renton/1/generator/temperature"),
+				DataType: iotsitewisetypes.PropertyDataTypeDouble,
+				Unit:     Pointer("Celsius"),
+				Path: []iotsitewisetypes.AssetPropertyPathSegment{
+					{Id: Pointer(testdata.DemoTurbineAsset1), Name: Pointer("Demo Turbine Asset 1")},
+					{Id: Pointer("component-model"), Name: Pointer("Generator")},
+					{Id: Pointer("component-property"), Name: Pointer("Temperature")},
+				},
+			},
+		},
+	}, nil)
+
+	query := models.DescribeAssetPropertyQuery{}
+	query.AssetIds = []string{testdata.DemoTurbineAsset1}
+	query.PropertyIds = []string{testdata.TurbinePropAvgWindSpeed, "component-property"}
+
+	return &testScenario{
+		name: "DescribeAssetPropertyHappyCase",
+		queries: []backend.DataQuery{
+			{
+				RefID:     "A",
+				QueryType: models.QueryTypeDescribeAssetProperty,
+				JSON:      testdata.SerializeStruct(t, query),
+			},
+		},
+		mockSw:         mockSw,
+		goldenFileName: "describe-asset-property",
+		handlerFn: func(srvr *server.Server) backend.QueryDataHandlerFunc {
+			return srvr.HandleDescribeAssetProperty
+		},
+		validationFn: nil,
+	}
+}
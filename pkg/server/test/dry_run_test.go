@@ -0,0 +1,126 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+)
+
+func dryRunRows(t *testing.T, frame *data.Frame) []map[string]any {
+	t.Helper()
+	rows := []map[string]any{}
+	for i := range frame.Rows() {
+		row := map[string]any{}
+		for _, field := range frame.Fields {
+			if v, ok := field.ConcreteAt(i); ok {
+				row[field.Name] = v
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func Test_dry_run_plans_the_batches_of_an_aggregate_query_without_fetching_data(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	propertyIds := make([]string, 20)
+	for i := range propertyIds {
+		propertyIds[i] = fmt.Sprintf("property-%d", i)
+	}
+	model, err := json.Marshal(map[string]any{
+		"region":          "us-west-2",
+		"assetIds":        []string{mockAssetId},
+		"propertyIds":     propertyIds,
+		"aggregates":      []string{models.AggregateAvg},
+		"resolution":      "1m",
+		"lastObservation": true,
+		"dryRun":          true,
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID:         "A",
+			QueryType:     models.QueryTypePropertyAggregate,
+			MaxDataPoints: 100,
+			TimeRange:     backend.TimeRange{From: now.Add(-24 * time.Hour), To: now},
+			JSON:          model,
+		}},
+	})
+	require.NoError(t, err)
+	res := qdr.Responses["A"]
+	require.NoError(t, res.Error)
+	require.Len(t, res.Frames, 1)
+
+	// 1440 points per entry, 4000 points per page shared by the entries of a batch
+	require.Equal(t, []map[string]any{
+		{"operation": "BatchGetAssetPropertyAggregates", "calls": int64(1), "entries_per_call": int64(16), "resolution": "1m", "estimated_pages": int64(6), "expected_points": int64(16 * 1440)},
+		{"operation": "BatchGetAssetPropertyAggregates", "calls": int64(1), "entries_per_call": int64(4), "resolution": "1m", "estimated_pages": int64(2), "expected_points": int64(4 * 1440)},
+	}, dryRunRows(t, res.Frames[0]))
+	require.Equal(t, "lastObservation adds two queries of one point per entry, batched with the other queries of the request", res.Frames[0].Meta.Notices[0].Text)
+	require.Equal(t, float64(8), res.Frames[0].Meta.Stats[1].Value)
+	mockSw.AssertNotCalled(t, "BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockSw.AssertNotCalled(t, "BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func Test_dry_run_of_history_cannot_estimate_raw_values(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID:         "A",
+			QueryType:     models.QueryTypePropertyValueHistory,
+			MaxDataPoints: 100,
+			TimeRange:     timeRange,
+			JSON:          []byte(fmt.Sprintf(`{"region":"us-west-2","assetIds":["%s"],"propertyIds":["%s"],"dryRun":true}`, mockAssetId, mockPropertyId)),
+		}},
+	})
+	require.NoError(t, err)
+	res := qdr.Responses["A"]
+	require.NoError(t, res.Error)
+
+	require.Equal(t, []map[string]any{
+		{"operation": "BatchGetAssetPropertyValueHistory", "calls": int64(1), "entries_per_call": int64(1), "resolution": "RAW"},
+	}, dryRunRows(t, res.Frames[0]))
+	require.Contains(t, res.Frames[0].Meta.Notices[0].Text, "cannot be estimated")
+	mockSw.AssertNotCalled(t, "BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func Test_dry_run_of_execute_query_returns_the_interpolated_statement(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	qdr, err := srvr.HandleExecuteQuery(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID:     "A",
+			QueryType: models.QueryTypeExecuteQuery,
+			TimeRange: backend.TimeRange{From: time.Unix(1700000000, 0), To: time.Unix(1700003600, 0)},
+			JSON:      []byte(`{"rawSQL":"SELECT asset_id FROM asset WHERE $__timeFilter(event_timestamp)","dryRun":true}`),
+		}},
+	})
+	require.NoError(t, err)
+	res := qdr.Responses["A"]
+	require.NoError(t, res.Error)
+
+	require.Equal(t, []map[string]any{
+		{"operation": "ExecuteQuery", "calls": int64(1), "entries_per_call": int64(0), "resolution": ""},
+	}, dryRunRows(t, res.Frames[0]))
+	require.Equal(t, "SELECT asset_id FROM asset WHERE event_timestamp >= TIMESTAMP '2023-11-14 22:13:20' and event_timestamp <= TIMESTAMP '2023-11-14 23:13:20'", res.Frames[0].Meta.ExecutedQueryString)
+	mockSw.AssertNotCalled(t, "ExecuteQuery", mock.Anything, mock.Anything)
+}
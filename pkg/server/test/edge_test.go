@@ -0,0 +1,131 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+func edgeQuery(queryType string) backend.DataQuery {
+	return backend.DataQuery{
+		QueryType:     queryType,
+		RefID:         "A",
+		MaxDataPoints: 100,
+		Interval:      1000,
+		TimeRange:     timeRange,
+		JSON: []byte(fmt.Sprintf(`{
+			"region":"Edge",
+			"assetIds":["%s"],
+			"propertyIds":["%s","%s"]
+		}`, mockAssetId, mockPropertyId, mockSecondPropertyId)),
+	}
+}
+
+func Test_edge_property_value_history_returns_a_frame_per_entry(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	for i, propertyId := range []string{mockPropertyId, mockSecondPropertyId} {
+		mockSw.On("GetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.MatchedBy(func(req *iotsitewise.GetAssetPropertyValueHistoryInput) bool {
+			return *req.AssetId == mockAssetId && *req.PropertyId == propertyId
+		}), 1, 100).Return(&iotsitewise.GetAssetPropertyValueHistoryOutput{
+			AssetPropertyValueHistory: mockBatchGetAssetPropertyValueHistorySuccessEntry(nil, i).AssetPropertyValueHistory,
+			NextToken:                 Pointer(fmt.Sprintf("token-%d", i)),
+		}, nil).Once()
+	}
+	mockDescribeAssetProperty(mockSw)
+	mockDescribeAsset(mockSw)
+	mockDescribeAssetModel(mockSw)
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{edgeQuery(models.QueryTypePropertyValueHistory)},
+	})
+	require.NoError(t, err)
+
+	frames := qdr.Responses["A"].Frames
+	require.Len(t, frames, 2)
+	for i, propertyId := range []string{mockPropertyId, mockSecondPropertyId} {
+		meta := frames[i].Meta.Custom.(models.SitewiseCustomMeta)
+		require.Equal(t, *util.GetEntryIdFromAssetProperty(mockAssetId, propertyId), meta.EntryId)
+		require.Equal(t, fmt.Sprintf("token-%d", i), meta.NextToken)
+		require.Equal(t, 1, frames[i].Rows())
+	}
+
+	mockSw.AssertExpectations(t)
+}
+
+func Test_edge_property_value_history_requests_the_next_token_of_each_entry(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	secondEntryId := util.GetEntryIdFromAssetProperty(mockAssetId, mockSecondPropertyId)
+	mockSw.On("GetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.MatchedBy(func(req *iotsitewise.GetAssetPropertyValueHistoryInput) bool {
+		return *req.PropertyId == mockPropertyId && req.NextToken == nil
+	}), 1, 100).Return(&iotsitewise.GetAssetPropertyValueHistoryOutput{}, nil).Once()
+	mockSw.On("GetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.MatchedBy(func(req *iotsitewise.GetAssetPropertyValueHistoryInput) bool {
+		return *req.PropertyId == mockSecondPropertyId && req.NextToken != nil && *req.NextToken == "token-1"
+	}), 1, 100).Return(&iotsitewise.GetAssetPropertyValueHistoryOutput{}, nil).Once()
+	mockDescribeAssetProperty(mockSw)
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	query := edgeQuery(models.QueryTypePropertyValueHistory)
+	query.JSON = []byte(fmt.Sprintf(`{
+		"region":"Edge",
+		"assetIds":["%s"],
+		"propertyIds":["%s","%s"],
+		"nextToken":"token-1",
+		"nextTokens":{"%s":"token-1"}
+	}`, mockAssetId, mockPropertyId, mockSecondPropertyId, *secondEntryId))
+
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{query},
+	})
+	require.NoError(t, err)
+	require.NoError(t, qdr.Responses["A"].Error)
+
+	mockSw.AssertExpectations(t)
+}
+
+func Test_edge_property_value_returns_a_frame_per_entry(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	for i, propertyId := range []string{mockPropertyId, mockSecondPropertyId} {
+		mockSw.On("GetAssetPropertyValue", mock.Anything, mock.MatchedBy(func(req *iotsitewise.GetAssetPropertyValueInput) bool {
+			return *req.AssetId == mockAssetId && *req.PropertyId == propertyId
+		})).Return(&iotsitewise.GetAssetPropertyValueOutput{
+			PropertyValue: &iotsitewisetypes.AssetPropertyValue{
+				Quality:   iotsitewisetypes.QualityGood,
+				Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: Pointer(int64(1612207200 + i))},
+				Value:     &iotsitewisetypes.Variant{DoubleValue: Pointer(float64(i))},
+			},
+		}, nil).Once()
+	}
+	mockDescribeAssetProperty(mockSw)
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+
+	qdr, err := srvr.HandlePropertyValue(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{edgeQuery(models.QueryTypePropertyValue)},
+	})
+	require.NoError(t, err)
+
+	frames := qdr.Responses["A"].Frames
+	require.Len(t, frames, 2)
+	for i, propertyId := range []string{mockPropertyId, mockSecondPropertyId} {
+		meta := frames[i].Meta.Custom.(models.SitewiseCustomMeta)
+		require.Equal(t, *util.GetEntryIdFromAssetProperty(mockAssetId, propertyId), meta.EntryId)
+		require.Equal(t, float64(i), frames[i].Fields[1].At(0))
+	}
+
+	mockSw.AssertExpectations(t)
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+)
+
+func notFoundError() error {
+	return &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+		Err:      &iotsitewisetypes.ResourceNotFoundException{Message: aws.String("not found")},
+	}
+}
+
+func accessDeniedError() error {
+	return &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusForbidden}},
+		Err:      &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not authorized"},
+	}
+}
+
+func mockHealthCheckCalls(mockSw *mocks.SitewiseAPIClient) {
+	mockSw.On("ListAssetModels", mock.Anything, mock.Anything).Return(&iotsitewise.ListAssetModelsOutput{}, nil)
+	mockSw.On("ListAssets", mock.Anything, mock.Anything).Return(&iotsitewise.ListAssetsOutput{}, nil)
+	mockSw.On("DescribeAsset", mock.Anything, mock.Anything).Return(nil, notFoundError())
+	mockSw.On("GetInterpolatedAssetPropertyValues", mock.Anything, mock.Anything).Return(nil, notFoundError())
+}
+
+func checkHealth(t *testing.T, srvr *server.Server) (*backend.CheckHealthResult, map[string]any) {
+	t.Helper()
+	result, err := srvr.CheckHealth(context.Background(), &backend.CheckHealthRequest{})
+	require.NoError(t, err)
+
+	details := map[string]any{}
+	require.NoError(t, json.Unmarshal(result.JSONDetails, &details))
+	return result, details
+}
+
+func Test_health_check_reports_the_permissions_of_each_api(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockHealthCheckCalls(mockSw)
+	mockSw.On("DescribeTimeSeries", mock.Anything, mock.Anything).Return(nil, accessDeniedError())
+	mockSw.On("BatchGetAssetPropertyValue", mock.Anything, mock.Anything).Return(&iotsitewise.BatchGetAssetPropertyValueOutput{}, nil)
+	mockSw.On("ExecuteQuery", mock.Anything, mock.Anything).Return(nil, accessDeniedError())
+
+	result, details := checkHealth(t, &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)})
+
+	require.Equal(t, backend.HealthStatusOk, result.Status)
+	require.Equal(t, "OK, but access is denied to DescribeTimeSeries, ExecuteQuery", result.Message)
+	require.ElementsMatch(t, []any{"ListAssetModels", "ListAssets", "DescribeAsset", "BatchGetAssetPropertyValue", "GetInterpolatedAssetPropertyValues"}, details["allowed"])
+	require.Equal(t, []any{"DescribeTimeSeries", "ExecuteQuery"}, details["denied"])
+	require.Empty(t, details["unsupported"])
+	require.Len(t, details["apis"], 7)
+}
+
+func Test_health_check_fails_when_asset_models_cannot_be_listed(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("ListAssetModels", mock.Anything, mock.Anything).Return(nil, accessDeniedError())
+	for _, api := range []string{"ListAssets", "DescribeAsset", "DescribeTimeSeries", "BatchGetAssetPropertyValue", "GetInterpolatedAssetPropertyValues", "ExecuteQuery"} {
+		mockSw.On(api, mock.Anything, mock.Anything).Return(nil, accessDeniedError())
+	}
+
+	result, details := checkHealth(t, &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)})
+
+	require.Equal(t, backend.HealthStatusError, result.Status)
+	require.Contains(t, result.Message, "unable to test ListAssetModels")
+	require.Len(t, details["denied"], 7)
+}
+
+func edgeCertificate(t *testing.T, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "edge-gateway"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func Test_edge_health_check_skips_unsupported_apis_and_checks_the_certificate(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockHealthCheckCalls(mockSw)
+	mockSw.On("DescribeTimeSeries", mock.Anything, mock.Anything).Return(nil, notFoundError())
+
+	ds := mockedDatasource(mockSw).(*sitewise.Datasource)
+	ds.Cfg.Region = sitewise.EDGE_REGION
+	ds.Cfg.Cert = edgeCertificate(t, time.Now().Add(10*24*time.Hour+time.Hour))
+
+	result, details := checkHealth(t, &server.Server{Datasource: ds})
+
+	require.Equal(t, backend.HealthStatusOk, result.Status)
+	require.Equal(t, "OK, but the edge certificate expires in 10 days", result.Message)
+	require.Equal(t, []any{"BatchGetAssetPropertyValue", "ExecuteQuery"}, details["unsupported"])
+	certificate := details["certificate"].(map[string]any)
+	require.Equal(t, true, certificate["valid"])
+	require.Equal(t, "CN=edge-gateway", certificate["subject"])
+	mockSw.AssertNotCalled(t, "ExecuteQuery", mock.Anything, mock.Anything)
+
+	ds.Cfg.Cert = edgeCertificate(t, time.Now().Add(-time.Hour))
+	result, details = checkHealth(t, &server.Server{Datasource: ds})
+
+	require.Equal(t, backend.HealthStatusError, result.Status)
+	require.Contains(t, result.Message, "invalid edge certificate: the certificate expired on")
+	require.Equal(t, false, details["certificate"].(map[string]any)["valid"])
+}
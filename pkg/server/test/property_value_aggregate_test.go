@@ -22,7 +22,6 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/testdata"
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 
-	"github.com/patrickmn/go-cache"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -50,10 +49,27 @@ func mockBatchGetAssetPropertyAggregatesSuccessEntry(entryId *string, idx int) i
 }
 
 func mockBatchGetAssetPropertyAggregatesPageAggregation(mockSw *mocks.SitewiseAPIClient, nextToken *string, successEntries []iotsitewisetypes.BatchGetAssetPropertyAggregatesSuccessEntry, errorEntries []iotsitewisetypes.BatchGetAssetPropertyAggregatesErrorEntry) {
+	// batches are requested concurrently, so each response is matched to the batch of its first entry
+	var entryId *string
+	if len(successEntries) > 0 {
+		entryId = successEntries[0].EntryId
+	} else if len(errorEntries) > 0 {
+		entryId = errorEntries[0].EntryId
+	}
 	mockSw.On(
 		"BatchGetAssetPropertyAggregatesPageAggregation",
 		mock.Anything,
-		mock.Anything,
+		mock.MatchedBy(func(req *iotsitewise.BatchGetAssetPropertyAggregatesInput) bool {
+			if entryId == nil {
+				return true
+			}
+			for _, entry := range req.Entries {
+				if *entry.EntryId == *entryId {
+					return true
+				}
+			}
+			return false
+		}),
 		mock.Anything,
 		mock.Anything,
 	).Return(&iotsitewise.BatchGetAssetPropertyAggregatesOutput{
@@ -141,10 +157,6 @@ func TestPropertyValueAggregate(t *testing.T) {
 
 			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-			sitewise.GetCache = func() *cache.Cache {
-				return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-			}
-
 			query := &backend.QueryDataRequest{
 				PluginContext: backend.PluginContext{},
 				Queries: []backend.DataQuery{
@@ -234,10 +246,6 @@ func TestPropertyValueAggregateWithDisassociatedStream(t *testing.T) {
 
 		srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-		sitewise.GetCache = func() *cache.Cache {
-			return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-		}
-
 		query := &backend.QueryDataRequest{
 			PluginContext: backend.PluginContext{},
 			Queries: []backend.DataQuery{
@@ -347,10 +355,6 @@ func TestPropertyValueAggregate_with_error(t *testing.T) {
 
 		srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-		sitewise.GetCache = func() *cache.Cache {
-			return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-		}
-
 		query := &backend.QueryDataRequest{
 			PluginContext: backend.PluginContext{},
 			Queries: []backend.DataQuery{
@@ -374,7 +378,13 @@ func TestPropertyValueAggregate_with_error(t *testing.T) {
 		require.NotNil(t, qdr.Responses["A"].Frames[0])
 
 		expectedFrame := data.NewFrame("Demo Turbine Asset 1 Wind Speed").SetMeta(&data.FrameMeta{
-			Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: "Asset property not found."}},
+			Custom: models.SitewiseCustomMeta{
+				EntryId:     *mockAssetPropertyEntryId,
+				EntryStatus: models.EntryStatusError,
+				ErrorCode:   "ResourceNotFoundException",
+				Summary:     &models.EntrySummary{Entries: 1, Errored: 1},
+			},
+			Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: "Demo Turbine Asset 1 Wind Speed: ResourceNotFoundException: Asset property not found."}},
 		},
 		)
 		if diff := cmp.Diff(expectedFrame, qdr.Responses["A"].Frames[0], data.FrameTestCompareOptions()...); diff != "" {
@@ -473,10 +483,6 @@ func TestPropertyValueAggregate_batched(t *testing.T) {
 
 			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-			sitewise.GetCache = func() *cache.Cache {
-				return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-			}
-
 			var baseQuery models.BaseQuery
 			if tc.numPropertyAliases > 0 {
 				baseQuery = models.BaseQuery{
@@ -557,6 +563,7 @@ func TestPropertyValueAggregate_batched_with_error(t *testing.T) {
 		mockedErrorEntries := []iotsitewisetypes.BatchGetAssetPropertyAggregatesErrorEntry{}
 		numBatch := 0
 		errorIndex := 20
+		errorEntryId := ""
 
 		assetIds := generateIds(tc.numAssetIds, mockAssetId)
 		propertyIds := generateIds(tc.numPropertyIds, mockPropertyId)
@@ -567,6 +574,7 @@ func TestPropertyValueAggregate_batched_with_error(t *testing.T) {
 
 				// Build one error entry
 				if a*tc.numPropertyIds+p == errorIndex {
+					errorEntryId = *entryId
 					mockedErrorEntries = append(mockedErrorEntries, iotsitewisetypes.BatchGetAssetPropertyAggregatesErrorEntry{
 						ErrorCode:    iotsitewisetypes.BatchGetAssetPropertyAggregatesErrorCodeResourceNotFoundException,
 						ErrorMessage: Pointer("Asset property not found."),
@@ -595,10 +603,6 @@ func TestPropertyValueAggregate_batched_with_error(t *testing.T) {
 
 		srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-		sitewise.GetCache = func() *cache.Cache {
-			return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-		}
-
 		var baseQuery models.BaseQuery
 		if tc.numPropertyAliases > 0 {
 			baseQuery = models.BaseQuery{
@@ -640,7 +644,12 @@ func TestPropertyValueAggregate_batched_with_error(t *testing.T) {
 		for _, f := range qdr.Responses["A"].Frames {
 			if len(f.Meta.Notices) > 0 {
 				expectedErrorFrame := data.NewFrame("Demo Turbine Asset 1 Wind Speed").SetMeta(&data.FrameMeta{
-					Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: "Asset property not found."}},
+					Custom: models.SitewiseCustomMeta{
+						EntryId:     errorEntryId,
+						EntryStatus: models.EntryStatusError,
+						ErrorCode:   "ResourceNotFoundException",
+					},
+					Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: "Demo Turbine Asset 1 Wind Speed: ResourceNotFoundException: Asset property not found."}},
 				},
 				)
 				if diff := cmp.Diff(expectedErrorFrame, f, data.FrameTestCompareOptions()...); diff != "" {
@@ -654,3 +663,74 @@ func TestPropertyValueAggregate_batched_with_error(t *testing.T) {
 		mockSw.AssertExpectations(t)
 	})
 }
+
+func TestPropertyValueAggregateFetchesEachQuality(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockDescribeAssetProperty(mockSw)
+	mockDescribeAsset(mockSw)
+	mockDescribeAssetModel(mockSw)
+	mockSw.On("BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		func(_ context.Context, req *iotsitewise.BatchGetAssetPropertyAggregatesInput, _ int, _ int) *iotsitewise.BatchGetAssetPropertyAggregatesOutput {
+			quality := req.Entries[0].Qualities[0]
+			return &iotsitewise.BatchGetAssetPropertyAggregatesOutput{
+				NextToken: Pointer("token-" + string(quality)),
+				SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyAggregatesSuccessEntry{
+					mockBatchGetAssetPropertyAggregatesSuccessEntry(req.Entries[0].EntryId, len(quality)),
+				},
+			}
+		}, nil)
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+	query := func(json string) *backend.QueryDataRequest {
+		return &backend.QueryDataRequest{Queries: []backend.DataQuery{{
+			RefID:     "A",
+			QueryType: models.QueryTypePropertyAggregate,
+			TimeRange: timeRange,
+			JSON:      []byte(json),
+		}}}
+	}
+
+	qdr, err := srvr.HandlePropertyAggregate(context.Background(), query(fmt.Sprintf(`{
+		"region":"us-west-2",
+		"assetId":"%s",
+		"propertyId":"%s",
+		"aggregates":["SUM"],
+		"resolution":"1m",
+		"quality":"ANY"
+	}`, mockAssetId, mockPropertyId)))
+	require.NoError(t, err)
+	require.NoError(t, qdr.Responses["A"].Error)
+
+	frames := qdr.Responses["A"].Frames
+	require.Len(t, frames, 3)
+	for i, quality := range models.AllQualities {
+		require.Equal(t, models.SitewiseCustomMeta{
+			NextToken:  "token-" + string(quality),
+			EntryId:    models.QualityEntryId(*mockAssetPropertyEntryId, quality),
+			Resolution: "1m",
+			Aggregates: []string{models.AggregateSum},
+			Quality:    string(quality),
+		}, frames[i].Meta.Custom)
+		require.Equal(t, data.Labels{"quality": string(quality)}, frames[i].Fields[1].Labels)
+		require.Equal(t, 1688.6+float64(len(quality)), frames[i].Fields[1].At(0))
+	}
+	mockSw.AssertNumberOfCalls(t, "BatchGetAssetPropertyAggregatesPageAggregation", 3)
+
+	// the next page only continues the qualities with a next token
+	qdr, err = srvr.HandlePropertyAggregate(context.Background(), query(fmt.Sprintf(`{
+		"region":"us-west-2",
+		"assetId":"%s",
+		"propertyId":"%s",
+		"aggregates":["SUM"],
+		"resolution":"1m",
+		"qualities":["GOOD","BAD"],
+		"nextTokens":{"%s":"token-BAD"}
+	}`, mockAssetId, mockPropertyId, models.QualityEntryId(*mockAssetPropertyEntryId, iotsitewisetypes.QualityBad))))
+	require.NoError(t, err)
+	require.Len(t, qdr.Responses["A"].Frames, 1)
+	require.Equal(t, "BAD", qdr.Responses["A"].Frames[0].Meta.Custom.(models.SitewiseCustomMeta).Quality)
+	mockSw.AssertNumberOfCalls(t, "BatchGetAssetPropertyAggregatesPageAggregation", 4)
+	mockSw.AssertCalled(t, "BatchGetAssetPropertyAggregatesPageAggregation", mock.Anything, mock.MatchedBy(func(req *iotsitewise.BatchGetAssetPropertyAggregatesInput) bool {
+		return util.Dereference(req.NextToken) == "token-BAD" && req.Entries[0].Qualities[0] == iotsitewisetypes.QualityBad
+	}), mock.Anything, mock.Anything)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/grafana-plugin-sdk-go/experimental"
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/server"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
@@ -22,16 +23,32 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 
 	"github.com/google/go-cmp/cmp"
-	"github.com/patrickmn/go-cache"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
 func mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw *mocks.SitewiseAPIClient, nextToken *string, successEntries []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry, errorEntries []iotsitewisetypes.BatchGetAssetPropertyValueHistoryErrorEntry) {
+	// batches are requested concurrently, so each response is matched to the batch of its first entry
+	var entryId *string
+	if len(successEntries) > 0 {
+		entryId = successEntries[0].EntryId
+	} else if len(errorEntries) > 0 {
+		entryId = errorEntries[0].EntryId
+	}
 	mockSw.On(
 		"BatchGetAssetPropertyValueHistoryPageAggregation",
 		mock.Anything,
-		mock.Anything,
+		mock.MatchedBy(func(req *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+			if entryId == nil {
+				return true
+			}
+			for _, entry := range req.Entries {
+				if *entry.EntryId == *entryId {
+					return true
+				}
+			}
+			return false
+		}),
 		mock.Anything,
 		mock.Anything,
 	).Return(&iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
@@ -71,10 +88,6 @@ func Test_get_property_value_history_with_default_aka_table_response_format(t *t
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -101,7 +114,7 @@ func Test_get_property_value_history_with_default_aka_table_response_format(t *t
 	expectedFrame := data.NewFrame("Demo Turbine Asset 1",
 		data.NewField("time", nil, []time.Time{time.Date(2021, 2, 1, 19, 20, 0, 0, time.UTC)}),
 		data.NewField("Wind Speed", nil, []float64{23.8}).SetConfig(&data.FieldConfig{Unit: "m/s"}),
-		data.NewField("quality", nil, []string{"GOOD"}),
+		qualityField(iotsitewisetypes.QualityGood),
 	).SetMeta(&data.FrameMeta{
 		Custom: models.SitewiseCustomMeta{Resolution: "RAW", EntryId: *mockAssetPropertyEntryId},
 	})
@@ -139,10 +152,6 @@ func Test_get_property_value_history_with_time_series_response_format(t *testing
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -210,10 +219,6 @@ func Test_getPropertyValueBoolean(t *testing.T) {
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -310,10 +315,6 @@ func Test_get_property_value_history_with_flatten_l4e(t *testing.T) {
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -340,7 +341,7 @@ func Test_get_property_value_history_with_flatten_l4e(t *testing.T) {
 
 	expectedFrame := data.NewFrame("Demo Turbine Asset 1",
 		data.NewField("time", nil, []time.Time{time.Date(2021, 2, 1, 19, 20, 0, 0, time.UTC)}),
-		data.NewField("quality", nil, []string{"GOOD"}),
+		qualityField(iotsitewisetypes.QualityGood),
 		data.NewField("anomaly_score", nil, []float64{0.2674}),
 		data.NewField("prediction_reason", nil, []string{"NO_ANOMALY_DETECTED"}),
 		data.NewField("RPM", nil, []float64{0.44856}),
@@ -403,10 +404,6 @@ func Test_get_property_value_history_with_struct_type(t *testing.T) {
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -454,7 +451,7 @@ func Test_get_property_value_history_with_struct_type(t *testing.T) {
 	// Assert quality field
 	require.Contains(t, fieldMap, "quality")
 	require.Equal(t, 1, fieldMap["quality"].Len())
-	require.Equal(t, "GOOD", fieldMap["quality"].At(0).(string))
+	require.Equal(t, fields.QualityValue(iotsitewisetypes.QualityGood), fieldMap["quality"].At(0))
 
 	// Assert parsed JSON fields with their values
 	require.Contains(t, fieldMap, "prediction")
@@ -495,10 +492,6 @@ func Test_getPropertyValueHistoryFromAliasCaseTable(t *testing.T) {
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -536,10 +529,6 @@ func Test_getPropertyValueHistoryFromAliasCaseTable_disassociated_stream(t *test
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -576,10 +565,6 @@ func Test_getPropertyValueHistoryFromAliasCaseTable_disassociated_stream_empty_r
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -621,10 +606,6 @@ func Test_getPropertyValueHistoryFromAliasCaseTimeSeries(t *testing.T) {
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -662,10 +643,6 @@ func Test_getPropertyValueHistoryFromAliasCaseTimeSeries_disassociated_stream(t
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -703,10 +680,6 @@ func Test_getPropertyValueHistoryFromAliasCaseTimeSeries_disassociated_stream_wi
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -748,10 +721,6 @@ func Test_getPropertyValueBooleanFromAlias(t *testing.T) {
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -789,10 +758,6 @@ func Test_getPropertyValueBooleanFromAliasWithDisassociatedStream(t *testing.T)
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -830,10 +795,6 @@ func Test_getPropertyValueBooleanFromAlias_disassociated_stream_with_empty_respo
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -872,10 +833,6 @@ func Test_get_property_value_history_from_expression_query_with_time_series_resp
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
 		Headers:       map[string]string{"http_X-Grafana-From-Expr": "true"},
 		PluginContext: backend.PluginContext{},
@@ -1006,10 +963,6 @@ func Test_get_property_value_history_with_batched_queries(t *testing.T) {
 
 			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-			sitewise.GetCache = func() *cache.Cache {
-				return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-			}
-
 			var baseQuery models.BaseQuery
 			if tc.numPropertyAliases > 0 {
 				baseQuery = models.BaseQuery{
@@ -1094,6 +1047,7 @@ func Test_get_property_value_history_with_batched_queries_with_error(t *testing.
 		mockedErrorEntries := []iotsitewisetypes.BatchGetAssetPropertyValueHistoryErrorEntry{}
 		numBatch := 0
 		errorIndex := 20
+		errorEntryId := ""
 
 		assetIds := generateIds(tc.numAssetIds, mockAssetId)
 		propertyIds := generateIds(tc.numPropertyIds, mockPropertyId)
@@ -1104,6 +1058,7 @@ func Test_get_property_value_history_with_batched_queries_with_error(t *testing.
 
 				// Build one error entry
 				if a*tc.numPropertyIds+p == errorIndex {
+					errorEntryId = *entryId
 					mockedErrorEntries = append(mockedErrorEntries, iotsitewisetypes.BatchGetAssetPropertyValueHistoryErrorEntry{
 						ErrorCode:    iotsitewisetypes.BatchGetAssetPropertyValueHistoryErrorCodeResourceNotFoundException,
 						ErrorMessage: Pointer("Asset property not found."),
@@ -1132,10 +1087,6 @@ func Test_get_property_value_history_with_batched_queries_with_error(t *testing.
 
 		srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-		sitewise.GetCache = func() *cache.Cache {
-			return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-		}
-
 		var baseQuery models.BaseQuery
 		if tc.numPropertyAliases > 0 {
 			baseQuery = models.BaseQuery{
@@ -1177,7 +1128,12 @@ func Test_get_property_value_history_with_batched_queries_with_error(t *testing.
 		for _, f := range qdr.Responses["A"].Frames {
 			if len(f.Meta.Notices) > 0 {
 				expectedErrorFrame := data.NewFrame("Demo Turbine Asset 1 Wind Speed").SetMeta(&data.FrameMeta{
-					Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: "Asset property not found."}},
+					Custom: models.SitewiseCustomMeta{
+						EntryId:     errorEntryId,
+						EntryStatus: models.EntryStatusError,
+						ErrorCode:   "ResourceNotFoundException",
+					},
+					Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: "Demo Turbine Asset 1 Wind Speed: ResourceNotFoundException: Asset property not found."}},
 				},
 				)
 				if diff := cmp.Diff(expectedErrorFrame, f, data.FrameTestCompareOptions()...); diff != "" {
@@ -1191,3 +1147,66 @@ func Test_get_property_value_history_with_batched_queries_with_error(t *testing.
 		mockSw.AssertExpectations(t)
 	})
 }
+
+func Test_get_property_value_history_filters_qualities_per_point(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockDescribeAssetProperty(mockSw)
+	mockDescribeAsset(mockSw)
+	mockDescribeAssetModel(mockSw)
+
+	entry := mockBatchGetAssetPropertyValueHistorySuccessEntry(mockAssetPropertyEntryId, 0)
+	for i, quality := range []iotsitewisetypes.Quality{iotsitewisetypes.QualityUncertain, iotsitewisetypes.QualityBad} {
+		value := mockBatchGetAssetPropertyValueHistorySuccessEntry(mockAssetPropertyEntryId, i+1).AssetPropertyValueHistory[0]
+		value.Quality = quality
+		entry.AssetPropertyValueHistory = append(entry.AssetPropertyValueHistory, value)
+	}
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{entry}, nil)
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID:     "A",
+			QueryType: models.QueryTypePropertyValueHistory,
+			TimeRange: timeRange,
+			JSON: []byte(fmt.Sprintf(`{
+				"region":"us-west-2",
+				"assetId":"%s",
+				"propertyId":"%s",
+				"qualities":["GOOD","BAD"]
+			}`, mockAssetId, mockPropertyId)),
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, qdr.Responses["A"].Error)
+
+	// the history APIs take a single quality, so the qualities are requested without a filter
+	mockSw.AssertCalled(t, "BatchGetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.MatchedBy(func(req *iotsitewise.BatchGetAssetPropertyValueHistoryInput) bool {
+		return req.Entries[0].Qualities == nil
+	}), mock.Anything, mock.Anything)
+
+	expectedFrame := data.NewFrame("Demo Turbine Asset 1",
+		data.NewField("time", nil, []time.Time{time.Unix(1612207200, 0), time.Unix(1612207202, 0)}),
+		data.NewField("Wind Speed", nil, []float64{23.8, 25.8}).SetConfig(&data.FieldConfig{Unit: "m/s"}),
+		qualityField(iotsitewisetypes.QualityGood, iotsitewisetypes.QualityBad),
+	).SetMeta(&data.FrameMeta{
+		Custom: models.SitewiseCustomMeta{Resolution: "RAW", EntryId: *mockAssetPropertyEntryId},
+	})
+	if diff := cmp.Diff(expectedFrame, qdr.Responses["A"].Frames[0], data.FrameTestCompareOptions()...); diff != "" {
+		t.Errorf("Result mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_get_property_value_history_rejects_unknown_qualities(t *testing.T) {
+	srvr := &server.Server{Datasource: mockedDatasource(&mocks.SitewiseAPIClient{}).(*sitewise.Datasource)}
+	qdr, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID:     "A",
+			QueryType: models.QueryTypePropertyValueHistory,
+			TimeRange: timeRange,
+			JSON:      []byte(`{"region":"us-west-2","assetId":"a","propertyId":"p","qualities":["GOOD","FINE"]}`),
+		}},
+	})
+	require.NoError(t, err)
+	require.ErrorContains(t, qdr.Responses["A"].Error, `unknown quality "FINE": use GOOD, BAD, UNCERTAIN or ANY`)
+	require.Equal(t, backend.StatusValidationFailed, qdr.Responses["A"].Status)
+}
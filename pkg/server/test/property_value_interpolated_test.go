@@ -22,7 +22,6 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 
 	"github.com/google/go-cmp/cmp"
-	"github.com/patrickmn/go-cache"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -78,9 +77,6 @@ func TestPropertyValueInterpolatedQueryWithPropertyAlias(t *testing.T) {
 	}, nil)
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
 
 	query := &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
@@ -110,9 +106,10 @@ func TestPropertyValueInterpolatedQueryWithPropertyAlias(t *testing.T) {
 			data.NewField("Wind Speed", nil, []float64{1.1}).SetConfig(&data.FieldConfig{Unit: "m/s"}),
 		).SetMeta(&data.FrameMeta{
 			Custom: models.SitewiseCustomMeta{
-				NextToken:  "asset1-next-token",
-				EntryId:    *mockAssetPropertyEntryId,
-				Resolution: "1m",
+				NextToken:     "asset1-next-token",
+				EntryId:       *mockAssetPropertyEntryId,
+				Resolution:    "1m",
+				Interpolation: models.InterpolationTypeLinear,
 			},
 		}),
 	}
@@ -136,9 +133,6 @@ func TestPropertyValueInterpolatedQueryWithDisassociatedPropertyAlias(t *testing
 	}, nil)
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
 
 	query := &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
@@ -168,9 +162,10 @@ func TestPropertyValueInterpolatedQueryWithDisassociatedPropertyAlias(t *testing
 			data.NewField(mockPropertyAlias, nil, []float64{1.1}).SetConfig(&data.FieldConfig{}),
 		).SetMeta(&data.FrameMeta{
 			Custom: models.SitewiseCustomMeta{
-				NextToken:  "asset1-next-token",
-				EntryId:    *mockPropertyAliasEntryId,
-				Resolution: "1m",
+				NextToken:     "asset1-next-token",
+				EntryId:       *mockPropertyAliasEntryId,
+				Resolution:    "1m",
+				Interpolation: models.InterpolationTypeLinear,
 			},
 		}),
 	}
@@ -245,9 +240,6 @@ func TestPropertyValueInterpolatedQueryMultipleIds(t *testing.T) {
 			}
 
 			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
-			sitewise.GetCache = func() *cache.Cache {
-				return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-			}
 
 			var baseQuery models.BaseQuery
 			if tc.numPropertyAliases > 0 {
@@ -316,9 +308,10 @@ func TestPropertyValueInterpolatedQueryMultipleIds(t *testing.T) {
 					propertyField,
 				).SetMeta(&data.FrameMeta{
 					Custom: models.SitewiseCustomMeta{
-						NextToken:  expectedNextToken,
-						EntryId:    entryId,
-						Resolution: "1m",
+						NextToken:     expectedNextToken,
+						EntryId:       entryId,
+						Resolution:    "1m",
+						Interpolation: models.InterpolationTypeLinear,
 					},
 				}))
 				// require.Equal(t, entryId, f.Meta.Custom.(models.SitewiseCustomMeta).EntryId)
@@ -333,3 +326,183 @@ func TestPropertyValueInterpolatedQueryMultipleIds(t *testing.T) {
 		})
 	}
 }
+
+func TestPropertyValueInterpolatedQueryReportsFailedEntries(t *testing.T) {
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockGetInterpolatedAssetPropertyValuesPageAggregation(mockSw, nil, Pointer(1.1), func(input *iotsitewise.GetInterpolatedAssetPropertyValuesInput) bool {
+		return *input.PropertyId == mockPropertyId
+	})
+	mockSw.On("GetInterpolatedAssetPropertyValuesPageAggregation", mock.Anything, mock.MatchedBy(func(input *iotsitewise.GetInterpolatedAssetPropertyValuesInput) bool {
+		return *input.PropertyId == mockSecondPropertyId
+	}), mock.Anything, mock.Anything).Return(nil, notFoundError())
+	for _, property := range []struct{ id, name string }{{mockPropertyId, "Wind Speed"}, {mockSecondPropertyId, "Torque"}} {
+		mockSw.On("DescribeAssetProperty", mock.Anything, &iotsitewise.DescribeAssetPropertyInput{
+			AssetId:    aws.String(mockAssetId),
+			PropertyId: aws.String(property.id),
+		}, mock.Anything).Return(&iotsitewise.DescribeAssetPropertyOutput{
+			AssetId:   Pointer(mockAssetId),
+			AssetName: Pointer("Demo Turbine Asset 1"),
+			AssetProperty: &iotsitewisetypes.Property{
+				DataType: iotsitewisetypes.PropertyDataTypeDouble,
+				Name:     Pointer(property.name),
+				Id:       aws.String(property.id),
+			},
+		}, nil)
+	}
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+	qdr, err := srvr.HandleInterpolatedPropertyValue(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			QueryType: models.QueryTypePropertyInterpolated,
+			RefID:     "A",
+			TimeRange: timeRange,
+			JSON: testdata.SerializeStruct(t, models.AssetPropertyValueQuery{
+				BaseQuery: models.BaseQuery{
+					AwsRegion:   testdata.AwsRegion,
+					AssetIds:    []string{mockAssetId},
+					PropertyIds: []string{mockPropertyId, mockSecondPropertyId},
+				},
+				Resolution: "1m",
+			}),
+		}},
+	})
+	require.NoError(t, err)
+
+	res := qdr.Responses["A"]
+	require.NoError(t, res.Error)
+	require.Len(t, res.Frames, 2)
+	require.Equal(t, &models.EntrySummary{Entries: 2, Succeeded: 1, Errored: 1, Partial: true}, res.Frames[0].Meta.Custom.(models.SitewiseCustomMeta).Summary)
+
+	failed := res.Frames[1]
+	require.Equal(t, "Demo Turbine Asset 1 Torque", failed.Name)
+	require.Equal(t, models.EntryStatusError, failed.Meta.Custom.(models.SitewiseCustomMeta).EntryStatus)
+	require.Equal(t, []data.Notice{{Severity: data.NoticeSeverityError, Text: "Demo Turbine Asset 1 Torque: ResourceNotFoundException: not found"}}, failed.Meta.Notices)
+}
+
+func TestPropertyValueInterpolatedQueryInterpolationTypes(t *testing.T) {
+	tests := []struct {
+		name                  string
+		dataType              iotsitewisetypes.PropertyDataType
+		interpolationType     string
+		intervalWindow        string
+		expectedType          string
+		expectedWindow        *int64
+		expectedInterpolation string
+	}{
+		{
+			name:                  "string property defaults to LOCF",
+			dataType:              iotsitewisetypes.PropertyDataTypeString,
+			expectedType:          "LOCF_INTERPOLATION",
+			expectedInterpolation: models.InterpolationTypeLOCF,
+		},
+		{
+			name:                  "numeric property with LOCF",
+			dataType:              iotsitewisetypes.PropertyDataTypeDouble,
+			interpolationType:     models.InterpolationTypeLOCF,
+			expectedType:          "LOCF_INTERPOLATION",
+			expectedInterpolation: models.InterpolationTypeLOCF,
+		},
+		{
+			name:                  "linear with an interval window",
+			dataType:              iotsitewisetypes.PropertyDataTypeDouble,
+			interpolationType:     models.InterpolationTypeLinear,
+			intervalWindow:        "1h",
+			expectedType:          "LINEAR_INTERPOLATION",
+			expectedWindow:        Pointer(int64(3600)),
+			expectedInterpolation: "LINEAR, window 1h0m0s",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			value := &iotsitewisetypes.Variant{DoubleValue: Pointer(1.1)}
+			if tc.dataType == iotsitewisetypes.PropertyDataTypeString {
+				value = &iotsitewisetypes.Variant{StringValue: Pointer("on")}
+			}
+			var input *iotsitewise.GetInterpolatedAssetPropertyValuesInput
+			mockSw := &mocks.SitewiseAPIClient{}
+			mockSw.On("GetInterpolatedAssetPropertyValuesPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				input = args.Get(1).(*iotsitewise.GetInterpolatedAssetPropertyValuesInput)
+			}).Return(&iotsitewise.GetInterpolatedAssetPropertyValuesOutput{
+				InterpolatedAssetPropertyValues: []iotsitewisetypes.InterpolatedAssetPropertyValue{{
+					Timestamp: &iotsitewisetypes.TimeInNanos{OffsetInNanos: Pointer(int32(0)), TimeInSeconds: Pointer(int64(1612207200))},
+					Value:     value,
+				}},
+			}, nil)
+			mockSw.On("DescribeAssetProperty", mock.Anything, mock.Anything, mock.Anything).Return(&iotsitewise.DescribeAssetPropertyOutput{
+				AssetId:   Pointer(mockAssetId),
+				AssetName: Pointer("Demo Turbine Asset 1"),
+				AssetProperty: &iotsitewisetypes.Property{
+					DataType: tc.dataType,
+					Name:     Pointer("Mode"),
+					Id:       aws.String(mockPropertyId),
+				},
+			}, nil)
+
+			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+			qdr, err := srvr.HandleInterpolatedPropertyValue(context.Background(), &backend.QueryDataRequest{
+				Queries: []backend.DataQuery{{
+					QueryType: models.QueryTypePropertyInterpolated,
+					RefID:     "A",
+					TimeRange: timeRange,
+					JSON: testdata.SerializeStruct(t, models.AssetPropertyValueQuery{
+						BaseQuery: models.BaseQuery{
+							AwsRegion:   testdata.AwsRegion,
+							AssetIds:    []string{mockAssetId},
+							PropertyIds: []string{mockPropertyId},
+						},
+						Resolution:        "1m",
+						InterpolationType: tc.interpolationType,
+						IntervalWindow:    tc.intervalWindow,
+					}),
+				}},
+			})
+			require.NoError(t, err)
+			res := qdr.Responses["A"]
+			require.NoError(t, res.Error)
+			require.Equal(t, tc.expectedInterpolation, res.Frames[0].Meta.Custom.(models.SitewiseCustomMeta).Interpolation)
+
+			require.Equal(t, tc.expectedType, *input.Type)
+			require.Equal(t, tc.expectedWindow, input.IntervalWindowInSeconds)
+		})
+	}
+}
+
+func TestPropertyValueInterpolatedQueryRejectsInvalidInterpolation(t *testing.T) {
+	tests := []struct {
+		name              string
+		interpolationType string
+		intervalWindow    string
+		expected          string
+	}{
+		{name: "unknown type", interpolationType: "CUBIC", expected: `unknown interpolation type "CUBIC": use LINEAR or LOCF`},
+		{name: "window with LOCF", interpolationType: models.InterpolationTypeLOCF, intervalWindow: "1h", expected: "an interval window is only supported by LINEAR interpolation"},
+		{name: "invalid window", intervalWindow: "soon", expected: `invalid interval window "soon"`},
+		{name: "window out of range", intervalWindow: "20y", expected: "the interval window 20y is out of range"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSw := &mocks.SitewiseAPIClient{}
+			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+			qdr, err := srvr.HandleInterpolatedPropertyValue(context.Background(), &backend.QueryDataRequest{
+				Queries: []backend.DataQuery{{
+					QueryType: models.QueryTypePropertyInterpolated,
+					RefID:     "A",
+					TimeRange: timeRange,
+					JSON: testdata.SerializeStruct(t, models.AssetPropertyValueQuery{
+						BaseQuery: models.BaseQuery{
+							AwsRegion:   testdata.AwsRegion,
+							AssetIds:    []string{mockAssetId},
+							PropertyIds: []string{mockPropertyId},
+						},
+						InterpolationType: tc.interpolationType,
+						IntervalWindow:    tc.intervalWindow,
+					}),
+				}},
+			})
+			require.NoError(t, err)
+			require.ErrorContains(t, qdr.Responses["A"].Error, tc.expected)
+			require.Equal(t, backend.StatusValidationFailed, qdr.Responses["A"].Status)
+			mockSw.AssertNotCalled(t, "GetInterpolatedAssetPropertyValuesPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
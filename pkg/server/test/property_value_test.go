@@ -13,6 +13,7 @@ import (
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/server"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
@@ -22,7 +23,6 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 
 	"github.com/google/go-cmp/cmp"
-	"github.com/patrickmn/go-cache"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -65,10 +65,6 @@ func Test_property_value_query_by_asset_id_and_property_id(t *testing.T) {
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValue(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -93,7 +89,7 @@ func Test_property_value_query_by_asset_id_and_property_id(t *testing.T) {
 	expectedFrame := data.NewFrame("Demo Turbine Asset 1",
 		data.NewField("time", nil, []time.Time{time.Date(2021, 2, 1, 19, 20, 0, 0, time.UTC)}),
 		data.NewField("Wind Speed", nil, []float64{23.8}).SetConfig(&data.FieldConfig{Unit: "m/s"}),
-		data.NewField("quality", nil, []string{"GOOD"}),
+		qualityField(iotsitewisetypes.QualityGood),
 	).SetMeta(&data.FrameMeta{
 		Custom: models.SitewiseCustomMeta{EntryId: *mockAssetPropertyEntryId},
 	})
@@ -171,10 +167,6 @@ func Test_property_value_query_by_asset_id_and_property_id_of_flatten_L4E_anomal
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValue(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -199,7 +191,7 @@ func Test_property_value_query_by_asset_id_and_property_id_of_flatten_L4E_anomal
 
 	expectedFrame := data.NewFrame("Demo Turbine Asset 1",
 		data.NewField("time", nil, []time.Time{time.Date(2021, 2, 1, 19, 20, 0, 0, time.UTC)}),
-		data.NewField("quality", nil, []string{"GOOD"}),
+		qualityField(iotsitewisetypes.QualityGood),
 		data.NewField("anomaly_score", nil, []float64{0.2674}),
 		data.NewField("prediction_reason", nil, []string{"NO_ANOMALY_DETECTED"}),
 		data.NewField("RPM", nil, []float64{0.44856}),
@@ -241,10 +233,6 @@ func Test_property_value_query_by_asset_id_and_property_id_of_struct_type(t *tes
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValue(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -287,7 +275,7 @@ func Test_property_value_query_by_asset_id_and_property_id_of_struct_type(t *tes
 
 	// Assert quality field
 	require.Contains(t, fieldMap, "quality")
-	require.Equal(t, "GOOD", fieldMap["quality"].At(0).(string))
+	require.Equal(t, fields.QualityValue(iotsitewisetypes.QualityGood), fieldMap["quality"].At(0))
 
 	// Assert parsed JSON fields with their values
 	require.Contains(t, fieldMap, "prediction")
@@ -342,10 +330,6 @@ func Test_property_value_query_by_alias_associated_stream(t *testing.T) {
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValue(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -369,7 +353,7 @@ func Test_property_value_query_by_alias_associated_stream(t *testing.T) {
 	expectedFrame := data.NewFrame("Demo Turbine Asset 1",
 		data.NewField("time", nil, []time.Time{time.Date(2021, 2, 1, 19, 20, 0, 0, time.UTC)}),
 		data.NewField("Wind Speed", nil, []float64{23.8}).SetConfig(&data.FieldConfig{Unit: "m/s"}),
-		data.NewField("quality", nil, []string{"GOOD"}),
+		qualityField(iotsitewisetypes.QualityGood),
 	).SetMeta(&data.FrameMeta{
 		Custom: models.SitewiseCustomMeta{EntryId: *mockAssetPropertyEntryId},
 	})
@@ -415,10 +399,6 @@ func Test_property_value_query_by_alias_disassociated_stream(t *testing.T) {
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValue(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -442,7 +422,7 @@ func Test_property_value_query_by_alias_disassociated_stream(t *testing.T) {
 	expectedFrame := data.NewFrame("",
 		data.NewField("time", nil, []time.Time{time.Date(2021, 2, 1, 19, 20, 0, 0, time.UTC)}),
 		data.NewField(mockPropertyAlias, nil, []float64{23.8}).SetConfig(&data.FieldConfig{Unit: ""}),
-		data.NewField("quality", nil, []string{"GOOD"}),
+		qualityField(iotsitewisetypes.QualityGood),
 	).SetMeta(&data.FrameMeta{
 		Custom: models.SitewiseCustomMeta{EntryId: *mockPropertyAliasEntryId},
 	})
@@ -484,10 +464,6 @@ func Test_property_value_query_by_alias_disassociated_stream_with_integer_value(
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValue(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -511,7 +487,7 @@ func Test_property_value_query_by_alias_disassociated_stream_with_integer_value(
 	expectedFrame := data.NewFrame("",
 		data.NewField("time", nil, []time.Time{time.Date(2021, 2, 1, 19, 20, 0, 0, time.UTC)}),
 		data.NewField(mockPropertyAlias, nil, []int64{23}).SetConfig(&data.FieldConfig{Unit: ""}),
-		data.NewField("quality", nil, []string{"GOOD"}),
+		qualityField(iotsitewisetypes.QualityGood),
 	).SetMeta(&data.FrameMeta{
 		Custom: models.SitewiseCustomMeta{EntryId: *mockPropertyAliasEntryId},
 	})
@@ -554,10 +530,6 @@ func Test_property_value_query_with_empty_property_value_results(t *testing.T) {
 
 	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyValue(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -582,7 +554,7 @@ func Test_property_value_query_with_empty_property_value_results(t *testing.T) {
 	expectedFrame := data.NewFrame("Demo Turbine Asset 1",
 		data.NewField("time", nil, []time.Time{}),
 		data.NewField("Wind Speed", nil, []float64{}).SetConfig(&data.FieldConfig{Unit: "m/s"}),
-		data.NewField("quality", nil, []string{}),
+		qualityField(),
 	).SetMeta(&data.FrameMeta{
 		Custom: models.SitewiseCustomMeta{EntryId: *mockAssetPropertyEntryId},
 	})
@@ -691,10 +663,6 @@ func Test_property_value_query_with_batched_queries(t *testing.T) {
 
 			srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-			sitewise.GetCache = func() *cache.Cache {
-				return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-			}
-
 			var baseQuery models.BaseQuery
 			if tc.numPropertyAliases > 0 {
 				baseQuery = models.BaseQuery{
@@ -774,6 +742,7 @@ func Test_property_value_query_with_batched_queries_with_error(t *testing.T) {
 		mockedErrorEntries := []iotsitewisetypes.BatchGetAssetPropertyValueErrorEntry{}
 		numBatch := 0
 		errorIndex := 20
+		errorEntryId := ""
 
 		assetIds := generateIds(tc.numAssetIds, mockAssetId)
 		propertyIds := generateIds(tc.numPropertyIds, mockPropertyId)
@@ -784,6 +753,7 @@ func Test_property_value_query_with_batched_queries_with_error(t *testing.T) {
 
 				// Build one error entry
 				if a*tc.numPropertyIds+p == errorIndex {
+					errorEntryId = *entryId
 					mockedErrorEntries = append(mockedErrorEntries, iotsitewisetypes.BatchGetAssetPropertyValueErrorEntry{
 						ErrorCode:    iotsitewisetypes.BatchGetAssetPropertyValueErrorCodeResourceNotFoundException,
 						ErrorMessage: Pointer("Asset property not found."),
@@ -814,10 +784,6 @@ func Test_property_value_query_with_batched_queries_with_error(t *testing.T) {
 
 		srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
 
-		sitewise.GetCache = func() *cache.Cache {
-			return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-		}
-
 		var baseQuery models.BaseQuery
 		if tc.numPropertyAliases > 0 {
 			baseQuery = models.BaseQuery{
@@ -858,8 +824,13 @@ func Test_property_value_query_with_batched_queries_with_error(t *testing.T) {
 		numErrors := 0
 		for _, f := range qdr.Responses["A"].Frames {
 			if len(f.Meta.Notices) > 0 {
-				expectedErrorFrame := data.NewFrame("Demo Turbine Asset 1").SetMeta(&data.FrameMeta{
-					Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: "Asset property not found."}},
+				expectedErrorFrame := data.NewFrame("Demo Turbine Asset 1 Wind Speed").SetMeta(&data.FrameMeta{
+					Custom: models.SitewiseCustomMeta{
+						EntryId:     errorEntryId,
+						EntryStatus: models.EntryStatusError,
+						ErrorCode:   "ResourceNotFoundException",
+					},
+					Notices: []data.Notice{{Severity: data.NoticeSeverityError, Text: "Demo Turbine Asset 1 Wind Speed: ResourceNotFoundException: Asset property not found."}},
 				},
 				)
 				if diff := cmp.Diff(expectedErrorFrame, f, data.FrameTestCompareOptions()...); diff != "" {
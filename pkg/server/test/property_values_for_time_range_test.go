@@ -17,7 +17,6 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/testdata"
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 
-	"github.com/patrickmn/go-cache"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -46,10 +45,6 @@ func Test_propertyValueForTimeRange_raw_data_for_time_range(t *testing.T) {
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -110,10 +105,6 @@ func Test_propertyValueForTimeRange_1m_data_for_time_range(t *testing.T) {
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -174,10 +165,6 @@ func Test_propertyValueForTimeRange_1h_data_for_time_range(t *testing.T) {
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -238,10 +225,6 @@ func Test_propertyValueForTimeRange_1d_data_for_time_range(t *testing.T) {
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -302,10 +285,6 @@ func Test_propertyValueForTimeRange_1m_data_for_reduced_max_data_point(t *testin
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -364,10 +343,6 @@ func Test_propertyValueForTimeRange_raw_data_for_time_range_from_alias(t *testin
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -425,10 +400,6 @@ func Test_propertyValueForTimeRange_1m_data_for_time_range_from_alias(t *testing
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -486,10 +457,6 @@ func Test_propertyValueForTimeRange_1h_data_for_time_range_from_alias(t *testing
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -547,10 +514,6 @@ func Test_propertyValueForTimeRange_1d_data_for_time_range_from_alias(t *testing
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
@@ -608,10 +571,6 @@ func Test_propertyValueForTimeRange_1m_data_for_reduced_max_data_point_from_alia
 		Datasource: mockedDatasource(mockSw).(*sitewise.Datasource),
 	}
 
-	sitewise.GetCache = func() *cache.Cache {
-		return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-	}
-
 	qdr, err := srvr.HandlePropertyAggregate(context.Background(), &backend.QueryDataRequest{
 		PluginContext: backend.PluginContext{},
 		Queries: []backend.DataQuery{
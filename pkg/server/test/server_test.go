@@ -6,8 +6,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/patrickmn/go-cache"
-
 	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/server"
@@ -77,10 +75,6 @@ func runTestScenario(t *testing.T, scenario *testScenario) {
 			Datasource: mockedDatasource(scenario.mockSw).(*sitewise.Datasource),
 		}
 
-		sitewise.GetCache = func() *cache.Cache {
-			return cache.New(cache.DefaultExpiration, cache.NoExpiration)
-		}
-
 		qdr, err := scenario.handlerFn(srvr)(ctx, req)
 
 		// this should always be nil, as the error is wrapped in the QueryDataResponse
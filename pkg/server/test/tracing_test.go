@@ -0,0 +1,61 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/server"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+)
+
+func Test_property_value_history_query_is_traced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracing.InitDefaultTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test"))
+	t.Cleanup(func() { tracing.InitDefaultTracer(nil) })
+
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockBatchGetAssetPropertyValueHistoryPageAggregation(mockSw, nil, []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{
+		mockBatchGetAssetPropertyValueHistorySuccessEntry(mockAssetPropertyEntryId, 0),
+	}, nil)
+	mockDescribeAssetProperty(mockSw)
+	mockDescribeAsset(mockSw)
+	mockDescribeAssetModel(mockSw)
+
+	srvr := &server.Server{Datasource: mockedDatasource(mockSw).(*sitewise.Datasource)}
+	_, err := srvr.HandlePropertyValueHistory(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			QueryType:     models.QueryTypePropertyValueHistory,
+			RefID:         "A",
+			MaxDataPoints: 100,
+			Interval:      1000,
+			TimeRange:     timeRange,
+			JSON: []byte(fmt.Sprintf(`{
+				"region":"us-west-2",
+				"assetIds":["%s"],
+				"propertyIds":["%s"],
+				"resolution":"1m"
+			}`, mockAssetId, mockPropertyId)),
+		}},
+	})
+	require.NoError(t, err)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	require.Contains(t, spans["sitewise.query"].Attributes(), attribute.String("sitewise.ref_id", "A"))
+	require.Contains(t, spans["Datasource.HandleGetAssetPropertyValueHistoryQuery"].Attributes(), attribute.String("sitewise.resolution", "1m"))
+	require.Contains(t, spans["api.BatchGetAssetPropertyValues"].Attributes(), attribute.Int("sitewise.entries", 1))
+	require.Contains(t, spans["framer.Frames"].Attributes(), attribute.Bool("sitewise.next_token", false))
+}
@@ -6,7 +6,9 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
 	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
 	"github.com/stretchr/testify/mock"
 )
@@ -20,6 +22,15 @@ type batch_test struct {
 
 func Pointer[T any](v T) *T { return &v }
 
+// qualityField is the quality enum field of the data points with the given qualities
+func qualityField(qualities ...iotsitewisetypes.Quality) *data.Field {
+	field := fields.QualityField(len(qualities))
+	for i, quality := range qualities {
+		field.Set(i, fields.QualityValue(quality))
+	}
+	return field
+}
+
 func generateIds(numIds int, idString string) []string {
 	ids := []string{}
 	for i := 1; i <= numIds; i++ {
@@ -0,0 +1,443 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api/propvals"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// A batch plan fetches the entries of several queries of a request together, so that queries
+// with the same parameters share batch API calls instead of each filling their own batches.
+// Entries requested by more than one query are only fetched once. The results are split back
+// out per query by EntryId.
+//
+// A page token returned for a merged batch continues that merged batch, so it is handed to every
+// entry of the batch. The queries of the next page carry the token of each entry, and the entries
+// with the same token are merged into the same batch again.
+
+// PlannedQuery is the resolved query and the framer of a query that was fetched as part of a batch plan
+type PlannedQuery[T any] struct {
+	Query  models.AssetPropertyValueQuery
+	Framer T
+}
+
+// planGroup is a set of queries that can share batches, and the entries they share. The entries
+// of a group with a next token continue the merged batch that returned it.
+type planGroup struct {
+	queries   []int
+	entries   []models.AssetPropertyEntry
+	entryIds  map[string]bool
+	nextToken string
+}
+
+// query is the query of the batches of the group
+func (g *planGroup) query(resolved []models.AssetPropertyValueQuery) models.AssetPropertyValueQuery {
+	query := resolved[g.queries[0]]
+	query.AssetPropertyEntries = g.entries
+	query.NextToken = g.nextToken
+	query.NextTokens = nil
+	return query
+}
+
+// groupPlannedQueries resolves the entries of the queries and groups the entries of the queries
+// with the same key and next token. The unique entries of each group are collected in order of
+// appearance, and the entries of a query without a next token on a next page are left out, since
+// they are complete.
+func groupPlannedQueries(ctx context.Context, sw client.SitewiseAPIClient, queries []models.AssetPropertyValueQuery, key func(models.AssetPropertyValueQuery) string) ([]models.AssetPropertyValueQuery, []*planGroup, error) {
+	resolved := make([]models.AssetPropertyValueQuery, len(queries))
+	groups := []*planGroup{}
+	byKey := map[string]*planGroup{}
+
+	for i, query := range queries {
+		modifiedQuery, err := getAssetIdAndPropertyId(query, sw, ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		resolved[i] = modifiedQuery
+
+		for _, entry := range modifiedQuery.AssetPropertyEntries {
+			entryId := *util.GetEntryIdFromAssetPropertyEntry(entry)
+			nextToken := modifiedQuery.NextTokens[entryId]
+			if len(modifiedQuery.NextTokens) > 0 && nextToken == "" {
+				continue
+			}
+
+			k := key(modifiedQuery) + "/" + nextToken
+			group, ok := byKey[k]
+			if !ok {
+				group = &planGroup{entryIds: map[string]bool{}, nextToken: nextToken}
+				byKey[k] = group
+				groups = append(groups, group)
+			}
+			if !slices.Contains(group.queries, i) {
+				group.queries = append(group.queries, i)
+			}
+			if !group.entryIds[entryId] {
+				group.entryIds[entryId] = true
+				group.entries = append(group.entries, entry)
+			}
+		}
+	}
+
+	return resolved, groups, nil
+}
+
+func planKey(query models.AssetPropertyValueQuery, extra ...any) string {
+	return fmt.Sprint(query.TimeRange.From.UnixNano(), query.TimeRange.To.UnixNano(), query.TimeOrdering, query.GetQualities(),
+		query.MaxPageAggregations, query.MaxDataPoints, extra)
+}
+
+// plannedEntries indexes the entries of the responses of a group by EntryId
+type plannedEntries[S, E, K any] struct {
+	success    map[string]S
+	errors     map[string]E
+	skipped    map[string]K
+	nextTokens map[string]*string
+}
+
+func newPlannedEntries[S, E, K any]() *plannedEntries[S, E, K] {
+	return &plannedEntries[S, E, K]{
+		success:    map[string]S{},
+		errors:     map[string]E{},
+		skipped:    map[string]K{},
+		nextTokens: map[string]*string{},
+	}
+}
+
+// plannedResponse is the part of the responses of a group that belongs to a query, for the
+// entries of the query that share a next token
+type plannedResponse[S, E, K any] struct {
+	nextToken *string
+	success   []S
+	errors    []E
+	skipped   []K
+}
+
+// split collects the entries of a query in a group, with a response per next token
+func (p *plannedEntries[S, E, K]) split(query models.AssetPropertyValueQuery, group *planGroup) []*plannedResponse[S, E, K] {
+	responses := []*plannedResponse[S, E, K]{}
+	byToken := map[string]*plannedResponse[S, E, K]{}
+	for _, entry := range query.AssetPropertyEntries {
+		entryId := *util.GetEntryIdFromAssetPropertyEntry(entry)
+		if !group.entryIds[entryId] {
+			continue
+		}
+		nextToken := p.nextTokens[entryId]
+		response, ok := byToken[util.Dereference(nextToken)]
+		if !ok {
+			response = &plannedResponse[S, E, K]{nextToken: nextToken, success: []S{}, errors: []E{}, skipped: []K{}}
+			byToken[util.Dereference(nextToken)] = response
+			responses = append(responses, response)
+		}
+		if e, ok := p.success[entryId]; ok {
+			response.success = append(response.success, e)
+		}
+		if e, ok := p.errors[entryId]; ok {
+			response.errors = append(response.errors, e)
+		}
+		if e, ok := p.skipped[entryId]; ok {
+			response.skipped = append(response.skipped, e)
+		}
+	}
+	return responses
+}
+
+// PlanBatchGetAssetPropertyValues fetches the history of the queries with shared batches
+func PlanBatchGetAssetPropertyValues(ctx context.Context, sw client.SitewiseAPIClient, queries []models.AssetPropertyValueQuery) ([]*PlannedQuery[*framer.AssetPropertyValueHistoryBatch], error) {
+	resolved, groups, err := groupPlannedQueries(ctx, sw, queries, func(q models.AssetPropertyValueQuery) string { return planKey(q) })
+	if err != nil {
+		return nil, err
+	}
+
+	type historyResponse = plannedResponse[iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry, iotsitewisetypes.BatchGetAssetPropertyValueHistoryErrorEntry, iotsitewisetypes.BatchGetAssetPropertyValueHistorySkippedEntry]
+	split := make([][]*historyResponse, len(queries))
+	for _, group := range groups {
+		groupQuery := group.query(resolved)
+		batchedQueries := batchQueriesInitial(groupQuery, BatchGetAssetPropertyValueHistoryMaxEntries)
+
+		responses := make([]*iotsitewise.BatchGetAssetPropertyValueHistoryOutput, len(batchedQueries))
+		eg, ectx := errgroup.WithContext(ctx)
+		eg.SetLimit(MaxConcurrentRequests)
+		for i, q := range batchedQueries {
+			awsReq := historyBatchQueryToInput(q)
+			eg.Go(func() error {
+				resp, err := sw.BatchGetAssetPropertyValueHistoryPageAggregation(ectx, awsReq, groupQuery.MaxPageAggregations, int(groupQuery.MaxDataPoints))
+				if err != nil {
+					return err
+				}
+				responses[i] = filterBatchHistoryQualities(q, resp)
+				return nil
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			return nil, err
+		}
+
+		entries := newPlannedEntries[iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry, iotsitewisetypes.BatchGetAssetPropertyValueHistoryErrorEntry, iotsitewisetypes.BatchGetAssetPropertyValueHistorySkippedEntry]()
+		for i, resp := range responses {
+			for _, entry := range batchedQueries[i].AssetPropertyEntries {
+				entries.nextTokens[*util.GetEntryIdFromAssetPropertyEntry(entry)] = resp.NextToken
+			}
+			for _, e := range resp.SuccessEntries {
+				entries.success[*e.EntryId] = e
+			}
+			for _, e := range resp.ErrorEntries {
+				entries.errors[*e.EntryId] = e
+			}
+			for _, e := range resp.SkippedEntries {
+				entries.skipped[*e.EntryId] = e
+			}
+		}
+
+		for _, i := range group.queries {
+			split[i] = append(split[i], entries.split(resolved[i], group)...)
+		}
+	}
+
+	planned := make([]*PlannedQuery[*framer.AssetPropertyValueHistoryBatch], len(queries))
+	for i, responses := range split {
+		if len(responses) == 0 {
+			continue
+		}
+		historyBatch := &framer.AssetPropertyValueHistoryBatch{
+			Query:           resolved[i],
+			AnomalyAssetIds: []string{},
+			SitewiseClient:  sw,
+		}
+		for _, r := range responses {
+			historyBatch.Responses = append(historyBatch.Responses, &iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+				NextToken:      r.nextToken,
+				SuccessEntries: r.success,
+				ErrorEntries:   r.errors,
+				SkippedEntries: r.skipped,
+			})
+		}
+		planned[i] = &PlannedQuery[*framer.AssetPropertyValueHistoryBatch]{Query: resolved[i], Framer: historyBatch}
+	}
+
+	return planned, nil
+}
+
+// PlanBatchGetAssetPropertyAggregates fetches the aggregates of the queries with shared batches
+func PlanBatchGetAssetPropertyAggregates(ctx context.Context, sw client.SitewiseAPIClient, queries []models.AssetPropertyValueQuery) ([]*PlannedQuery[*framer.AssetPropertyAggregatesBatch], error) {
+	resolved, groups, err := groupPlannedQueries(ctx, sw, queries, func(q models.AssetPropertyValueQuery) string {
+		return planKey(q, q.Resolution, q.AggregateTypes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type aggregatesResponse = plannedResponse[iotsitewisetypes.BatchGetAssetPropertyAggregatesSuccessEntry, iotsitewisetypes.BatchGetAssetPropertyAggregatesErrorEntry, iotsitewisetypes.BatchGetAssetPropertyAggregatesSkippedEntry]
+	split := make([][]*aggregatesResponse, len(queries))
+	requests := make([]iotsitewise.BatchGetAssetPropertyAggregatesInput, len(queries))
+	for _, group := range groups {
+		groupQuery := group.query(resolved)
+		batchedQueries := batchQueriesInitial(groupQuery, BatchGetAssetPropertyAggregatesMaxEntries)
+
+		groupRequests := make([]*iotsitewise.BatchGetAssetPropertyAggregatesInput, len(batchedQueries))
+		responses := make([]*iotsitewise.BatchGetAssetPropertyAggregatesOutput, len(batchedQueries))
+		eg, ectx := errgroup.WithContext(ctx)
+		eg.SetLimit(MaxConcurrentRequests)
+		for i, q := range batchedQueries {
+			awsReq := aggregateBatchQueryToInput(q)
+			groupRequests[i] = awsReq
+			eg.Go(func() error {
+				resp, err := sw.BatchGetAssetPropertyAggregatesPageAggregation(ectx, awsReq, groupQuery.MaxPageAggregations, int(groupQuery.MaxDataPoints))
+				if err != nil {
+					return err
+				}
+				responses[i] = resp
+				return nil
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			return nil, err
+		}
+
+		requestEntries := map[string]iotsitewisetypes.BatchGetAssetPropertyAggregatesEntry{}
+		entries := newPlannedEntries[iotsitewisetypes.BatchGetAssetPropertyAggregatesSuccessEntry, iotsitewisetypes.BatchGetAssetPropertyAggregatesErrorEntry, iotsitewisetypes.BatchGetAssetPropertyAggregatesSkippedEntry]()
+		for i, resp := range responses {
+			for _, e := range groupRequests[i].Entries {
+				requestEntries[*e.EntryId] = e
+				entries.nextTokens[*e.EntryId] = resp.NextToken
+			}
+			for _, e := range resp.SuccessEntries {
+				entries.success[*e.EntryId] = e
+			}
+			for _, e := range resp.ErrorEntries {
+				entries.errors[*e.EntryId] = e
+			}
+			for _, e := range resp.SkippedEntries {
+				entries.skipped[*e.EntryId] = e
+			}
+		}
+
+		for _, i := range group.queries {
+			split[i] = append(split[i], entries.split(resolved[i], group)...)
+			for _, entry := range resolved[i].AssetPropertyEntries {
+				if entryId := *util.GetEntryIdFromAssetPropertyEntry(entry); group.entryIds[entryId] {
+					requests[i].Entries = append(requests[i].Entries, requestEntries[entryId])
+				}
+			}
+		}
+	}
+
+	planned := make([]*PlannedQuery[*framer.AssetPropertyAggregatesBatch], len(queries))
+	for i, responses := range split {
+		if len(responses) == 0 {
+			continue
+		}
+		aggregatesBatch := &framer.AssetPropertyAggregatesBatch{
+			Requests: []iotsitewise.BatchGetAssetPropertyAggregatesInput{requests[i]},
+		}
+		for _, r := range responses {
+			aggregatesBatch.Responses = append(aggregatesBatch.Responses, iotsitewise.BatchGetAssetPropertyAggregatesOutput{
+				NextToken:      r.nextToken,
+				SuccessEntries: r.success,
+				ErrorEntries:   r.errors,
+				SkippedEntries: r.skipped,
+			})
+		}
+		planned[i] = &PlannedQuery[*framer.AssetPropertyAggregatesBatch]{Query: resolved[i], Framer: aggregatesBatch}
+	}
+
+	return planned, nil
+}
+
+// PlanBatchGetAssetPropertyValuesForTimeRange fetches the queries with AUTO resolution that resolve
+// to raw data with the history plan, and the others with the aggregates plan
+func PlanBatchGetAssetPropertyValuesForTimeRange(ctx context.Context, sw client.SitewiseAPIClient, queries []models.AssetPropertyValueQuery) ([]*PlannedQuery[*framer.AssetPropertyValuesForTimeRangeBatch], error) {
+	var historyIdx, aggregateIdx []int
+	var historyQueries, aggregateQueries []models.AssetPropertyValueQuery
+	for i, query := range queries {
+		if query.Resolution == "AUTO" {
+			resolution := propvals.Resolution(query.BaseQuery)
+			// todo: remove propvals.ResolutionSecond condition once 1s aggregation is supported
+			if propvals.ResolutionRaw == resolution || propvals.ResolutionSecond == resolution {
+				historyIdx = append(historyIdx, i)
+				historyQueries = append(historyQueries, query)
+				continue
+			}
+		}
+		aggregateIdx = append(aggregateIdx, i)
+		aggregateQueries = append(aggregateQueries, query)
+	}
+
+	planned := make([]*PlannedQuery[*framer.AssetPropertyValuesForTimeRangeBatch], len(queries))
+	if len(historyQueries) > 0 {
+		history, err := PlanBatchGetAssetPropertyValues(ctx, sw, historyQueries)
+		if err != nil {
+			return nil, err
+		}
+		for j, p := range history {
+			if p != nil {
+				planned[historyIdx[j]] = &PlannedQuery[*framer.AssetPropertyValuesForTimeRangeBatch]{
+					Query:  p.Query,
+					Framer: &framer.AssetPropertyValuesForTimeRangeBatch{History: p.Framer},
+				}
+			}
+		}
+	}
+	if len(aggregateQueries) > 0 {
+		aggregates, err := PlanBatchGetAssetPropertyAggregates(ctx, sw, aggregateQueries)
+		if err != nil {
+			return nil, err
+		}
+		for j, p := range aggregates {
+			if p != nil {
+				planned[aggregateIdx[j]] = &PlannedQuery[*framer.AssetPropertyValuesForTimeRangeBatch]{
+					Query:  p.Query,
+					Framer: &framer.AssetPropertyValuesForTimeRangeBatch{Aggregates: p.Framer},
+				}
+			}
+		}
+	}
+
+	return planned, nil
+}
+
+// PlanBatchGetAssetPropertyValue fetches the latest values of the queries with shared batches
+func PlanBatchGetAssetPropertyValue(ctx context.Context, sw client.SitewiseAPIClient, queries []models.AssetPropertyValueQuery) ([]*PlannedQuery[*framer.AssetPropertyValueBatch], error) {
+	// the latest value has no time range or quality, so all queries share their batches
+	resolved, groups, err := groupPlannedQueries(ctx, sw, queries, func(models.AssetPropertyValueQuery) string { return "" })
+	if err != nil {
+		return nil, err
+	}
+
+	type valueResponse = plannedResponse[iotsitewisetypes.BatchGetAssetPropertyValueSuccessEntry, iotsitewisetypes.BatchGetAssetPropertyValueErrorEntry, iotsitewisetypes.BatchGetAssetPropertyValueSkippedEntry]
+	split := make([][]*valueResponse, len(queries))
+	for _, group := range groups {
+		groupQuery := group.query(resolved)
+		batchedQueries := batchQueriesInitial(groupQuery, BatchGetAssetPropertyValueMaxEntries)
+
+		responses := make([]*iotsitewise.BatchGetAssetPropertyValueOutput, len(batchedQueries))
+		eg, ectx := errgroup.WithContext(ctx)
+		eg.SetLimit(MaxConcurrentRequests)
+		for i, q := range batchedQueries {
+			awsReq := valueBatchQueryToInput(q)
+			eg.Go(func() error {
+				resp, err := sw.BatchGetAssetPropertyValue(ectx, awsReq)
+				if err != nil {
+					return err
+				}
+				responses[i] = resp
+				return nil
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			return nil, err
+		}
+
+		entries := newPlannedEntries[iotsitewisetypes.BatchGetAssetPropertyValueSuccessEntry, iotsitewisetypes.BatchGetAssetPropertyValueErrorEntry, iotsitewisetypes.BatchGetAssetPropertyValueSkippedEntry]()
+		for i, resp := range responses {
+			for _, entry := range batchedQueries[i].AssetPropertyEntries {
+				entries.nextTokens[*util.GetEntryIdFromAssetPropertyEntry(entry)] = resp.NextToken
+			}
+			for _, e := range resp.SuccessEntries {
+				entries.success[*e.EntryId] = e
+			}
+			for _, e := range resp.ErrorEntries {
+				entries.errors[*e.EntryId] = e
+			}
+			for _, e := range resp.SkippedEntries {
+				entries.skipped[*e.EntryId] = e
+			}
+		}
+
+		for _, i := range group.queries {
+			split[i] = append(split[i], entries.split(resolved[i], group)...)
+		}
+	}
+
+	planned := make([]*PlannedQuery[*framer.AssetPropertyValueBatch], len(queries))
+	for i, responses := range split {
+		if len(responses) == 0 {
+			continue
+		}
+		valueBatch := &framer.AssetPropertyValueBatch{
+			AnomalyAssetIds: []string{},
+			SitewiseClient:  sw,
+		}
+		for _, r := range responses {
+			valueBatch.Responses = append(valueBatch.Responses, &iotsitewise.BatchGetAssetPropertyValueOutput{
+				NextToken:      r.nextToken,
+				SuccessEntries: r.success,
+				ErrorEntries:   r.errors,
+				SkippedEntries: r.skipped,
+			})
+		}
+		planned[i] = &PlannedQuery[*framer.AssetPropertyValueBatch]{Query: resolved[i], Framer: valueBatch}
+	}
+
+	return planned, nil
+}
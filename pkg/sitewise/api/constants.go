@@ -13,4 +13,10 @@ const (
 
 	// Max number of entries from: https://docs.aws.amazon.com/iot-sitewise/latest/APIReference/API_BatchGetAssetPropertyValue.html#iotsitewise-BatchGetAssetPropertyValue-request-entries
 	BatchGetAssetPropertyValueMaxEntries = 128
+
+	// Max results number from: https://docs.aws.amazon.com/iot-sitewise/latest/APIReference/API_GetInterpolatedAssetPropertyValues.html#iotsitewise-GetInterpolatedAssetPropertyValues-request-maxResults
+	GetInterpolatedAssetPropertyValuesMaxResults = 250
+
+	// Number of batched or per entry requests of a single query that run at the same time
+	MaxConcurrentRequests = 4
 )
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
 
 	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
@@ -24,9 +25,39 @@ func GetAssetPropertyDescription(ctx context.Context, sw client.SitewiseAPIClien
 	}
 
 	return &framer.AssetProperty{
-		AssetId:       resp.AssetId,
-		AssetModelId:  resp.AssetModelId,
-		AssetName:     resp.AssetName,
-		AssetProperty: resp.AssetProperty,
+		AssetId:        resp.AssetId,
+		AssetModelId:   resp.AssetModelId,
+		AssetName:      resp.AssetName,
+		AssetProperty:  resp.AssetProperty,
+		CompositeModel: resp.CompositeModel,
 	}, nil
 }
+
+// DescribeAssetProperties describes every property selected by the query, either by
+// asset and property ids or by property alias.
+func DescribeAssetProperties(ctx context.Context, sw client.SitewiseAPIClient, query models.DescribeAssetPropertyQuery) (framer.AssetPropertyDescriptions, error) {
+	modifiedQuery, err := getAssetIdAndPropertyId(models.AssetPropertyValueQuery{BaseQuery: query.BaseQuery}, sw, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptions := framer.AssetPropertyDescriptions{}
+	for _, entry := range modifiedQuery.AssetPropertyEntries {
+		// disassociated streams have no asset property to describe
+		if entry.AssetId == "" || entry.PropertyId == "" {
+			continue
+		}
+
+		resp, err := sw.DescribeAssetProperty(ctx, &iotsitewise.DescribeAssetPropertyInput{
+			AssetId:    aws.String(entry.AssetId),
+			PropertyId: aws.String(entry.PropertyId),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		descriptions = append(descriptions, framer.AssetProperty(*resp))
+	}
+
+	return descriptions, nil
+}
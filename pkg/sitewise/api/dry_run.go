@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"math"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api/propvals"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+const (
+	rawValuesNotice     = "The pages and points of raw values depend on how often the properties are written, and cannot be estimated"
+	lastObservationNote = "lastObservation adds two queries of one point per entry, batched with the other queries of the request"
+	downsampleNote      = "downsample still fetches every raw value, and reduces them to about maxDataPoints per series before they are returned"
+	statementNotice     = "The cost of a SQL query depends on the data it scans, and cannot be estimated before it runs"
+)
+
+// DryRunAssetPropertyValueQuery plans the calls of a property query the way the query would be
+// fetched, without fetching any data. Property aliases are resolved, because they decide the
+// entries of each batch. edge is set for the queries of a SiteWise Edge gateway, which has no
+// batch APIs.
+func DryRunAssetPropertyValueQuery(ctx context.Context, sw client.SitewiseAPIClient, query models.AssetPropertyValueQuery, edge bool) (*framer.QueryPlan, error) {
+	ctx, span := util.StartSpan(ctx, "api.DryRunAssetPropertyValueQuery", util.PropertyValueQueryAttributes(query)...)
+	defer span.End()
+
+	modifiedQuery, err := getAssetIdAndPropertyId(query, sw, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &framer.QueryPlan{}
+	if len(query.PropertyAliases) > 0 {
+		plan.Calls = append(plan.Calls, framer.PlannedCalls{
+			Operation:      "DescribeTimeSeries",
+			Calls:          len(query.PropertyAliases),
+			EntriesPerCall: 1,
+			EstimatedPages: aws.Int64(1),
+			ExpectedPoints: aws.Int64(0),
+		})
+	}
+
+	switch query.QueryType {
+	case models.QueryTypePropertyValue:
+		plan.Calls = append(plan.Calls, planLatestValues(modifiedQuery, edge)...)
+	case models.QueryTypePropertyValueHistory:
+		plan.Calls = append(plan.Calls, planHistory(modifiedQuery, edge)...)
+	case models.QueryTypePropertyAggregate:
+		if resolution := aggregateResolution(modifiedQuery); resolution == propvals.ResolutionRaw {
+			plan.Calls = append(plan.Calls, planHistory(modifiedQuery, edge)...)
+		} else {
+			plan.Calls = append(plan.Calls, perQuality(modifiedQuery, planAggregates(modifiedQuery, resolution, edge))...)
+		}
+	case models.QueryTypePropertyInterpolated:
+		if _, err := intervalWindowSeconds(modifiedQuery); err != nil {
+			return nil, err
+		}
+		plan.Calls = append(plan.Calls, perQuality(modifiedQuery, []framer.PlannedCalls{planInterpolated(modifiedQuery)})...)
+	}
+
+	for _, c := range plan.Calls {
+		if c.ExpectedPoints == nil {
+			plan.Notices = append(plan.Notices, rawValuesNotice)
+			break
+		}
+	}
+	if query.LastObservation {
+		plan.Notices = append(plan.Notices, lastObservationNote)
+	}
+	if query.Downsample != "" && query.QueryType == models.QueryTypePropertyValueHistory {
+		plan.Notices = append(plan.Notices, downsampleNote)
+	}
+	return plan, nil
+}
+
+// DryRunExecuteQuery returns the statement an ExecuteQuery query would run
+func DryRunExecuteQuery(query models.ExecuteQuery) *framer.QueryPlan {
+	return &framer.QueryPlan{
+		Calls:     []framer.PlannedCalls{{Operation: "ExecuteQuery", Calls: 1}},
+		Statement: query.RawSQL,
+		Notices:   []string{statementNotice},
+	}
+}
+
+// aggregateResolution is the resolution an aggregate query is fetched at, the same way
+// BatchGetAssetPropertyValuesForTimeRange picks it. Raw values are fetched below a minute.
+func aggregateResolution(query models.AssetPropertyValueQuery) string {
+	if query.Resolution != "AUTO" {
+		return query.Resolution
+	}
+	resolution := propvals.Resolution(query.BaseQuery)
+	// todo: remove propvals.ResolutionSecond condition once 1s aggregation is supported
+	if resolution == propvals.ResolutionSecond {
+		return propvals.ResolutionRaw
+	}
+	return resolution
+}
+
+// perQuality repeats the calls of a query that is fetched once per quality for each quality
+func perQuality(query models.AssetPropertyValueQuery, calls []framer.PlannedCalls) []framer.PlannedCalls {
+	if !FetchesPerQuality(query) {
+		return calls
+	}
+	n := len(query.GetQualities())
+	for i, c := range calls {
+		c.Calls *= n
+		if c.EstimatedPages != nil {
+			c.EstimatedPages = aws.Int64(*c.EstimatedPages * int64(n))
+		}
+		if c.ExpectedPoints != nil {
+			c.ExpectedPoints = aws.Int64(*c.ExpectedPoints * int64(n))
+		}
+		calls[i] = c
+	}
+	return calls
+}
+
+// entryBatches returns the number of entries of each batch of a query
+func entryBatches(query models.AssetPropertyValueQuery, maxEntries int) []int {
+	batches := []int{}
+	for _, batch := range batchQueries(query, maxEntries) {
+		batches = append(batches, len(batch.AssetPropertyEntries))
+	}
+	return batches
+}
+
+func planLatestValues(query models.AssetPropertyValueQuery, edge bool) []framer.PlannedCalls {
+	entries := len(query.AssetPropertyEntries)
+	if edge {
+		return []framer.PlannedCalls{{
+			Operation:      "GetAssetPropertyValue",
+			Calls:          entries,
+			EntriesPerCall: 1,
+			EstimatedPages: aws.Int64(int64(entries)),
+			ExpectedPoints: aws.Int64(int64(entries)),
+		}}
+	}
+
+	calls := []framer.PlannedCalls{}
+	for _, size := range entryBatches(query, BatchGetAssetPropertyValueMaxEntries) {
+		calls = append(calls, framer.PlannedCalls{
+			Operation:      "BatchGetAssetPropertyValue",
+			Calls:          1,
+			EntriesPerCall: size,
+			EstimatedPages: aws.Int64(1),
+			ExpectedPoints: aws.Int64(int64(size)),
+		})
+	}
+	return calls
+}
+
+func planHistory(query models.AssetPropertyValueQuery, edge bool) []framer.PlannedCalls {
+	if edge {
+		return []framer.PlannedCalls{{
+			Operation:      "GetAssetPropertyValueHistory",
+			Calls:          len(query.AssetPropertyEntries),
+			EntriesPerCall: 1,
+			Resolution:     propvals.ResolutionRaw,
+		}}
+	}
+
+	calls := []framer.PlannedCalls{}
+	for _, size := range entryBatches(query, BatchGetAssetPropertyValueHistoryMaxEntries) {
+		calls = append(calls, framer.PlannedCalls{
+			Operation:      "BatchGetAssetPropertyValueHistory",
+			Calls:          1,
+			EntriesPerCall: size,
+			Resolution:     propvals.ResolutionRaw,
+		})
+	}
+	return calls
+}
+
+func planAggregates(query models.AssetPropertyValueQuery, resolution string, edge bool) []framer.PlannedCalls {
+	pointsPerEntry := int64(propvals.DataPointsForResolution(resolution, query.TimeRange))
+
+	if edge {
+		entries := len(query.AssetPropertyEntries)
+		pageSize := math.Max(float64(query.MaxDataPoints), 1)
+		return []framer.PlannedCalls{{
+			Operation:      "GetAssetPropertyAggregates",
+			Calls:          entries,
+			EntriesPerCall: 1,
+			Resolution:     resolution,
+			EstimatedPages: aws.Int64(int64(entries) * max(propvals.PagesForResolution(resolution, query.TimeRange, pageSize), 1)),
+			ExpectedPoints: aws.Int64(int64(entries) * pointsPerEntry),
+		}}
+	}
+
+	// the data points of a page are shared by the entries of a batch
+	calls := []framer.PlannedCalls{}
+	for _, size := range entryBatches(query, BatchGetAssetPropertyAggregatesMaxEntries) {
+		pageSize := float64(BatchGetAssetPropertyAggregatesMaxResults) / float64(size)
+		calls = append(calls, framer.PlannedCalls{
+			Operation:      "BatchGetAssetPropertyAggregates",
+			Calls:          1,
+			EntriesPerCall: size,
+			Resolution:     resolution,
+			EstimatedPages: aws.Int64(max(propvals.PagesForResolution(resolution, query.TimeRange, pageSize), 1)),
+			ExpectedPoints: aws.Int64(int64(size) * pointsPerEntry),
+		})
+	}
+	return calls
+}
+
+func planInterpolated(query models.AssetPropertyValueQuery) framer.PlannedCalls {
+	resolution := propvals.InterpolatedResolution(query)
+	if query.Resolution != "AUTO" && query.Resolution != "" {
+		resolution = query.Resolution
+	}
+	entries := int64(len(query.AssetPropertyEntries))
+	interval := max(int64(propvals.ResolutionToDuration(resolution).Seconds()), 1)
+	from, to := util.TimeRangeToUnix(query.TimeRange)
+	windows := len(interpolatedWindows(from.Unix(), to.Unix(), interval, query.MaxPageAggregations))
+	pages := max(propvals.PagesForResolution(resolution, query.TimeRange, GetInterpolatedAssetPropertyValuesMaxResults), int64(windows))
+	return framer.PlannedCalls{
+		Operation:      "GetInterpolatedAssetPropertyValues",
+		Calls:          len(query.AssetPropertyEntries) * windows,
+		EntriesPerCall: 1,
+		Resolution:     resolution,
+		EstimatedPages: aws.Int64(entries * pages),
+		ExpectedPoints: aws.Int64(entries * int64(propvals.DataPointsForResolution(resolution, query.TimeRange))),
+	}
+}
@@ -9,9 +9,13 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 )
 
 func ExecuteQuery(ctx context.Context, client iotsitewise.ExecuteQueryAPIClient, query models.ExecuteQuery) (*framer.QueryResults, error) {
+	ctx, span := util.StartSpan(ctx, "api.ExecuteQuery", util.QueryAttributes(query.BaseQuery)...)
+	defer span.End()
+
 	backend.Logger.FromContext(ctx).Debug("Running ExecuteQuery", "query", query.RawSQL)
 	input := &iotsitewise.ExecuteQueryInput{
 		QueryStatement: aws.String(query.RawSQL),
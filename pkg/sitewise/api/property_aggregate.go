@@ -14,7 +14,7 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 )
 
-func aggregateQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.GetAssetPropertyAggregatesInput {
+func aggregateQueryToInput(query models.AssetPropertyValueQuery, entry models.AssetPropertyEntry) *iotsitewise.GetAssetPropertyAggregatesInput {
 
 	resolution := query.Resolution
 	if resolution == "AUTO" {
@@ -25,14 +25,6 @@ func aggregateQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.Ge
 		}
 	}
 
-	qualities := make([]iotsitewisetypes.Quality, 1)
-
-	if query.Quality == "" || query.Quality == "ANY" {
-		qualities[0] = iotsitewisetypes.QualityGood
-	} else {
-		qualities[0] = query.Quality
-	}
-
 	from, to := util.TimeRangeToUnix(query.TimeRange)
 
 	timeOrdering := iotsitewisetypes.TimeOrderingAscending
@@ -44,43 +36,47 @@ func aggregateQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.Ge
 		query.MaxDataPoints = 250
 	}
 
+	assetId, propertyId, propertyAlias := getEntryIdentifiers(entry)
 	return &iotsitewise.GetAssetPropertyAggregatesInput{
 		AggregateTypes: query.AggregateTypes,
 		EndDate:        to,
 		MaxResults:     aws.Int32(query.MaxDataPoints),
-		NextToken:      getNextToken(query.BaseQuery),
-		AssetId:        getFirstAssetId(query.BaseQuery),
-		PropertyId:     getFirstPropertyId(query.BaseQuery),
-		PropertyAlias:  getFirstPropertyAlias(query.BaseQuery),
-		Qualities:      qualities,
+		NextToken:      getEntryNextToken(query.BaseQuery, entry),
+		AssetId:        assetId,
+		PropertyId:     propertyId,
+		PropertyAlias:  propertyAlias,
+		Qualities:      []iotsitewisetypes.Quality{requestQuality(query)},
 		Resolution:     aws.String(resolution),
 		StartDate:      from,
 		TimeOrdering:   timeOrdering,
 	}
 }
 
+// GetAssetPropertyAggregates fetches the aggregates of every AssetPropertyEntry of the query with its own request
 func GetAssetPropertyAggregates(ctx context.Context, sw client.SitewiseAPIClient,
 	query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.AssetPropertyAggregates, error) {
+	ctx, span := util.StartSpan(ctx, "api.GetAssetPropertyAggregates", util.PropertyValueQueryAttributes(query)...)
+	defer span.End()
 
 	modifiedQuery, err := getAssetIdAndPropertyId(query, sw, ctx)
 	if err != nil {
 		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
-	awsReq := aggregateQueryToInput(modifiedQuery)
-
-	resp, err := sw.GetAssetPropertyAggregatesPageAggregation(ctx, awsReq, modifiedQuery.MaxPageAggregations, int(query.MaxDataPoints))
-
+	responses, errorEntries, err := fanOutEntries(ctx, modifiedQuery, func(ctx context.Context, entry models.AssetPropertyEntry) (*iotsitewise.GetAssetPropertyAggregatesOutput, error) {
+		awsReq := aggregateQueryToInput(modifiedQuery, entry)
+		return sw.GetAssetPropertyAggregatesPageAggregation(ctx, awsReq, modifiedQuery.MaxPageAggregations, int(query.MaxDataPoints))
+	})
 	if err != nil {
 		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
+	// the resolution and aggregate types are the same for every entry
 	return modifiedQuery,
 		&framer.AssetPropertyAggregates{
-			Request: *awsReq,
-			Response: iotsitewise.GetAssetPropertyAggregatesOutput{
-				AggregatedValues: resp.AggregatedValues,
-				NextToken:        resp.NextToken,
-			},
+			Request:      *aggregateQueryToInput(modifiedQuery, models.AssetPropertyEntry{}),
+			Responses:    responses,
+			ErrorEntries: errorEntries,
+			Query:        modifiedQuery,
 		}, nil
 }
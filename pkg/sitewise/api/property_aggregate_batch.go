@@ -12,6 +12,7 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api/propvals"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+	"golang.org/x/sync/errgroup"
 )
 
 // `query.MaxDataPoints` is ignored and it always requests with the maximum number of data points the SiteWise API can support
@@ -26,13 +27,6 @@ func aggregateBatchQueryToInput(query models.AssetPropertyValueQuery) *iotsitewi
 		}
 	}
 
-	qualities := make([]iotsitewisetypes.Quality, 1)
-	if query.Quality == "" || query.Quality == "ANY" {
-		qualities[0] = iotsitewisetypes.QualityGood
-	} else {
-		qualities[0] = query.Quality
-	}
-
 	from, to := util.TimeRangeToUnix(query.TimeRange)
 
 	timeOrdering := iotsitewisetypes.TimeOrderingDescending
@@ -48,7 +42,7 @@ func aggregateBatchQueryToInput(query models.AssetPropertyValueQuery) *iotsitewi
 		aggregatesEntry := iotsitewisetypes.BatchGetAssetPropertyAggregatesEntry{
 			AggregateTypes: query.AggregateTypes,
 			EndDate:        to,
-			Qualities:      qualities,
+			Qualities:      []iotsitewisetypes.Quality{requestQuality(query)},
 			Resolution:     aws.String(resolution),
 			StartDate:      from,
 			TimeOrdering:   timeOrdering,
@@ -75,6 +69,9 @@ func aggregateBatchQueryToInput(query models.AssetPropertyValueQuery) *iotsitewi
 
 func BatchGetAssetPropertyAggregates(ctx context.Context, client client.SitewiseAPIClient,
 	query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.AssetPropertyAggregatesBatch, error) {
+	ctx, span := util.StartSpan(ctx, "api.BatchGetAssetPropertyAggregates", util.PropertyValueQueryAttributes(query)...)
+	defer span.End()
+
 	maxDps := int(query.MaxDataPoints)
 
 	modifiedQuery, err := getAssetIdAndPropertyId(query, client, ctx)
@@ -83,16 +80,24 @@ func BatchGetAssetPropertyAggregates(ctx context.Context, client client.Sitewise
 	}
 
 	batchedQueries := batchQueries(modifiedQuery, BatchGetAssetPropertyAggregatesMaxEntries)
-	requests := []iotsitewise.BatchGetAssetPropertyAggregatesInput{}
-	responses := []iotsitewise.BatchGetAssetPropertyAggregatesOutput{}
-	for _, q := range batchedQueries {
+	requests := make([]iotsitewise.BatchGetAssetPropertyAggregatesInput, len(batchedQueries))
+	responses := make([]iotsitewise.BatchGetAssetPropertyAggregatesOutput, len(batchedQueries))
+	eg, ectx := errgroup.WithContext(ctx)
+	eg.SetLimit(MaxConcurrentRequests)
+	for i, q := range batchedQueries {
 		awsReq := aggregateBatchQueryToInput(q)
-		requests = append(requests, *awsReq)
-		resp, err := client.BatchGetAssetPropertyAggregatesPageAggregation(ctx, awsReq, modifiedQuery.MaxPageAggregations, maxDps)
-		if err != nil {
-			return models.AssetPropertyValueQuery{}, nil, err
-		}
-		responses = append(responses, *resp)
+		requests[i] = *awsReq
+		eg.Go(func() error {
+			resp, err := client.BatchGetAssetPropertyAggregatesPageAggregation(ectx, awsReq, modifiedQuery.MaxPageAggregations, maxDps)
+			if err != nil {
+				return err
+			}
+			responses[i] = *resp
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
 	return modifiedQuery,
@@ -5,7 +5,6 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
-	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
 	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
@@ -17,33 +16,33 @@ import (
 // The front end component should ensure that both cannot be sent at the same time by the user.
 // If an invalid combo of assetId/propertyId/propertyAlias are sent to the API, an exception will be returned.
 // The Framer consumer should bubble up that error to the user.
-func historyQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.GetAssetPropertyValueHistoryInput {
-	quality := query.Quality
-	if quality == "" || quality == "ANY" {
-		quality = iotsitewisetypes.QualityGood
-	}
-
+func historyQueryToInput(query models.AssetPropertyValueQuery, entry models.AssetPropertyEntry) *iotsitewise.GetAssetPropertyValueHistoryInput {
 	from, to := util.TimeRangeToUnix(query.TimeRange)
 
 	if query.MaxDataPoints < 1 || query.MaxDataPoints > 20000 {
 		query.MaxDataPoints = 20000
 	}
 
+	assetId, propertyId, propertyAlias := getEntryIdentifiers(entry)
 	return &iotsitewise.GetAssetPropertyValueHistoryInput{
 		StartDate:     from,
 		EndDate:       to,
 		MaxResults:    aws.Int32(int32(query.MaxDataPoints)),
-		NextToken:     getNextToken(query.BaseQuery),
-		AssetId:       getFirstAssetId(query.BaseQuery),
-		PropertyId:    getFirstPropertyId(query.BaseQuery),
-		PropertyAlias: getFirstPropertyAlias(query.BaseQuery),
+		NextToken:     getEntryNextToken(query.BaseQuery, entry),
+		AssetId:       assetId,
+		PropertyId:    propertyId,
+		PropertyAlias: propertyAlias,
 		TimeOrdering:  query.TimeOrdering,
-		Qualities:     []iotsitewisetypes.Quality{quality},
+		Qualities:     historyQualities(query),
 	}
 }
 
+// GetAssetPropertyValues fetches the history of every AssetPropertyEntry of the query with its own request
 func GetAssetPropertyValues(ctx context.Context, sw client.SitewiseAPIClient,
 	query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.AssetPropertyValueHistory, error) {
+	ctx, span := util.StartSpan(ctx, "api.GetAssetPropertyValues", util.PropertyValueQueryAttributes(query)...)
+	defer span.End()
+
 	maxDps := int(query.MaxDataPoints)
 
 	modifiedQuery, err := getAssetIdAndPropertyId(query, sw, ctx)
@@ -51,17 +50,23 @@ func GetAssetPropertyValues(ctx context.Context, sw client.SitewiseAPIClient,
 		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
-	awsReq := historyQueryToInput(modifiedQuery)
-	resp, err := sw.GetAssetPropertyValueHistoryPageAggregation(ctx, awsReq, query.MaxPageAggregations, maxDps)
-
+	responses, errorEntries, err := fanOutEntries(ctx, modifiedQuery, func(ctx context.Context, entry models.AssetPropertyEntry) (*iotsitewise.GetAssetPropertyValueHistoryOutput, error) {
+		awsReq := historyQueryToInput(modifiedQuery, entry)
+		resp, err := sw.GetAssetPropertyValueHistoryPageAggregation(ctx, awsReq, query.MaxPageAggregations, maxDps)
+		if err != nil {
+			return nil, err
+		}
+		return filterHistoryQualities(modifiedQuery, resp), nil
+	})
 	if err != nil {
 		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
 	return modifiedQuery,
 		&framer.AssetPropertyValueHistory{
-			GetAssetPropertyValueHistoryOutput: resp,
-			Query:                              modifiedQuery,
+			Responses:    responses,
+			ErrorEntries: errorEntries,
+			Query:        modifiedQuery,
 		},
 		nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+	"golang.org/x/sync/errgroup"
 )
 
 // GetAssetPropertyValueHistory requires either PropertyAlias OR (AssetID and PropertyID) to be set.
@@ -19,11 +20,6 @@ import (
 // The Framer consumer should bubble up that error to the user.
 // `query.MaxDataPoints` is ignored and it always requests with the maximum number of data points the SiteWise API can support
 func historyBatchQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.BatchGetAssetPropertyValueHistoryInput {
-	qualities := []iotsitewisetypes.Quality{iotsitewisetypes.QualityGood}
-	if query.Quality != "" && query.Quality != "ANY" {
-		qualities[0] = query.Quality
-	}
-
 	from, to := util.TimeRangeToUnix(query.TimeRange)
 
 	entries := make([]iotsitewisetypes.BatchGetAssetPropertyValueHistoryEntry, 0)
@@ -35,7 +31,7 @@ func historyBatchQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise
 			StartDate:    from,
 			EndDate:      to,
 			TimeOrdering: query.TimeOrdering,
-			Qualities:    qualities,
+			Qualities:    historyQualities(query),
 		}
 		if entry.AssetId != "" && entry.PropertyId != "" {
 			historyEntry.AssetId = aws.String(entry.AssetId)
@@ -59,6 +55,9 @@ func historyBatchQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise
 
 func BatchGetAssetPropertyValues(ctx context.Context, client client.SitewiseAPIClient,
 	query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.AssetPropertyValueHistoryBatch, error) {
+	ctx, span := util.StartSpan(ctx, "api.BatchGetAssetPropertyValues", util.PropertyValueQueryAttributes(query)...)
+	defer span.End()
+
 	maxDps := int(query.MaxDataPoints)
 
 	modifiedQuery, err := getAssetIdAndPropertyId(query, client, ctx)
@@ -67,14 +66,22 @@ func BatchGetAssetPropertyValues(ctx context.Context, client client.SitewiseAPIC
 	}
 
 	batchedQueries := batchQueries(modifiedQuery, BatchGetAssetPropertyValueHistoryMaxEntries)
-	responses := []*iotsitewise.BatchGetAssetPropertyValueHistoryOutput{}
-	for _, q := range batchedQueries {
+	responses := make([]*iotsitewise.BatchGetAssetPropertyValueHistoryOutput, len(batchedQueries))
+	eg, ectx := errgroup.WithContext(ctx)
+	eg.SetLimit(MaxConcurrentRequests)
+	for i, q := range batchedQueries {
 		awsReq := historyBatchQueryToInput(q)
-		resp, err := client.BatchGetAssetPropertyValueHistoryPageAggregation(ctx, awsReq, query.MaxPageAggregations, maxDps)
-		if err != nil {
-			return models.AssetPropertyValueQuery{}, nil, err
-		}
-		responses = append(responses, resp)
+		eg.Go(func() error {
+			resp, err := client.BatchGetAssetPropertyValueHistoryPageAggregation(ectx, awsReq, query.MaxPageAggregations, maxDps)
+			if err != nil {
+				return err
+			}
+			responses[i] = filterBatchHistoryQualities(q, resp)
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
 	anomalyAssetIds := []string{}
@@ -2,11 +2,20 @@ package api
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
 
+	dserrors "github.com/grafana/iot-sitewise-datasource/pkg/errors"
 	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api/propvals"
@@ -16,28 +25,142 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+// maxInterpolatedWindows is the number of windows the time range of an entry is split into at most
+const maxInterpolatedWindows = 8
+
+// maxIntervalWindowSeconds is the largest interval window GetInterpolatedAssetPropertyValues accepts
+const maxIntervalWindowSeconds = 320000000
+
 var (
 	LOCF_INTERPOLATION   string = "LOCF_INTERPOLATION"
 	LINEAR_INTERPOLATION string = "LINEAR_INTERPOLATION"
 )
 
-type responseWrapper struct {
-	DataResponse *iotsitewise.GetInterpolatedAssetPropertyValuesOutput
-	EntryId      string
+// PropertyDataTypes looks up the data types of the entries of a query by their entry id. It is
+// used to pick the interpolation type of queries that do not set one.
+type PropertyDataTypes func(ctx context.Context, query models.AssetPropertyValueQuery) (map[string]types.PropertyDataType, error)
+
+// interpolation is how the values of an entry are interpolated
+type interpolation struct {
+	apiType       string
+	windowSeconds *int64
 }
 
-func interpolatedQueryToInputs(query models.AssetPropertyValueQuery) []*iotsitewise.GetInterpolatedAssetPropertyValuesInput {
+// String is the interpolation recorded in the frame meta
+func (i interpolation) String() string {
+	name := models.InterpolationTypeLinear
+	if i.apiType == LOCF_INTERPOLATION {
+		name = models.InterpolationTypeLOCF
+	}
+	if i.windowSeconds != nil {
+		return fmt.Sprintf("%s, window %s", name, time.Duration(*i.windowSeconds)*time.Second)
+	}
+	return name
+}
 
-	from, to := util.TimeRangeToUnix(query.TimeRange)
-	startTimeInSeconds := from.Unix()
-	endTimeInSeconds := to.Unix()
+// intervalWindowSeconds validates the interpolation type and interval window of a query against
+// what GetInterpolatedAssetPropertyValues accepts, and returns the window in seconds
+func intervalWindowSeconds(query models.AssetPropertyValueQuery) (*int64, error) {
+	switch query.InterpolationType {
+	case "", models.InterpolationTypeLinear, models.InterpolationTypeLOCF:
+	default:
+		return nil, dserrors.Validationf("unknown interpolation type %q: use %s or %s", query.InterpolationType, models.InterpolationTypeLinear, models.InterpolationTypeLOCF)
+	}
+	if query.IntervalWindow == "" {
+		return nil, nil
+	}
+	if query.InterpolationType == models.InterpolationTypeLOCF {
+		return nil, dserrors.Validationf("an interval window is only supported by %s interpolation", models.InterpolationTypeLinear)
+	}
 
-	quality := query.Quality
-	if quality == "" || quality == "ANY" {
-		quality = types.QualityGood
+	window, err := gtime.ParseDuration(query.IntervalWindow)
+	if err != nil {
+		return nil, dserrors.Validationf("invalid interval window %q: use a duration such as 1h", query.IntervalWindow)
+	}
+	seconds := int64(window.Seconds())
+	if seconds < 1 || seconds > maxIntervalWindowSeconds {
+		return nil, dserrors.Validationf("the interval window %s is out of range: it must be between 1s and %d seconds", query.IntervalWindow, maxIntervalWindowSeconds)
+	}
+	return aws.Int64(seconds), nil
+}
+
+// entryInterpolations picks the interpolation of each entry. Queries without an interpolation type
+// use LOCF for string and boolean properties, which LINEAR interpolation does not support.
+func entryInterpolations(query models.AssetPropertyValueQuery, dataTypes map[string]types.PropertyDataType) (map[string]interpolation, error) {
+	window, err := intervalWindowSeconds(query)
+	if err != nil {
+		return nil, err
 	}
 
-	interpolationType := LINEAR_INTERPOLATION
+	interpolations := make(map[string]interpolation, len(query.AssetPropertyEntries))
+	for _, entry := range query.AssetPropertyEntries {
+		entryId := *util.GetEntryIdFromAssetPropertyEntry(entry)
+		apiType := LINEAR_INTERPOLATION
+		switch query.InterpolationType {
+		case models.InterpolationTypeLOCF:
+			apiType = LOCF_INTERPOLATION
+		case "":
+			if dataTypes[entryId] == types.PropertyDataTypeString || dataTypes[entryId] == types.PropertyDataTypeBoolean {
+				apiType = LOCF_INTERPOLATION
+			}
+		}
+		i := interpolation{apiType: apiType}
+		if apiType == LINEAR_INTERPOLATION {
+			i.windowSeconds = window
+		}
+		interpolations[entryId] = i
+	}
+	return interpolations, nil
+}
+
+// isEntryError reports whether an error is about the entry of a request, such as a property that
+// does not exist, rather than about the whole query
+func isEntryError(err error) bool {
+	switch dserrors.KindOf(err) {
+	case dserrors.KindNotFound, dserrors.KindInvalidRequest:
+		return true
+	}
+	return false
+}
+
+// interpolatedWindow is a part of the time range of an entry that is fetched with its own requests
+type interpolatedWindow struct {
+	start int64
+	end   int64
+	// last is the window that fetches up to the pages of the query, and that a next token continues
+	last bool
+}
+
+// interpolatedWindows splits a time range into windows of whole intervals that each fit in the
+// pages of the query, up to maxInterpolatedWindows. The last window takes the rest of the range.
+// The windows only depend on the query, so that a next token is sent to the same window again.
+func interpolatedWindows(start int64, end int64, interval int64, maxPages int) []interpolatedWindow {
+	points := (end-start)/interval + 1
+	windowPoints := int64(GetInterpolatedAssetPropertyValuesMaxResults * max(maxPages, 1))
+	count := min((points+windowPoints-1)/windowPoints, maxInterpolatedWindows)
+	span := windowPoints * interval
+
+	windows := make([]interpolatedWindow, 0, count)
+	for i := int64(0); i < count-1; i++ {
+		windowStart := start + i*span
+		windows = append(windows, interpolatedWindow{start: windowStart, end: windowStart + span - 1})
+	}
+	return append(windows, interpolatedWindow{start: start + (count-1)*span, end: end, last: true})
+}
+
+// interpolatedRequest is the request of one window of an entry
+type interpolatedRequest struct {
+	entryId string
+	window  int
+	last    bool
+	input   *iotsitewise.GetInterpolatedAssetPropertyValuesInput
+}
+
+func interpolatedQueryToInputs(query models.AssetPropertyValueQuery, interpolations map[string]interpolation) []interpolatedRequest {
+
+	from, to := util.TimeRangeToUnix(query.TimeRange)
+	startTimeInSeconds := from.Unix()
+	endTimeInSeconds := to.Unix()
 
 	intervalInSeconds := int64(propvals.ResolutionToDuration(propvals.InterpolatedResolution(query)).Seconds())
 	if query.Resolution != "AUTO" && query.Resolution != "" {
@@ -52,43 +175,73 @@ func interpolatedQueryToInputs(query models.AssetPropertyValueQuery) []*iotsitew
 		intervalInSeconds = 1
 	}
 
-	awsReqs := make([]*iotsitewise.GetInterpolatedAssetPropertyValuesInput, 0)
+	windows := interpolatedWindows(startTimeInSeconds, endTimeInSeconds, intervalInSeconds, query.MaxPageAggregations)
+	awsReqs := make([]interpolatedRequest, 0, len(query.AssetPropertyEntries)*len(windows))
 
 	// All unique properties are collected in AssetPropertyEntries and used in
-	// separate GetInterpolatedAssetPropertyValues requests
+	// separate GetInterpolatedAssetPropertyValues requests for each window
 	for _, entry := range query.AssetPropertyEntries {
-		interpolatedInput := iotsitewise.GetInterpolatedAssetPropertyValuesInput{
-			StartTimeInSeconds: &startTimeInSeconds,
-			EndTimeInSeconds:   &endTimeInSeconds,
-			IntervalInSeconds:  aws.Int64(intervalInSeconds),
-			MaxResults:         aws.Int32(10),
-			Quality:            quality,
-			Type:               &interpolationType,
-		}
-		var entryId *string
-		if entry.AssetId != "" && entry.PropertyId != "" {
-			interpolatedInput.AssetId = aws.String(entry.AssetId)
-			interpolatedInput.PropertyId = aws.String(entry.PropertyId)
-			entryId = util.GetEntryIdFromAssetProperty(entry.AssetId, entry.PropertyId)
-		} else {
-			// If there is no assetId or propertyId, then we use the propertyAlias
-			interpolatedInput.PropertyAlias = aws.String(entry.PropertyAlias)
-			entryId = util.GetEntryIdFromPropertyAlias(entry.PropertyAlias)
-		}
-		var nextToken *string
-		token, ok := query.NextTokens[*entryId]
-		if ok {
-			nextToken = aws.String(token)
+		entryId := *util.GetEntryIdFromAssetPropertyEntry(entry)
+		interpolation := interpolations[entryId]
+		token, resumed := query.NextTokens[entryId]
+		for i, window := range windows {
+			// a next token continues the last window, the others were already fetched
+			if resumed && !window.last {
+				continue
+			}
+			interpolatedInput := iotsitewise.GetInterpolatedAssetPropertyValuesInput{
+				StartTimeInSeconds:      aws.Int64(window.start),
+				EndTimeInSeconds:        aws.Int64(window.end),
+				IntervalInSeconds:       aws.Int64(intervalInSeconds),
+				IntervalWindowInSeconds: interpolation.windowSeconds,
+				MaxResults:              aws.Int32(GetInterpolatedAssetPropertyValuesMaxResults),
+				Quality:                 requestQuality(query),
+				Type:                    aws.String(interpolation.apiType),
+			}
+			if entry.AssetId != "" && entry.PropertyId != "" {
+				interpolatedInput.AssetId = aws.String(entry.AssetId)
+				interpolatedInput.PropertyId = aws.String(entry.PropertyId)
+			} else {
+				// If there is no assetId or propertyId, then we use the propertyAlias
+				interpolatedInput.PropertyAlias = aws.String(entry.PropertyAlias)
+			}
+			if resumed {
+				interpolatedInput.NextToken = aws.String(token)
+			}
+			awsReqs = append(awsReqs, interpolatedRequest{entryId: entryId, window: i, last: window.last, input: &interpolatedInput})
 		}
-		interpolatedInput.NextToken = nextToken
-		awsReqs = append(awsReqs, &interpolatedInput)
 	}
 
 	return awsReqs
 }
 
+// stitchWindows joins the windows of an entry in order into one response, with the next token of
+// the last window
+func stitchWindows(windows map[int]*iotsitewise.GetInterpolatedAssetPropertyValuesOutput) *iotsitewise.GetInterpolatedAssetPropertyValuesOutput {
+	stitched := &iotsitewise.GetInterpolatedAssetPropertyValuesOutput{}
+	var lastTime int64
+	for _, i := range slices.Sorted(maps.Keys(windows)) {
+		for _, v := range windows[i].InterpolatedAssetPropertyValues {
+			// windows share no intervals, but a value at a boundary is only kept once
+			if t := aws.ToInt64(v.Timestamp.TimeInSeconds); len(stitched.InterpolatedAssetPropertyValues) == 0 || t > lastTime {
+				stitched.InterpolatedAssetPropertyValues = append(stitched.InterpolatedAssetPropertyValues, v)
+				lastTime = t
+			}
+		}
+		stitched.NextToken = windows[i].NextToken
+	}
+	return stitched
+}
+
+// GetInterpolatedAssetPropertyValues fetches the interpolated values of each entry of a query.
+// Long time ranges are split into windows that are fetched in parallel with the other entries,
+// up to MaxConcurrentRequests at a time, and stitched back into one response per entry.
+// dataTypes is only called for queries without an interpolation type.
 func GetInterpolatedAssetPropertyValues(ctx context.Context, client client.SitewiseAPIClient,
-	query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.InterpolatedAssetPropertyValue, error) {
+	query models.AssetPropertyValueQuery, dataTypes PropertyDataTypes) (models.AssetPropertyValueQuery, *framer.InterpolatedAssetPropertyValue, error) {
+	ctx, span := util.StartSpan(ctx, "api.GetInterpolatedAssetPropertyValues", util.PropertyValueQueryAttributes(query)...)
+	defer span.End()
+
 	maxDps := int(query.MaxDataPoints)
 
 	modifiedQuery, err := getAssetIdAndPropertyId(query, client, ctx)
@@ -96,46 +249,86 @@ func GetInterpolatedAssetPropertyValues(ctx context.Context, client client.Sitew
 		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
-	awsReqs := interpolatedQueryToInputs(modifiedQuery)
+	var entryDataTypes map[string]types.PropertyDataType
+	if modifiedQuery.InterpolationType == "" && modifiedQuery.IntervalWindow == "" {
+		// entries whose data type is unknown are interpolated linearly, and fail on their own
+		entryDataTypes, _ = dataTypes(ctx, modifiedQuery)
+	}
+	interpolations, err := entryInterpolations(modifiedQuery, entryDataTypes)
+	if err != nil {
+		return models.AssetPropertyValueQuery{}, nil, err
+	}
+	awsReqs := interpolatedQueryToInputs(modifiedQuery, interpolations)
 
-	resultChan := make(chan *responseWrapper, len(awsReqs))
+	var (
+		mu      sync.Mutex
+		windows = map[string]map[int]*iotsitewise.GetInterpolatedAssetPropertyValuesOutput{}
+		// entries that failed on their own are reported by the framer, unless every entry failed
+		errorEntries = map[string]error{}
+	)
 	eg, ectx := errgroup.WithContext(ctx)
+	eg.SetLimit(MaxConcurrentRequests)
 	for _, req := range awsReqs {
-		awsReq := req
 		eg.Go(func() error {
-			resp, err := client.GetInterpolatedAssetPropertyValuesPageAggregation(ectx, awsReq, query.MaxPageAggregations, maxDps)
-			if err != nil {
+			// the windows before the last one fit in the pages of the query, and are always fetched whole
+			maxPages := math.MaxInt32
+			if req.last {
+				maxPages = query.MaxPageAggregations
+			}
+			resp, err := client.GetInterpolatedAssetPropertyValuesPageAggregation(ectx, req.input, maxPages, maxDps)
+			if err != nil && !isEntryError(err) {
 				return err
 			}
-			entryId := ""
-			if awsReq.AssetId != nil && awsReq.PropertyId != nil {
-				entryId = *util.GetEntryIdFromAssetProperty(*awsReq.AssetId, *awsReq.PropertyId)
-			} else {
-				entryId = *util.GetEntryIdFromPropertyAlias(*awsReq.PropertyAlias)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errorEntries[req.entryId] = err
+				return nil
 			}
-			resultChan <- &responseWrapper{
-				DataResponse: resp,
-				EntryId:      entryId,
+			// a window before the last one only stops early when SiteWise kept throttling it, which
+			// would leave a gap in the middle of the entry that no next token continues
+			if !req.last && resp.NextToken != nil {
+				errorEntries[req.entryId] = fmt.Errorf("the interpolated values from %s to %s were cut short because SiteWise throttled their requests: try again later",
+					time.Unix(*req.input.StartTimeInSeconds, 0).UTC().Format(time.RFC3339), time.Unix(*req.input.EndTimeInSeconds, 0).UTC().Format(time.RFC3339))
+				return nil
 			}
-
+			if windows[req.entryId] == nil {
+				windows[req.entryId] = map[int]*iotsitewise.GetInterpolatedAssetPropertyValuesOutput{}
+			}
+			windows[req.entryId][req.window] = resp
 			return nil
 		})
 	}
-
-	err = eg.Wait()
-	close(resultChan)
-	if err != nil {
+	if err := eg.Wait(); err != nil {
 		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
-	responses := make(map[string]*iotsitewise.GetInterpolatedAssetPropertyValuesOutput, len(awsReqs))
-	for result := range resultChan {
-		responses[result.EntryId] = result.DataResponse
+	responses := make(map[string]*iotsitewise.GetInterpolatedAssetPropertyValuesOutput, len(windows))
+	for entryId, entryWindows := range windows {
+		if _, failed := errorEntries[entryId]; !failed {
+			responses[entryId] = stitchWindows(entryWindows)
+		}
+	}
+	if len(responses) == 0 {
+		for _, err := range errorEntries {
+			return models.AssetPropertyValueQuery{}, nil, err
+		}
 	}
 
 	return modifiedQuery,
 		&framer.InterpolatedAssetPropertyValue{
-			Responses: responses,
-			Query:     modifiedQuery,
+			Responses:      responses,
+			ErrorEntries:   errorEntries,
+			Query:          modifiedQuery,
+			Interpolations: interpolationNames(interpolations),
 		}, nil
 }
+
+func interpolationNames(interpolations map[string]interpolation) map[string]string {
+	names := make(map[string]string, len(interpolations))
+	for entryId, i := range interpolations {
+		names[entryId] = i.String()
+	}
+	return names
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+func TestInterpolatedWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		end      int64
+		interval int64
+		maxPages int
+		want     []interpolatedWindow
+	}{
+		{
+			name:     "range within one page",
+			end:      3600,
+			interval: 60,
+			maxPages: 1,
+			want:     []interpolatedWindow{{start: 0, end: 3600, last: true}},
+		},
+		{
+			name:     "a day at 1m in pages of 250 values",
+			end:      86400,
+			interval: 60,
+			maxPages: 1,
+			want: []interpolatedWindow{
+				{start: 0, end: 14999},
+				{start: 15000, end: 29999},
+				{start: 30000, end: 44999},
+				{start: 45000, end: 59999},
+				{start: 60000, end: 74999},
+				{start: 75000, end: 86400, last: true},
+			},
+		},
+		{
+			name:     "the last window takes the rest of the range",
+			end:      86400,
+			interval: 1,
+			maxPages: 2,
+			want: []interpolatedWindow{
+				{start: 0, end: 499},
+				{start: 500, end: 999},
+				{start: 1000, end: 1499},
+				{start: 1500, end: 1999},
+				{start: 2000, end: 2499},
+				{start: 2500, end: 2999},
+				{start: 3000, end: 3499},
+				{start: 3500, end: 86400, last: true},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, interpolatedWindows(0, tc.end, tc.interval, tc.maxPages))
+		})
+	}
+}
+
+func interpolatedValues(times ...int64) []iotsitewisetypes.InterpolatedAssetPropertyValue {
+	values := []iotsitewisetypes.InterpolatedAssetPropertyValue{}
+	for _, t := range times {
+		values = append(values, iotsitewisetypes.InterpolatedAssetPropertyValue{
+			Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(t)},
+			Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(float64(t))},
+		})
+	}
+	return values
+}
+
+func TestGetInterpolatedAssetPropertyValuesStitchesWindows(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	query := models.AssetPropertyValueQuery{Resolution: "1m", InterpolationType: models.InterpolationTypeLinear}
+	query.AssetIds = []string{"asset"}
+	query.PropertyIds = []string{"property"}
+	query.MaxPageAggregations = 1
+	query.TimeRange = backend.TimeRange{From: start, To: start.Add(24 * time.Hour)}
+
+	var (
+		mu     sync.Mutex
+		starts []int64
+	)
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("GetInterpolatedAssetPropertyValuesPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		func(_ context.Context, input *iotsitewise.GetInterpolatedAssetPropertyValuesInput, maxPages int, _ int) *iotsitewise.GetInterpolatedAssetPropertyValuesOutput {
+			mu.Lock()
+			starts = append(starts, *input.StartTimeInSeconds)
+			mu.Unlock()
+			out := &iotsitewise.GetInterpolatedAssetPropertyValuesOutput{
+				InterpolatedAssetPropertyValues: interpolatedValues(*input.StartTimeInSeconds, *input.EndTimeInSeconds),
+			}
+			if maxPages == 1 {
+				out.NextToken = aws.String("last-window-token")
+			}
+			return out
+		}, nil)
+
+	_, fr, err := GetInterpolatedAssetPropertyValues(context.Background(), mockSw, query, nil)
+	require.NoError(t, err)
+	require.Len(t, starts, 6)
+
+	entryId := *util.GetEntryIdFromAssetProperty("asset", "property")
+	stitched := fr.Responses[entryId]
+	require.Len(t, stitched.InterpolatedAssetPropertyValues, 12)
+	for i := 1; i < len(stitched.InterpolatedAssetPropertyValues); i++ {
+		require.Less(t, *stitched.InterpolatedAssetPropertyValues[i-1].Timestamp.TimeInSeconds, *stitched.InterpolatedAssetPropertyValues[i].Timestamp.TimeInSeconds)
+	}
+	require.Equal(t, "last-window-token", *stitched.NextToken)
+
+	// the next token continues the last window only
+	query.NextTokens = map[string]string{entryId: "last-window-token"}
+	starts = nil
+	_, _, err = GetInterpolatedAssetPropertyValues(context.Background(), mockSw, query, nil)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1700000000 + 5*15000}, starts)
+	mockSw.AssertCalled(t, "GetInterpolatedAssetPropertyValuesPageAggregation", mock.Anything, mock.MatchedBy(func(input *iotsitewise.GetInterpolatedAssetPropertyValuesInput) bool {
+		return aws.ToString(input.NextToken) == "last-window-token"
+	}), mock.Anything, mock.Anything)
+}
+
+func TestGetInterpolatedAssetPropertyValuesFailsEntriesWithAThrottledWindow(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	query := models.AssetPropertyValueQuery{Resolution: "1m", InterpolationType: models.InterpolationTypeLinear}
+	query.AssetIds = []string{"asset"}
+	query.PropertyIds = []string{"throttled", "property"}
+	query.MaxPageAggregations = 1
+	query.TimeRange = backend.TimeRange{From: start, To: start.Add(24 * time.Hour)}
+
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("GetInterpolatedAssetPropertyValuesPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		func(_ context.Context, input *iotsitewise.GetInterpolatedAssetPropertyValuesInput, _ int, _ int) *iotsitewise.GetInterpolatedAssetPropertyValuesOutput {
+			out := &iotsitewise.GetInterpolatedAssetPropertyValuesOutput{
+				InterpolatedAssetPropertyValues: interpolatedValues(*input.StartTimeInSeconds, *input.EndTimeInSeconds),
+			}
+			// the second window of the throttled property stops after its first page
+			if *input.PropertyId == "throttled" && *input.StartTimeInSeconds == start.Unix()+15000 {
+				out.InterpolatedAssetPropertyValues = out.InterpolatedAssetPropertyValues[:1]
+				out.NextToken = aws.String("throttled-window-token")
+			}
+			return out
+		}, nil)
+
+	_, fr, err := GetInterpolatedAssetPropertyValues(context.Background(), mockSw, query, nil)
+	require.NoError(t, err)
+
+	throttled := *util.GetEntryIdFromAssetProperty("asset", "throttled")
+	require.NotContains(t, fr.Responses, throttled)
+	require.ErrorContains(t, fr.ErrorEntries[throttled], "the interpolated values from 2023-11-15T02:23:20Z to 2023-11-15T06:33:19Z were cut short because SiteWise throttled their requests")
+
+	// the other entries are stitched whole
+	stitched := fr.Responses[*util.GetEntryIdFromAssetProperty("asset", "property")]
+	require.Len(t, stitched.InterpolatedAssetPropertyValues, 12)
+	require.Nil(t, stitched.NextToken)
+}
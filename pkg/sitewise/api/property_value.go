@@ -8,30 +8,35 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/framer"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 )
 
-func valueQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.GetAssetPropertyValueInput {
-
+func valueQueryToInput(entry models.AssetPropertyEntry) *iotsitewise.GetAssetPropertyValueInput {
+	assetId, propertyId, propertyAlias := getEntryIdentifiers(entry)
 	return &iotsitewise.GetAssetPropertyValueInput{
-		AssetId:       getFirstAssetId(query.BaseQuery),
-		PropertyId:    getFirstPropertyId(query.BaseQuery),
-		PropertyAlias: getFirstPropertyAlias(query.BaseQuery),
+		AssetId:       assetId,
+		PropertyId:    propertyId,
+		PropertyAlias: propertyAlias,
 	}
 }
 
+// GetAssetPropertyValue fetches the latest value of every AssetPropertyEntry of the query with its own request
 func GetAssetPropertyValue(ctx context.Context, client client.SitewiseAPIClient, query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.AssetPropertyValue, error) {
+	ctx, span := util.StartSpan(ctx, "api.GetAssetPropertyValue", util.PropertyValueQueryAttributes(query)...)
+	defer span.End()
+
 	modifiedQuery, err := getAssetIdAndPropertyId(query, client, ctx)
 	if err != nil {
 		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
-	awsReq := valueQueryToInput(modifiedQuery)
-
-	resp, err := client.GetAssetPropertyValue(ctx, awsReq)
-
+	responses, errorEntries, err := fanOutEntries(ctx, modifiedQuery, func(ctx context.Context, entry models.AssetPropertyEntry) (*iotsitewise.GetAssetPropertyValueOutput, error) {
+		return client.GetAssetPropertyValue(ctx, valueQueryToInput(entry))
+	})
 	if err != nil {
 		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
-	return modifiedQuery, &framer.AssetPropertyValue{PropertyValue: resp.PropertyValue}, nil
+	return modifiedQuery, &framer.AssetPropertyValue{Responses: responses,
+		ErrorEntries: errorEntries, Query: modifiedQuery}, nil
 }
@@ -39,20 +39,17 @@ func valueBatchQueryToInput(query models.AssetPropertyValueQuery) *iotsitewise.B
 }
 
 func BatchGetAssetPropertyValue(ctx context.Context, client client.SitewiseAPIClient, query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.AssetPropertyValueBatch, error) {
+	ctx, span := util.StartSpan(ctx, "api.BatchGetAssetPropertyValue", util.PropertyValueQueryAttributes(query)...)
+	defer span.End()
+
 	modifiedQuery, err := getAssetIdAndPropertyId(query, client, ctx)
 	if err != nil {
 		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
-	batchedQueries := batchQueries(modifiedQuery, BatchGetAssetPropertyValueMaxEntries)
-	responses := []*iotsitewise.BatchGetAssetPropertyValueOutput{}
-	for _, q := range batchedQueries {
-		req := valueBatchQueryToInput(q)
-		resp, err := client.BatchGetAssetPropertyValue(ctx, req)
-		if err != nil {
-			return models.AssetPropertyValueQuery{}, nil, err
-		}
-		responses = append(responses, resp)
+	responses, err := batchGetAssetPropertyValue(ctx, client, modifiedQuery)
+	if err != nil {
+		return models.AssetPropertyValueQuery{}, nil, err
 	}
 
 	anomalyAssetIds := []string{}
@@ -71,3 +68,35 @@ func BatchGetAssetPropertyValue(ctx context.Context, client client.SitewiseAPICl
 		},
 		nil
 }
+
+// BatchGetAssetPropertyValueForEntries fetches the latest values for a query whose
+// AssetPropertyEntries have already been resolved, skipping the alias lookups.
+// It is used to poll the same entries repeatedly for streaming.
+func BatchGetAssetPropertyValueForEntries(ctx context.Context, client client.SitewiseAPIClient, query models.AssetPropertyValueQuery) (*framer.AssetPropertyValueBatch, error) {
+	ctx, span := util.StartSpan(ctx, "api.BatchGetAssetPropertyValueForEntries", util.PropertyValueQueryAttributes(query)...)
+	defer span.End()
+
+	responses, err := batchGetAssetPropertyValue(ctx, client, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &framer.AssetPropertyValueBatch{
+		Responses:      responses,
+		SitewiseClient: client,
+	}, nil
+}
+
+func batchGetAssetPropertyValue(ctx context.Context, client client.SitewiseAPIClient, query models.AssetPropertyValueQuery) ([]*iotsitewise.BatchGetAssetPropertyValueOutput, error) {
+	batchedQueries := batchQueries(query, BatchGetAssetPropertyValueMaxEntries)
+	responses := []*iotsitewise.BatchGetAssetPropertyValueOutput{}
+	for _, q := range batchedQueries {
+		req := valueBatchQueryToInput(q)
+		resp, err := client.BatchGetAssetPropertyValue(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, resp)
+	}
+	return responses, nil
+}
@@ -7,10 +7,13 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api/propvals"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 )
 
 func GetAssetPropertyValuesForTimeRange(ctx context.Context, sw client.SitewiseAPIClient,
 	query models.AssetPropertyValueQuery) (models.AssetPropertyValueQuery, *framer.AssetPropertyValuesForTimeRange, error) {
+	ctx, span := util.StartSpan(ctx, "api.GetAssetPropertyValuesForTimeRange", util.PropertyValueQueryAttributes(query)...)
+	defer span.End()
 
 	if query.Resolution == "AUTO" {
 		resolution := propvals.Resolution(query.BaseQuery)
@@ -50,21 +50,23 @@ func durationForTimeRange(resolution string, timeRange backend.TimeRange) float6
 	}
 }
 
+// PagesForResolution is the number of pages of maxResponseSize data points it takes to load a time range at a resolution.
 // Takes the ceil of the pages. Ex: a duration that takes 2.1 pages to load all data takes 3 requests/pages to load
-func pagesForResolution(resolution string, timeRange backend.TimeRange, maxResponseSize float64) int64 {
+func PagesForResolution(resolution string, timeRange backend.TimeRange, maxResponseSize float64) int64 {
 	duration := durationForTimeRange(resolution, timeRange)
 	return roundUp(duration / maxResponseSize)
 }
 
+// DataPointsForResolution is the number of data points of a time range at a resolution.
 // Takes the floor of the duration - ex: duration of 10.5 minutes would load 10 data points
-func dataPointsForResolution(resolution string, timeRange backend.TimeRange) int32 {
+func DataPointsForResolution(resolution string, timeRange backend.TimeRange) int32 {
 	return int32(durationForTimeRange(resolution, timeRange))
 }
 
 func Resolution(query models.BaseQuery) string {
 	for _, resolution := range []string{ResolutionSecond, ResolutionMinute, ResolutionFifteenMinutes, ResolutionHour} {
-		pages := pagesForResolution(resolution, query.TimeRange, maxHistoryResponseSize)
-		dps := dataPointsForResolution(resolution, query.TimeRange)
+		pages := PagesForResolution(resolution, query.TimeRange, maxHistoryResponseSize)
+		dps := DataPointsForResolution(resolution, query.TimeRange)
 		// TODO: once '1s' resolution is supported, will need to add threshold for determining
 		if dps <= query.MaxDataPoints && pages <= maxHistoryPagesToLoad {
 			return resolution
@@ -76,8 +78,8 @@ func Resolution(query models.BaseQuery) string {
 
 func InterpolatedResolution(query models.AssetPropertyValueQuery) string {
 	for _, resolution := range []string{ResolutionSecond, ResolutionTenSeconds, ResolutionMinute, ResolutionTenMinutes, ResolutionHour, ResolutionTenHours} {
-		pages := pagesForResolution(resolution, query.TimeRange, maxInterpolatedResponseSize)
-		dps := dataPointsForResolution(resolution, query.TimeRange)
+		pages := PagesForResolution(resolution, query.TimeRange, maxInterpolatedResponseSize)
+		dps := DataPointsForResolution(resolution, query.TimeRange)
 		if dps <= query.MaxDataPoints && pages <= maxInterpolatedPagesToLoad {
 			return resolution
 		}
@@ -81,3 +81,33 @@ func TestResolution(t *testing.T) {
 		})
 	}
 }
+
+var interpolatedScenarios = []scenario{
+	{
+		// dps = 300, pages = 30
+		name: "selects '10s' resolution",
+		query: models.BaseQuery{
+			TimeRange:     backend.TimeRange{From: testdata.FiveMinutes, To: testdata.Now},
+			MaxDataPoints: 720,
+		},
+		expected: ResolutionTenSeconds,
+	},
+	{
+		// dps = 1440, pages = 144
+		name: "selects '1h' resolution for a day with enough data points for '1m'",
+		query: models.BaseQuery{
+			TimeRange:     backend.TimeRange{From: testdata.OneDay, To: testdata.Now},
+			MaxDataPoints: 1440,
+		},
+		expected: ResolutionHour,
+	},
+}
+
+func TestInterpolatedResolution(t *testing.T) {
+	for _, scene := range interpolatedScenarios {
+		t.Run(scene.name, func(t *testing.T) {
+			actual := InterpolatedResolution(models.AssetPropertyValueQuery{BaseQuery: scene.query})
+			assert.Equal(t, scene.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+// The history, aggregates and interpolated APIs filter by a single quality. History queries with
+// more than one quality are requested without a filter and their values are filtered per point,
+// while aggregate and interpolated queries are fetched once per quality with QueryForQuality.
+
+// historyQualities is the quality filter of the history requests of a query
+func historyQualities(query models.AssetPropertyValueQuery) []iotsitewisetypes.Quality {
+	qualities := query.GetQualities()
+	if len(qualities) > 1 {
+		return nil
+	}
+	return qualities
+}
+
+// filterQualities returns the values of the qualities a query requested out of the history of a
+// request without a quality filter. The values are left as they are, they belong to the response
+// of the client, which may be shared with other queries.
+func filterQualities(query models.AssetPropertyValueQuery, values []iotsitewisetypes.AssetPropertyValue) []iotsitewisetypes.AssetPropertyValue {
+	qualities := query.GetQualities()
+	if len(qualities) == 1 || len(qualities) == len(models.AllQualities) {
+		return values
+	}
+	filtered := make([]iotsitewisetypes.AssetPropertyValue, 0, len(values))
+	for _, v := range values {
+		if slices.Contains(qualities, v.Quality) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
+// filterHistoryQualities is a copy of a history response with the values of its entries filtered
+// by filterQualities
+func filterHistoryQualities(query models.AssetPropertyValueQuery, resp *iotsitewise.GetAssetPropertyValueHistoryOutput) *iotsitewise.GetAssetPropertyValueHistoryOutput {
+	filtered := *resp
+	filtered.AssetPropertyValueHistory = filterQualities(query, resp.AssetPropertyValueHistory)
+	return &filtered
+}
+
+// filterBatchHistoryQualities is a copy of a batch history response with the values of its
+// entries filtered by filterQualities
+func filterBatchHistoryQualities(query models.AssetPropertyValueQuery, resp *iotsitewise.BatchGetAssetPropertyValueHistoryOutput) *iotsitewise.BatchGetAssetPropertyValueHistoryOutput {
+	filtered := *resp
+	filtered.SuccessEntries = make([]iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry, len(resp.SuccessEntries))
+	for i, entry := range resp.SuccessEntries {
+		entry.AssetPropertyValueHistory = filterQualities(query, entry.AssetPropertyValueHistory)
+		filtered.SuccessEntries[i] = entry
+	}
+	return &filtered
+}
+
+// requestQuality is the quality of the aggregates and interpolated requests of a query
+func requestQuality(query models.AssetPropertyValueQuery) iotsitewisetypes.Quality {
+	return query.GetQualities()[0]
+}
+
+// FetchesPerQuality reports whether a query has more than one quality and is fetched with an API
+// that filters by a single quality, so it has to be fetched once per quality
+func FetchesPerQuality(query models.AssetPropertyValueQuery) bool {
+	if len(query.GetQualities()) < 2 {
+		return false
+	}
+	switch query.QueryType {
+	case models.QueryTypePropertyAggregate:
+		return aggregateResolution(query) != models.PropertyQueryResolutionRaw
+	case models.QueryTypePropertyInterpolated:
+		return true
+	}
+	return false
+}
+
+// QueryForQuality returns the query that fetches one quality of a query that is fetched once per
+// quality. The next tokens of a page are keyed by the QualityEntryId of each entry, so the tokens
+// of the quality are picked out of them. It returns false when the query is a next page that the
+// quality has no more pages for.
+func QueryForQuality(query models.AssetPropertyValueQuery, quality iotsitewisetypes.Quality) (models.AssetPropertyValueQuery, bool) {
+	q := query
+	q.Quality = quality
+	q.Qualities = nil
+	q.NextToken = ""
+	q.NextTokens = nil
+	if len(query.NextTokens) == 0 {
+		return q, true
+	}
+
+	q.NextTokens = map[string]string{}
+	for key, token := range query.NextTokens {
+		if entryId, ok := strings.CutSuffix(key, models.QualityEntryId("", quality)); ok && token != "" {
+			q.NextTokens[entryId] = token
+		}
+	}
+	return q, len(q.NextTokens) > 0
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+func qualityValues(qualities ...iotsitewisetypes.Quality) []iotsitewisetypes.AssetPropertyValue {
+	values := make([]iotsitewisetypes.AssetPropertyValue, len(qualities))
+	for i, quality := range qualities {
+		values[i] = iotsitewisetypes.AssetPropertyValue{
+			Quality:   quality,
+			Timestamp: &iotsitewisetypes.TimeInNanos{TimeInSeconds: aws.Int64(int64(i))},
+			Value:     &iotsitewisetypes.Variant{DoubleValue: aws.Float64(float64(i))},
+		}
+	}
+	return values
+}
+
+func TestFilterQualitiesLeavesTheClientResponse(t *testing.T) {
+	query := models.AssetPropertyValueQuery{Qualities: []iotsitewisetypes.Quality{iotsitewisetypes.QualityGood, iotsitewisetypes.QualityBad}}
+	query.AssetIds = []string{"asset"}
+	query.PropertyIds = []string{"p1", "p2", "p3"}
+
+	// the entries share one response, as they would with a client that shares its results
+	shared := &iotsitewise.GetAssetPropertyValueHistoryOutput{
+		AssetPropertyValueHistory: qualityValues(iotsitewisetypes.QualityUncertain, iotsitewisetypes.QualityGood, iotsitewisetypes.QualityUncertain, iotsitewisetypes.QualityBad),
+	}
+	mockSw := &mocks.SitewiseAPIClient{}
+	mockSw.On("GetAssetPropertyValueHistoryPageAggregation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(shared, nil)
+
+	_, fr, err := GetAssetPropertyValues(context.Background(), mockSw, query)
+	require.NoError(t, err)
+	for _, propertyId := range query.PropertyIds {
+		values := fr.Responses[*util.GetEntryIdFromAssetProperty("asset", propertyId)].AssetPropertyValueHistory
+		require.Equal(t, []iotsitewisetypes.AssetPropertyValue{shared.AssetPropertyValueHistory[1], shared.AssetPropertyValueHistory[3]}, values)
+	}
+	require.Equal(t, qualityValues(iotsitewisetypes.QualityUncertain, iotsitewisetypes.QualityGood, iotsitewisetypes.QualityUncertain, iotsitewisetypes.QualityBad), shared.AssetPropertyValueHistory)
+}
+
+func TestFilterBatchHistoryQualitiesLeavesTheClientResponse(t *testing.T) {
+	query := models.AssetPropertyValueQuery{Qualities: []iotsitewisetypes.Quality{iotsitewisetypes.QualityGood, iotsitewisetypes.QualityBad}}
+	values := qualityValues(iotsitewisetypes.QualityUncertain, iotsitewisetypes.QualityGood, iotsitewisetypes.QualityBad)
+	resp := &iotsitewise.BatchGetAssetPropertyValueHistoryOutput{
+		SuccessEntries: []iotsitewisetypes.BatchGetAssetPropertyValueHistorySuccessEntry{{EntryId: aws.String("entry"), AssetPropertyValueHistory: values}},
+		NextToken:      aws.String("next"),
+	}
+
+	filtered := filterBatchHistoryQualities(query, resp)
+	require.Equal(t, "next", *filtered.NextToken)
+	require.Equal(t, "entry", *filtered.SuccessEntries[0].EntryId)
+	require.Equal(t, values[1:], filtered.SuccessEntries[0].AssetPropertyValueHistory)
+	require.Equal(t, qualityValues(iotsitewisetypes.QualityUncertain, iotsitewisetypes.QualityGood, iotsitewisetypes.QualityBad), resp.SuccessEntries[0].AssetPropertyValueHistory)
+}
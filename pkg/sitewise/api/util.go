@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"math"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
@@ -10,6 +11,8 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
 	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -58,6 +61,9 @@ func getAssetIdAndPropertyId(query models.AssetPropertyValueQuery, client client
 	// There should only be a list of property aliases OR lists for assetIds and propertyIds
 	// Look up the assetId and propertyId for a property alias
 	if len(query.PropertyAliases) > 0 {
+		ctx, span := util.StartSpan(ctx, "api.resolvePropertyAliases", attribute.Int("sitewise.aliases", len(query.PropertyAliases)))
+		defer span.End()
+
 		for _, propertyAlias := range query.PropertyAliases {
 			resp, err := client.DescribeTimeSeries(ctx, &iotsitewise.DescribeTimeSeriesInput{
 				Alias: aws.String(propertyAlias),
@@ -91,25 +97,75 @@ func getAssetIdAndPropertyId(query models.AssetPropertyValueQuery, client client
 	return result, nil
 }
 
-func getFirstAssetId(query models.BaseQuery) *string {
-	if len(query.AssetIds) == 0 {
-		return nil
+// ResolveAssetPropertyEntries expands the asset ids, property ids and property aliases
+// of a query into the AssetPropertyEntries used by the batch APIs.
+func ResolveAssetPropertyEntries(ctx context.Context, client client.SitewiseAPIClient, query models.AssetPropertyValueQuery) ([]models.AssetPropertyEntry, error) {
+	modifiedQuery, err := getAssetIdAndPropertyId(query, client, ctx)
+	if err != nil {
+		return nil, err
 	}
-	return aws.String(query.AssetIds[0])
+	return modifiedQuery.AssetPropertyEntries, nil
 }
 
-func getFirstPropertyId(query models.BaseQuery) *string {
-	if len(query.PropertyIds) == 0 {
-		return nil
+// getEntryIdentifiers returns either the assetId and propertyId, or the propertyAlias of a
+// disassociated stream, to request a single entry with
+func getEntryIdentifiers(entry models.AssetPropertyEntry) (assetId *string, propertyId *string, propertyAlias *string) {
+	if entry.AssetId != "" && entry.PropertyId != "" {
+		return aws.String(entry.AssetId), aws.String(entry.PropertyId), nil
 	}
-	return aws.String(query.PropertyIds[0])
+	return nil, nil, aws.String(entry.PropertyAlias)
 }
 
-func getFirstPropertyAlias(query models.BaseQuery) *string {
-	if len(query.PropertyAliases) == 0 {
+// getEntryNextToken returns the next token of one entry of a query that is requested entry by entry.
+// The nextToken of the query only belongs to a single entry.
+func getEntryNextToken(query models.BaseQuery, entry models.AssetPropertyEntry) *string {
+	nextToken := query.NextToken
+	if len(query.NextTokens) > 0 {
+		nextToken = query.NextTokens[*util.GetEntryIdFromAssetPropertyEntry(entry)]
+	} else if len(query.AssetPropertyEntries) > 1 {
+		nextToken = ""
+	}
+	if nextToken == "" {
 		return nil
 	}
-	return aws.String(query.PropertyAliases[0])
+	return aws.String(nextToken)
+}
+
+// fanOutEntries runs fn for every AssetPropertyEntry of the query, up to MaxConcurrentRequests
+// at a time, and collects the results and the errors by EntryId, so that an entry that fails does
+// not fail the others. It only fails when every entry failed. It is used by the APIs without a
+// batch variant, such as the ones of SiteWise Edge.
+func fanOutEntries[T any](ctx context.Context, query models.AssetPropertyValueQuery, fn func(ctx context.Context, entry models.AssetPropertyEntry) (T, error)) (map[string]T, map[string]error, error) {
+	var mu sync.Mutex
+	results := make(map[string]T, len(query.AssetPropertyEntries))
+	errorEntries := map[string]error{}
+
+	var eg errgroup.Group
+	eg.SetLimit(MaxConcurrentRequests)
+	for _, entry := range query.AssetPropertyEntries {
+		eg.Go(func() error {
+			result, err := fn(ctx, entry)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errorEntries[*util.GetEntryIdFromAssetPropertyEntry(entry)] = err
+				return nil
+			}
+			results[*util.GetEntryIdFromAssetPropertyEntry(entry)] = result
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	if len(results) == 0 {
+		for _, entry := range query.AssetPropertyEntries {
+			if err, ok := errorEntries[*util.GetEntryIdFromAssetPropertyEntry(entry)]; ok {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return results, errorEntries, nil
 }
 
 func filterAnomalyAssetIds(ctx context.Context, client client.SitewiseAPIClient, query models.AssetPropertyValueQuery) ([]string, error) {
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
@@ -107,6 +109,44 @@ func TestGetNextToken(t *testing.T) {
 	}
 }
 
+func TestFanOutEntries(t *testing.T) {
+	query := models.AssetPropertyValueQuery{}
+	query.AssetPropertyEntries = []models.AssetPropertyEntry{
+		{AssetId: "asset-1", PropertyId: "good"},
+		{AssetId: "asset-1", PropertyId: "bad"},
+	}
+	goodEntryID := *util.GetEntryIdFromAssetProperty("asset-1", "good")
+	badEntryID := *util.GetEntryIdFromAssetProperty("asset-1", "bad")
+	errBad := errors.New("property not found")
+
+	t.Run("a failing entry keeps the others", func(t *testing.T) {
+		results, errorEntries, err := fanOutEntries(context.Background(), query, func(_ context.Context, entry models.AssetPropertyEntry) (string, error) {
+			if entry.PropertyId == "bad" {
+				return "", errBad
+			}
+			return entry.PropertyId, nil
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if results[goodEntryID] != "good" || len(results) != 1 {
+			t.Fatalf("expected the result of the good entry only, got %v", results)
+		}
+		if !errors.Is(errorEntries[badEntryID], errBad) || len(errorEntries) != 1 {
+			t.Fatalf("expected the error of the bad entry only, got %v", errorEntries)
+		}
+	})
+
+	t.Run("every entry failing fails the query", func(t *testing.T) {
+		_, _, err := fanOutEntries(context.Background(), query, func(context.Context, models.AssetPropertyEntry) (string, error) {
+			return "", errBad
+		})
+		if !errors.Is(err, errBad) {
+			t.Fatalf("expected %v, got %v", errBad, err)
+		}
+	})
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
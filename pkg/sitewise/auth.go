@@ -2,25 +2,58 @@ package sitewise
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
-	"encoding/pem"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"net/url"
 	"path"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"golang.org/x/sync/singleflight"
 )
 
+// edgeAuthTimeout is how long a request for new credentials may take
+const edgeAuthTimeout = 5 * time.Second
+
+// EdgeAuthenticator manages the session credentials of an edge gateway. Concurrent callers share
+// a single refresh, and the credentials are renewed RenewalMargin before they expire.
 type EdgeAuthenticator struct {
 	Settings models.AWSSiteWiseDataSourceSetting
-	authInfo *models.AuthInfo
+	// RenewalMargin is how long before the session expiry the credentials are renewed.
+	// It is capped at half of the session lifetime.
+	RenewalMargin time.Duration
+
+	mu          sync.Mutex
+	authInfo    *models.AuthInfo
+	generation  uint64
+	refreshedAt time.Time
+	lastError   error
+
+	// refreshes shares the refresh in flight, which runs without holding mu
+	refreshes singleflight.Group
+
+	clientOnce sync.Once
+	client     *http.Client
+	clientErr  error
+}
+
+// EdgeTokenState describes the edge session credentials for the health check
+type EdgeTokenState struct {
+	AuthMode      string    `json:"authMode"`
+	Authenticated bool      `json:"authenticated"`
+	ExpiresAt     time.Time `json:"expiresAt,omitempty"`
+	RefreshedAt   time.Time `json:"refreshedAt,omitempty"`
+	Refreshes     uint64    `json:"refreshes"`
+	LastError     string    `json:"lastError,omitempty"`
 }
 
 type AuthRequest struct {
@@ -29,108 +62,210 @@ type AuthRequest struct {
 	AuthMechanism string `json:"authMechanism,omitempty"`
 }
 
-func (a *EdgeAuthenticator) GetAuthInfo() (*models.AuthInfo, error) {
+// GetAuthInfo returns the current credentials, renewing them first when they are missing or about to expire
+func (a *EdgeAuthenticator) GetAuthInfo(ctx context.Context) (*models.AuthInfo, error) {
 	if a == nil {
 		return nil, nil
 	}
-	if a.authInfo == nil || time.Now().After(a.authInfo.SessionExpiryTime) {
-		err := a.Authenticate()
-		if err != nil {
-			return nil, err
-		}
+	a.mu.Lock()
+	if a.authInfo != nil && time.Now().Before(a.renewAt()) {
+		defer a.mu.Unlock()
+		return a.authInfo, nil
+	}
+	generation := a.generation
+	a.mu.Unlock()
+
+	if err := a.refresh(ctx, generation); err != nil {
+		return nil, err
 	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	return a.authInfo, nil
+}
 
+// Generation identifies the credentials returned by GetAuthInfo, it changes on every refresh
+func (a *EdgeAuthenticator) Generation() uint64 {
+	if a == nil {
+		return 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.generation
 }
 
-func (a *EdgeAuthenticator) Authenticate() error {
+// Refresh renews the credentials after they were rejected by the gateway. The credentials are
+// only renewed if they are still of the given generation, so requests that fail together
+// trigger a single refresh.
+func (a *EdgeAuthenticator) Refresh(ctx context.Context, generation uint64) error {
 	if a == nil {
 		return nil
 	}
-	reqBodyJson, err := json.Marshal(
-		&AuthRequest{
-			Username:      a.Settings.EdgeAuthUser,
-			Password:      a.Settings.EdgeAuthPass,
-			AuthMechanism: a.Settings.EdgeAuthMode,
-		})
-	if err != nil {
-		return err
+	return a.refresh(ctx, generation)
+}
+
+// State returns the state of the credentials without renewing them
+func (a *EdgeAuthenticator) State() EdgeTokenState {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	state := EdgeTokenState{
+		AuthMode:    a.Settings.EdgeAuthMode,
+		RefreshedAt: a.refreshedAt,
+		Refreshes:   a.generation,
 	}
+	if a.authInfo != nil {
+		state.Authenticated = time.Now().Before(a.authInfo.SessionExpiryTime)
+		state.ExpiresAt = a.authInfo.SessionExpiryTime
+	}
+	if a.lastError != nil {
+		state.LastError = a.lastError.Error()
+	}
+	return state
+}
 
-	pool, _ := x509.SystemCertPool()
-	if pool == nil {
-		pool = x509.NewCertPool()
+func (a *EdgeAuthenticator) renewAt() time.Time {
+	margin := a.RenewalMargin
+	if lifetime := a.authInfo.SessionExpiryTime.Sub(a.refreshedAt); margin > lifetime/2 {
+		margin = lifetime / 2
 	}
+	return a.authInfo.SessionExpiryTime.Add(-margin)
+}
 
-	if a.Settings.Cert == "" {
-		return fmt.Errorf("certificate cannot be null")
+// Authenticate requests new credentials from the gateway
+func (a *EdgeAuthenticator) Authenticate(ctx context.Context) error {
+	if a == nil {
+		return nil
 	}
+	return a.refresh(ctx, a.Generation())
+}
 
-	block, _ := pem.Decode([]byte(a.Settings.Cert))
-	if block == nil || block.Type != "CERTIFICATE" || len(block.Headers) != 0 {
-		return fmt.Errorf("decode certificate failed: %s", a.Settings.Cert)
+// refresh requests new credentials unless they were renewed since the given generation. The
+// request is shared by the callers that refresh at the same time, so it runs with its own
+// timeout and is not canceled with the context of the caller that started it; each caller stops
+// waiting for it when its own context is done.
+func (a *EdgeAuthenticator) refresh(ctx context.Context, generation uint64) error {
+	ch := a.refreshes.DoChan("refresh", func() (any, error) {
+		if a.Generation() != generation {
+			return nil, nil
+		}
+		rctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), edgeAuthTimeout)
+		defer cancel()
+		authInfo, err := a.requestAuthInfo(rctx)
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.lastError = err
+		if err != nil {
+			return nil, err
+		}
+		a.authInfo = authInfo
+		a.generation++
+		a.refreshedAt = time.Now()
+		return nil, nil
+	})
+	select {
+	case res := <-ch:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return err
-	}
-	pool.AddCert(cert)
-
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true, //Not actually skipping, check the cert in VerifyPeerCertificate
-			RootCAs:            pool,
-			VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
-				// If this is the first handshake on a connection, process and
-				// (optionally) verify the server's certificates.
-				certs := make([]*x509.Certificate, len(rawCerts))
-				for i, asn1Data := range rawCerts {
-					cert, err := x509.ParseCertificate(asn1Data)
-					if err != nil {
-						return fmt.Errorf("tls: failed to parse certificate from server: %w", err)
+// httpClient is the client of the authentication requests, built once with the certificate of
+// the gateway
+func (a *EdgeAuthenticator) httpClient() (*http.Client, error) {
+	a.clientOnce.Do(func() {
+		pool, _ := x509.SystemCertPool()
+		if pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		cert, err := client.ParseCertificate(a.Settings.Cert)
+		if err != nil {
+			a.clientErr = err
+			return
+		}
+		pool.AddCert(cert)
+
+		tr := &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true, //Not actually skipping, check the cert in VerifyPeerCertificate
+				RootCAs:            pool,
+				VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+					// If this is the first handshake on a connection, process and
+					// (optionally) verify the server's certificates.
+					certs := make([]*x509.Certificate, len(rawCerts))
+					for i, asn1Data := range rawCerts {
+						cert, err := x509.ParseCertificate(asn1Data)
+						if err != nil {
+							return fmt.Errorf("tls: failed to parse certificate from server: %w", err)
+						}
+						certs[i] = cert
+					}
+
+					// see: https://github.com/golang/go/issues/21971
+					opts := x509.VerifyOptions{
+						Roots:         pool,
+						CurrentTime:   time.Now(),
+						DNSName:       "", // <- skip hostname verification
+						Intermediates: x509.NewCertPool(),
 					}
-					certs[i] = cert
-				}
-
-				// see: https://github.com/golang/go/issues/21971
-				opts := x509.VerifyOptions{
-					Roots:         pool,
-					CurrentTime:   time.Now(),
-					DNSName:       "", // <- skip hostname verification
-					Intermediates: x509.NewCertPool(),
-				}
-
-				for i, cert := range certs {
-					if i == 0 {
-						continue
+
+					for i, cert := range certs {
+						if i == 0 {
+							continue
+						}
+						opts.Intermediates.AddCert(cert)
 					}
-					opts.Intermediates.AddCert(cert)
-				}
-				_, err := certs[0].Verify(opts)
-				return err
+					_, err := certs[0].Verify(opts)
+					return err
+				},
 			},
-		},
+		}
+
+		a.client = &http.Client{Transport: tr, Timeout: edgeAuthTimeout}
+	})
+	return a.client, a.clientErr
+}
+
+func (a *EdgeAuthenticator) requestAuthInfo(ctx context.Context) (*models.AuthInfo, error) {
+	reqBodyJson, err := json.Marshal(
+		&AuthRequest{
+			Username:      a.Settings.EdgeAuthUser,
+			Password:      a.Settings.EdgeAuthPass,
+			AuthMechanism: a.Settings.EdgeAuthMode,
+		})
+	if err != nil {
+		return nil, err
 	}
 
-	client := &http.Client{Transport: tr, Timeout: time.Second * 5}
+	httpClient, err := a.httpClient()
+	if err != nil {
+		return nil, err
+	}
 
 	u, err := url.Parse(a.Settings.Endpoint)
 	if err != nil {
 		log.DefaultLogger.Error("error parsing edge endpoint url.", "endpoint url:", a.Settings.Endpoint)
-		return fmt.Errorf("cannot parse edge endpoint url. url: %v", a.Settings.Endpoint)
+		return nil, fmt.Errorf("cannot parse edge endpoint url. url: %v", a.Settings.Endpoint)
 	}
 	u.Path = path.Join(u.Path, "authenticate")
 	authEndpoint := u.String()
 
-	resp, err := client.Post(authEndpoint, "application/json", bytes.NewBuffer(reqBodyJson))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, authEndpoint, bytes.NewBuffer(reqBodyJson))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		log.DefaultLogger.Error("edge auth response not ok:", "response code:", strconv.Itoa(resp.StatusCode))
-		return fmt.Errorf("request not ok. returned code: %v", resp.StatusCode)
+		return nil, fmt.Errorf("request not ok. returned code: %v", resp.StatusCode)
 	}
 
 	log.DefaultLogger.Debug("edge auth response ok.")
@@ -138,10 +273,9 @@ func (a *EdgeAuthenticator) Authenticate() error {
 	authInfo := models.AuthInfo{}
 	err = json.NewDecoder(resp.Body).Decode(&authInfo)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	a.authInfo = &authInfo
-	return nil
+	return &authInfo, nil
 }
 
 type DummyAuthenticator struct {
@@ -1,6 +1,7 @@
 package sitewise
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -14,10 +15,15 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -54,7 +60,7 @@ cciS5hf80XzIFqwhzaVS9gmiyM8=
 	`
 
 	a := EdgeAuthenticator{Settings: settings}
-	err := a.Authenticate()
+	err := a.Authenticate(context.Background())
 
 	require.NoError(t, err)
 	require.Equal(t, settings.EdgeAuthMode, a.authInfo.AuthMechanism)
@@ -89,7 +95,7 @@ func TestAuthWithServer(t *testing.T) {
 	settings.Cert = string(rootCertPEM)
 
 	a := EdgeAuthenticator{Settings: settings}
-	err = a.Authenticate()
+	err = a.Authenticate(context.Background())
 
 	require.NoError(t, err)
 	require.Equal(t, settings.EdgeAuthMode, a.authInfo.AuthMechanism)
@@ -214,3 +220,156 @@ func createTLSCert() ([]byte, tls.Certificate, error) {
 
 	return rootCertPEM, rootTLSCert, nil
 }
+
+// helper function to start an edge gateway that counts the authentication requests
+func newCountingAuthServer(t *testing.T, sessionDuration time.Duration) (models.AWSSiteWiseDataSourceSetting, *atomic.Int32) {
+	t.Helper()
+	var count atomic.Int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count.Add(1)
+		// slow enough for concurrent callers to wait on the same refresh
+		time.Sleep(20 * time.Millisecond)
+		_ = json.NewEncoder(w).Encode(models.AuthInfo{
+			AccessKeyId:       fmt.Sprintf("key-%d", count.Load()),
+			SecretAccessKey:   "secret",
+			SessionExpiryTime: time.Now().Add(sessionDuration),
+		})
+	}))
+
+	rootCertPEM, rootTLSCert, err := createTLSCert()
+	require.NoError(t, err)
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{rootTLSCert}}
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	settings := models.AWSSiteWiseDataSourceSetting{
+		EdgeAuthMode: "ldap",
+		EdgeAuthUser: "username",
+		EdgeAuthPass: "password",
+	}
+	settings.Endpoint = ts.URL
+	settings.Cert = string(rootCertPEM)
+	return settings, &count
+}
+
+func TestEdgeAuthenticatorRefreshesOnceForConcurrentCallers(t *testing.T) {
+	settings, count := newCountingAuthServer(t, time.Hour)
+	a := &EdgeAuthenticator{Settings: settings, RenewalMargin: time.Minute}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			authInfo, err := a.GetAuthInfo(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "key-1", authInfo.AccessKeyId)
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(1), count.Load())
+}
+
+func TestEdgeAuthenticatorRenewsBeforeExpiry(t *testing.T) {
+	settings, count := newCountingAuthServer(t, time.Hour)
+	a := &EdgeAuthenticator{Settings: settings, RenewalMargin: time.Minute}
+
+	// outside of the margin the credentials are kept
+	a.authInfo = &models.AuthInfo{AccessKeyId: "current", SessionExpiryTime: time.Now().Add(10 * time.Minute)}
+	a.refreshedAt = time.Now().Add(-time.Hour)
+	authInfo, err := a.GetAuthInfo(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "current", authInfo.AccessKeyId)
+	require.Equal(t, int32(0), count.Load())
+
+	// within the margin they are renewed before they expire
+	a.authInfo.SessionExpiryTime = time.Now().Add(30 * time.Second)
+	authInfo, err = a.GetAuthInfo(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "key-1", authInfo.AccessKeyId)
+	require.Equal(t, int32(1), count.Load())
+}
+
+func TestEdgeAuthenticatorRefreshesRejectedCredentialsOnce(t *testing.T) {
+	settings, count := newCountingAuthServer(t, time.Hour)
+	a := &EdgeAuthenticator{Settings: settings, RenewalMargin: time.Minute}
+	require.NoError(t, a.Authenticate(context.Background()))
+
+	generation := a.Generation()
+	require.NoError(t, a.Refresh(context.Background(), generation))
+	require.NoError(t, a.Refresh(context.Background(), generation))
+	require.Equal(t, int32(2), count.Load())
+
+	state := a.State()
+	require.True(t, state.Authenticated)
+	require.Equal(t, uint64(2), state.Refreshes)
+	require.Equal(t, "ldap", state.AuthMode)
+}
+
+func TestEdgeAuthenticatorRefreshOutlivesCanceledCallers(t *testing.T) {
+	settings, count := newCountingAuthServer(t, time.Hour)
+	a := &EdgeAuthenticator{Settings: settings, RenewalMargin: time.Minute}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := a.GetAuthInfo(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// the refresh the canceled caller started is shared with the next caller
+	authInfo, err := a.GetAuthInfo(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "key-1", authInfo.AccessKeyId)
+	require.Equal(t, int32(1), count.Load())
+
+	// the client of the gateway is built once
+	httpClient := a.client
+	require.NoError(t, a.Refresh(context.Background(), a.Generation()))
+	require.Same(t, httpClient, a.client)
+}
+
+func TestEdgeAuthRetryMiddleware(t *testing.T) {
+	settings, count := newCountingAuthServer(t, time.Hour)
+	a := &EdgeAuthenticator{Settings: settings, RenewalMargin: time.Minute}
+	require.NoError(t, a.Authenticate(context.Background()))
+
+	tests := []struct {
+		name          string
+		statusCodes   []int
+		expectedCalls int
+		expectedErr   bool
+	}{
+		{name: "ok", statusCodes: []int{http.StatusOK}, expectedCalls: 1},
+		{name: "forbidden once", statusCodes: []int{http.StatusForbidden, http.StatusOK}, expectedCalls: 2},
+		{name: "unauthorized twice", statusCodes: []int{http.StatusUnauthorized, http.StatusUnauthorized}, expectedCalls: 2, expectedErr: true},
+		{name: "other errors are not retried", statusCodes: []int{http.StatusInternalServerError}, expectedCalls: 1, expectedErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			next := middleware.FinalizeHandlerFunc(func(ctx context.Context, in middleware.FinalizeInput) (middleware.FinalizeOutput, middleware.Metadata, error) {
+				code := tt.statusCodes[calls]
+				calls++
+				if code == http.StatusOK {
+					return middleware.FinalizeOutput{}, middleware.Metadata{}, nil
+				}
+				return middleware.FinalizeOutput{}, middleware.Metadata{}, &smithyhttp.ResponseError{
+					Response: &smithyhttp.Response{Response: &http.Response{StatusCode: code}},
+					Err:      errors.New("request failed"),
+				}
+			})
+
+			m := &edgeAuthRetryMiddleware{authenticator: a}
+			_, _, err := m.HandleFinalize(context.Background(), middleware.FinalizeInput{Request: smithyhttp.NewStackRequest()}, next)
+
+			require.Equal(t, tt.expectedCalls, calls)
+			if tt.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+	// one authentication up front and one per rejected request
+	require.Equal(t, int32(3), count.Load())
+}
@@ -0,0 +1,100 @@
+package sitewise
+
+import (
+	"context"
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/framer"
+)
+
+// IsBatchPlannable reports whether the entries of a query can be fetched together with the
+// entries of other queries. The next pages of a query are planned by the next token of each of
+// its entries, so queries with a single next token are run on their own, as are edge, streaming
+// and L4E queries, and the queries that are fetched once per quality.
+func IsBatchPlannable(query *models.AssetPropertyValueQuery) bool {
+	return query.AwsRegion != EDGE_REGION &&
+		(query.NextToken == "" || len(query.NextTokens) > 0) &&
+		!query.FlattenL4e &&
+		!query.Streaming &&
+		!query.DryRun &&
+		!api.FetchesPerQuality(*query)
+}
+
+// HandleBatchedAssetPropertyValueQueries fetches the queries of one query type together, sharing
+// the batch API calls of the queries in the same region. It returns the frames by RefID of the
+// queries that were fetched; the others should be run on their own.
+func (ds *Datasource) HandleBatchedAssetPropertyValueQueries(ctx context.Context, queryType string, queries map[string]*models.AssetPropertyValueQuery) (map[string]data.Frames, error) {
+	byRegion := map[string][]string{}
+	for refID, query := range queries {
+		byRegion[query.AwsRegion] = append(byRegion[query.AwsRegion], refID)
+	}
+
+	result := map[string]data.Frames{}
+	for region, refIDs := range byRegion {
+		// a single query gains nothing from a plan
+		if len(refIDs) < 2 {
+			continue
+		}
+		sort.Strings(refIDs)
+
+		sw, err := ds.getClient(ctx, region)
+		if err != nil {
+			return nil, err
+		}
+
+		regionQueries := make([]models.AssetPropertyValueQuery, len(refIDs))
+		for i, refID := range refIDs {
+			regionQueries[i] = *queries[refID]
+		}
+
+		planned, err := planAssetPropertyValueQueries(ctx, sw, queryType, regionQueries)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, p := range planned {
+			if p == nil {
+				continue
+			}
+			frames, err := ds.frameResponse(ctx, p.Query.BaseQuery, p.Framer, sw)
+			if err != nil {
+				backend.Logger.Debug("failed to frame batched query, running it on its own", "refId", refIDs[i], "error", err.Error())
+				continue
+			}
+			result[refIDs[i]] = frames
+		}
+	}
+
+	return result, nil
+}
+
+func planAssetPropertyValueQueries(ctx context.Context, sw client.SitewiseAPIClient, queryType string, queries []models.AssetPropertyValueQuery) ([]*api.PlannedQuery[framer.Framer], error) {
+	switch queryType {
+	case models.QueryTypePropertyValueHistory:
+		return toFramers(api.PlanBatchGetAssetPropertyValues(ctx, sw, queries))
+	case models.QueryTypePropertyAggregate:
+		return toFramers(api.PlanBatchGetAssetPropertyValuesForTimeRange(ctx, sw, queries))
+	case models.QueryTypePropertyValue:
+		return toFramers(api.PlanBatchGetAssetPropertyValue(ctx, sw, queries))
+	default:
+		return make([]*api.PlannedQuery[framer.Framer], len(queries)), nil
+	}
+}
+
+func toFramers[T framer.Framer](planned []*api.PlannedQuery[T], err error) ([]*api.PlannedQuery[framer.Framer], error) {
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*api.PlannedQuery[framer.Framer], len(planned))
+	for i, p := range planned {
+		if p != nil {
+			result[i] = &api.PlannedQuery[framer.Framer]{Query: p.Query, Framer: p.Framer}
+		}
+	}
+	return result, nil
+}
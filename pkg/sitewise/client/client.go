@@ -16,6 +16,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
 	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
+	"github.com/grafana/iot-sitewise-datasource/pkg/metrics"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 )
 
@@ -58,6 +59,10 @@ type SitewiseAPIClient interface {
 
 type SitewiseClient struct {
 	*iotsitewise.Client
+	// Retry is how throttled pages of the page aggregation helpers are retried
+	Retry ThrottleRetry
+	// Metrics label the pages fetched by the page aggregation helpers
+	Metrics metrics.Labels
 }
 
 // NewSitewiseClientForRegion is mainly for testing in this case
@@ -77,10 +82,16 @@ func (c *SitewiseClient) GetAssetPropertyValueHistoryPageAggregation(ctx context
 	pager := iotsitewise.NewGetAssetPropertyValueHistoryPaginator(c.Client, req)
 	for pager.HasMorePages() && numPages < maxPages && len(values) <= maxResults {
 		numPages += 1
-		page, err := pager.NextPage(ctx)
+		page, err := nextPage(ctx, c.Retry, pager.NextPage)
 		if err != nil {
+			// keep the pages that were fetched, the next token continues after them
+			if nextToken != nil && IsThrottling(err) {
+				reportThrottledPage(ctx, "GetAssetPropertyValueHistory", err)
+				break
+			}
 			return nil, err
 		}
+		c.Metrics.PageFetched("GetAssetPropertyValueHistory")
 		nextToken = page.NextToken
 		values = append(values, page.AssetPropertyValueHistory...)
 	}
@@ -104,10 +115,16 @@ func (c *SitewiseClient) BatchGetAssetPropertyValueHistoryPageAggregation(ctx co
 	pager := iotsitewise.NewBatchGetAssetPropertyValueHistoryPaginator(c.Client, req)
 	for pager.HasMorePages() && numPages < maxPages && count <= maxResults {
 		numPages += 1
-		page, err := pager.NextPage(ctx)
+		page, err := nextPage(ctx, c.Retry, pager.NextPage)
 		if err != nil {
+			// keep the pages that were fetched, the next token continues after them
+			if nextToken != nil && IsThrottling(err) {
+				reportThrottledPage(ctx, "BatchGetAssetPropertyValueHistory", err)
+				break
+			}
 			return nil, err
 		}
+		c.Metrics.PageFetched("BatchGetAssetPropertyValueHistory")
 		if len(page.SuccessEntries) > 0 {
 			count += len(page.SuccessEntries[0].AssetPropertyValueHistory)
 		}
@@ -151,10 +168,16 @@ func (c *SitewiseClient) GetInterpolatedAssetPropertyValuesPageAggregation(ctx c
 	pager := iotsitewise.NewGetInterpolatedAssetPropertyValuesPaginator(c.Client, req)
 	for pager.HasMorePages() && numPages < maxPages {
 		numPages += 1
-		page, err := pager.NextPage(ctx)
+		page, err := nextPage(ctx, c.Retry, pager.NextPage)
 		if err != nil {
+			// keep the pages that were fetched, the next token continues after them
+			if nextToken != nil && IsThrottling(err) {
+				reportThrottledPage(ctx, "GetInterpolatedAssetPropertyValues", err)
+				break
+			}
 			return nil, err
 		}
+		c.Metrics.PageFetched("GetInterpolatedAssetPropertyValues")
 		values = append(values, page.InterpolatedAssetPropertyValues...)
 		nextToken = page.NextToken
 	}
@@ -175,10 +198,16 @@ func (c *SitewiseClient) GetAssetPropertyAggregatesPageAggregation(ctx context.C
 	pager := iotsitewise.NewGetAssetPropertyAggregatesPaginator(c.Client, req)
 	for pager.HasMorePages() && numPages < maxPages && len(values) <= maxResults {
 		numPages += 1
-		page, err := pager.NextPage(ctx)
+		page, err := nextPage(ctx, c.Retry, pager.NextPage)
 		if err != nil {
+			// keep the pages that were fetched, the next token continues after them
+			if nextToken != nil && IsThrottling(err) {
+				reportThrottledPage(ctx, "GetAssetPropertyAggregates", err)
+				break
+			}
 			return nil, err
 		}
+		c.Metrics.PageFetched("GetAssetPropertyAggregates")
 		values = append(values, page.AggregatedValues...)
 		nextToken = page.NextToken
 	}
@@ -202,10 +231,17 @@ func (c *SitewiseClient) BatchGetAssetPropertyAggregatesPageAggregation(ctx cont
 
 	pager := iotsitewise.NewBatchGetAssetPropertyAggregatesPaginator(c.Client, req)
 	for pager.HasMorePages() && numPages < maxPages && count <= maxResults {
-		page, err := pager.NextPage(ctx)
+		numPages += 1
+		page, err := nextPage(ctx, c.Retry, pager.NextPage)
 		if err != nil {
+			// keep the pages that were fetched, the next token continues after them
+			if nextToken != nil && IsThrottling(err) {
+				reportThrottledPage(ctx, "BatchGetAssetPropertyAggregates", err)
+				break
+			}
 			return nil, err
 		}
+		c.Metrics.PageFetched("BatchGetAssetPropertyAggregates")
 		if len(page.SuccessEntries) > 0 {
 			count += len(page.SuccessEntries[0].AggregatedValues)
 		}
@@ -239,9 +275,32 @@ func (c *SitewiseClient) BatchGetAssetPropertyAggregatesPageAggregation(ctx cont
 	}, nil
 }
 
+// newTransport returns a transport that keeps enough idle connections for the concurrent
+// requests of a datasource, so that they are reused instead of handshaking again
+func newTransport() *http.Transport {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.MaxIdleConns = 100
+	tr.MaxIdleConnsPerHost = 32
+	tr.IdleConnTimeout = 90 * time.Second
+	return tr
+}
+
+// ParseCertificate decodes the PEM encoded certificate of an edge gateway
+func ParseCertificate(cert string) (*x509.Certificate, error) {
+	if cert == "" {
+		return nil, errors.New("certificate cannot be null")
+	}
+
+	block, _ := pem.Decode([]byte(cert))
+	if block == nil || block.Type != "CERTIFICATE" || len(block.Headers) != 0 {
+		return nil, fmt.Errorf("decode certificate failed: %s", cert)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
 func GetHTTPClient(settings models.AWSSiteWiseDataSourceSetting) (*http.Client, error) {
 	if settings.Region != models.EDGE_REGION {
-		return &http.Client{}, nil
+		return &http.Client{Transport: newTransport()}, nil
 	}
 
 	pool, _ := x509.SystemCertPool()
@@ -249,53 +308,43 @@ func GetHTTPClient(settings models.AWSSiteWiseDataSourceSetting) (*http.Client,
 		pool = x509.NewCertPool()
 	}
 
-	if settings.Cert == "" {
-		return nil, errors.New("certificate cannot be null")
-	}
-
-	block, _ := pem.Decode([]byte(settings.Cert))
-	if block == nil || block.Type != "CERTIFICATE" || len(block.Headers) != 0 {
-		return nil, fmt.Errorf("decode certificate failed: %s", settings.Cert)
-	}
-
-	cert, err := x509.ParseCertificate(block.Bytes)
+	cert, err := ParseCertificate(settings.Cert)
 	if err != nil {
 		return nil, err
 	}
 	pool.AddCert(cert)
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true, //Not actually skipping, check the cert in VerifyPeerCertificate
-			RootCAs:            pool,
-			VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
-				// If this is the first handshake on a connection, process and
-				// (optionally) verify the server's certificates.
-				certs := make([]*x509.Certificate, len(rawCerts))
-				for i, asn1Data := range rawCerts {
-					cert, err := x509.ParseCertificate(asn1Data)
-					if err != nil {
-						return errors.New("tls: failed to parse certificate from server: " + err.Error())
-					}
-					certs[i] = cert
+	tr := newTransport()
+	tr.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: true, //Not actually skipping, check the cert in VerifyPeerCertificate
+		RootCAs:            pool,
+		VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			// If this is the first handshake on a connection, process and
+			// (optionally) verify the server's certificates.
+			certs := make([]*x509.Certificate, len(rawCerts))
+			for i, asn1Data := range rawCerts {
+				cert, err := x509.ParseCertificate(asn1Data)
+				if err != nil {
+					return errors.New("tls: failed to parse certificate from server: " + err.Error())
 				}
+				certs[i] = cert
+			}
 
-				opts := x509.VerifyOptions{
-					Roots:         pool,
-					CurrentTime:   time.Now(),
-					DNSName:       "", // <- skip hostname verification
-					Intermediates: x509.NewCertPool(),
-				}
+			opts := x509.VerifyOptions{
+				Roots:         pool,
+				CurrentTime:   time.Now(),
+				DNSName:       "", // <- skip hostname verification
+				Intermediates: x509.NewCertPool(),
+			}
 
-				for i, cert := range certs {
-					if i == 0 {
-						continue
-					}
-					opts.Intermediates.AddCert(cert)
+			for i, cert := range certs {
+				if i == 0 {
+					continue
 				}
-				_, err := certs[0].Verify(opts)
-				return err
-			},
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := certs[0].Verify(opts)
+			return err
 		},
 	}
 
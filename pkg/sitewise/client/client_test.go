@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchAggregatesPageAggregationStopsAtMaxPages(t *testing.T) {
+	var calls atomic.Int32
+	// every page has one aggregate and a next token
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"successEntries":[{"entryId":"entry","aggregatedValues":[{"timestamp":1,"value":{"average":1}}]}],"skippedEntries":[],"errorEntries":[],"nextToken":"next"}`))
+	}))
+	t.Cleanup(ts.Close)
+	sw := &SitewiseClient{Client: newServerClient(ts.URL)}
+
+	resp, err := sw.BatchGetAssetPropertyAggregatesPageAggregation(context.Background(), &iotsitewise.BatchGetAssetPropertyAggregatesInput{
+		Entries: []iotsitewisetypes.BatchGetAssetPropertyAggregatesEntry{{
+			EntryId:        aws.String("entry"),
+			AssetId:        aws.String("asset"),
+			PropertyId:     aws.String("property"),
+			AggregateTypes: []iotsitewisetypes.AggregateType{iotsitewisetypes.AggregateTypeAverage},
+			Resolution:     aws.String("1m"),
+			StartDate:      aws.Time(time.Unix(0, 0)),
+			EndDate:        aws.Time(time.Unix(3600, 0)),
+		}},
+	}, 3, 1000)
+	require.NoError(t, err)
+	require.Equal(t, int32(3), calls.Load())
+	require.Len(t, resp.SuccessEntries[0].AggregatedValues, 3)
+	require.Equal(t, "next", *resp.NextToken)
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"reflect"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	"github.com/aws/smithy-go/middleware"
+
+	dserrors "github.com/grafana/iot-sitewise-datasource/pkg/errors"
+)
+
+// WithErrorResources annotates the errors of a client with the asset, property or alias of the
+// request, so that the message of a failed query names what the user has to fix
+func WithErrorResources() func(*iotsitewise.Options) {
+	return func(o *iotsitewise.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(&errorResourceMiddleware{}, middleware.After)
+		})
+	}
+}
+
+type errorResourceMiddleware struct{}
+
+func (m *errorResourceMiddleware) ID() string {
+	return "SitewiseErrorResourceMiddleware"
+}
+
+func (m *errorResourceMiddleware) HandleInitialize(
+	ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+) (
+	out middleware.InitializeOutput, metadata middleware.Metadata, err error,
+) {
+	out, metadata, err = next.HandleInitialize(ctx, in)
+	if err != nil {
+		err = &dserrors.APIError{
+			Operation: awsmiddleware.GetOperationName(ctx),
+			Resource:  requestResource(in.Parameters),
+			Err:       err,
+		}
+	}
+	return out, metadata, err
+}
+
+// requestResource returns the resource of the input of a call
+func requestResource(params any) dserrors.Resource {
+	resource := dserrors.Resource{
+		AssetId:      stringField(params, "AssetId"),
+		PropertyId:   stringField(params, "PropertyId"),
+		Alias:        stringField(params, "PropertyAlias"),
+		AssetModelId: stringField(params, "AssetModelId"),
+	}
+	if resource.Alias == "" {
+		resource.Alias = stringField(params, "Alias")
+	}
+	if entries, ok := batchEntries(params); ok {
+		resource.Entries = entries
+	}
+	return resource
+}
+
+// stringField returns the value of a *string field of an input, if it is set
+func stringField(params any, name string) string {
+	v := reflect.ValueOf(params)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ""
+	}
+	field := v.Elem().FieldByName(name)
+	if !field.IsValid() || field.Kind() != reflect.Pointer || field.IsNil() || field.Elem().Kind() != reflect.String {
+		return ""
+	}
+	return field.Elem().String()
+}
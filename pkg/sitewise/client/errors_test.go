@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+
+	dserrors "github.com/grafana/iot-sitewise-datasource/pkg/errors"
+)
+
+func TestErrorResourcesNameTheResourceOfTheRequest(t *testing.T) {
+	sw, _, _ := newThrottlingClient(t, 100, WithErrorResources())
+	input := historyInput()
+	input.NextToken = aws.String("page-2")
+
+	_, err := sw.GetAssetPropertyValueHistory(context.Background(), input)
+
+	var apiErr *dserrors.APIError
+	require.True(t, errors.As(err, &apiErr))
+	require.Equal(t, "GetAssetPropertyValueHistory", apiErr.Operation)
+	require.Equal(t, dserrors.Resource{AssetId: "asset", PropertyId: "property"}, apiErr.Resource)
+	// the original error is still reachable
+	require.True(t, IsThrottling(err))
+	require.Equal(t, dserrors.KindThrottled, dserrors.KindOf(err))
+	require.EqualError(t, dserrors.Wrap(err), `GetAssetPropertyValueHistory for asset "asset", property "property" was throttled by SiteWise: try again later or lower the request rate`)
+}
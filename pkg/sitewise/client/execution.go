@@ -0,0 +1,322 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// WithExecutionStats records every call of a client in the Execution of the context of the call
+func WithExecutionStats() func(*iotsitewise.Options) {
+	return func(o *iotsitewise.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(&executionMiddleware{}, middleware.After)
+		})
+	}
+}
+
+type executionMiddleware struct{}
+
+func (m *executionMiddleware) ID() string {
+	return "SitewiseExecutionMiddleware"
+}
+
+func (m *executionMiddleware) HandleInitialize(
+	ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+) (
+	out middleware.InitializeOutput, metadata middleware.Metadata, err error,
+) {
+	if e := ExecutionFrom(ctx); e != nil {
+		e.call(awsmiddleware.GetOperationName(ctx), in.Parameters)
+	}
+	return next.HandleInitialize(ctx, in)
+}
+
+type executionKey struct{}
+
+type phase struct {
+	name     string
+	duration time.Duration
+}
+
+// Execution records how a query was run: the SiteWise calls it made, the pages fetched for each
+// entry, the effective resolution and quality of its requests, and the time spent in each phase.
+// It is shown in the query inspector, to explain a query without turning on debug logs.
+type Execution struct {
+	mu         sync.Mutex
+	mark       time.Time
+	operations map[string]int
+	pages      map[string]int
+	resolution string
+	quality    string
+	sql        string
+	phases     []phase
+	shared     bool
+}
+
+// WithExecution returns a context that records the execution of the requests made with it
+func WithExecution(ctx context.Context) (context.Context, *Execution) {
+	e := &Execution{
+		mark:       time.Now(),
+		operations: map[string]int{},
+		pages:      map[string]int{},
+	}
+	return context.WithValue(ctx, executionKey{}, e), e
+}
+
+// ExecutionFrom returns the Execution of a context, or nil. The methods of a nil Execution do nothing.
+func ExecutionFrom(ctx context.Context) *Execution {
+	e, _ := ctx.Value(executionKey{}).(*Execution)
+	return e
+}
+
+// Phase records the time since the previous phase ended, or since the execution started
+func (e *Execution) Phase(name string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := time.Now()
+	e.phases = append(e.phases, phase{name: name, duration: now.Sub(e.mark)})
+	e.mark = now
+}
+
+// SetSQL records the statement of an ExecuteQuery query, after its macros were interpolated
+func (e *Execution) SetSQL(sql string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sql = sql
+}
+
+// Merge adds the calls of an execution shared with other queries, such as a batch plan
+func (e *Execution) Merge(shared *Execution) {
+	if e == nil || shared == nil {
+		return
+	}
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for operation, calls := range shared.operations {
+		e.operations[operation] += calls
+	}
+	for entryId, pages := range shared.pages {
+		e.pages[entryId] += pages
+	}
+	if e.resolution == "" {
+		e.resolution = shared.resolution
+	}
+	if e.quality == "" {
+		e.quality = shared.quality
+	}
+	// the shared execution frames each of its queries in turn, its phases are summed by name
+	index := map[string]int{}
+	for _, p := range shared.phases {
+		name := "batch " + p.name
+		if i, ok := index[name]; ok {
+			e.phases[i].duration += p.duration
+			continue
+		}
+		index[name] = len(e.phases)
+		e.phases = append(e.phases, phase{name: name, duration: p.duration})
+	}
+	e.shared = true
+}
+
+// Pages returns the pages fetched for an entry, zero if none named it
+func (e *Execution) Pages(entryId string) int {
+	if e == nil {
+		return 0
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pages[entryId]
+}
+
+// call records a call of an operation, and the entries, resolution and quality of its input
+func (e *Execution) call(operation string, params any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.operations[operation]++
+	for _, entryId := range requestEntryIds(params) {
+		e.pages[entryId]++
+	}
+	if resolution := requestResolution(params); resolution != "" {
+		e.resolution = resolution
+	}
+	if quality := requestQuality(params); quality != "" {
+		e.quality = quality
+	}
+}
+
+// Annotate adds the execution to the Stats and ExecutedQueryString of the meta of each frame. The
+// pages fetched are those of the entry of a frame, or of the whole query for frames of no entry.
+// Frames of queries that made no SiteWise calls, such as those served from the metadata cache,
+// are left as they are.
+func (e *Execution) Annotate(frames data.Frames) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.operations) == 0 {
+		return
+	}
+
+	operations := slices.Sorted(maps.Keys(e.operations))
+	totalPages := 0
+	for _, calls := range e.operations {
+		totalPages += calls
+	}
+
+	for _, frame := range frames {
+		if frame == nil {
+			continue
+		}
+		if frame.Meta == nil {
+			frame.Meta = &data.FrameMeta{}
+		}
+		custom, _ := frame.Meta.Custom.(models.SitewiseCustomMeta)
+		pages, pagesOf := totalPages, "query"
+		if entryPages, ok := e.pages[custom.EntryId]; ok && custom.EntryId != "" {
+			pages, pagesOf = entryPages, "entry "+custom.EntryId
+		}
+		resolution := e.resolution
+		if resolution == "" {
+			resolution = custom.Resolution
+		}
+
+		var lines []string
+		calls := make([]string, 0, len(operations))
+		for _, operation := range operations {
+			frame.Meta.Stats = append(frame.Meta.Stats, data.QueryStat{
+				FieldConfig: data.FieldConfig{DisplayName: operation + " calls"},
+				Value:       float64(e.operations[operation]),
+			})
+			calls = append(calls, fmt.Sprintf("%s x%d", operation, e.operations[operation]))
+		}
+		lines = append(lines, "Operations: "+strings.Join(calls, ", "))
+		if e.shared {
+			lines = append(lines, "The calls were shared with the other queries of the request")
+		}
+
+		frame.Meta.Stats = append(frame.Meta.Stats, data.QueryStat{
+			FieldConfig: data.FieldConfig{DisplayName: "Pages fetched"},
+			Value:       float64(pages),
+		})
+		lines = append(lines, fmt.Sprintf("Pages fetched: %d for the %s", pages, pagesOf))
+		if resolution != "" {
+			lines = append(lines, "Resolution: "+resolution)
+		}
+		if e.quality != "" {
+			lines = append(lines, "Quality: "+e.quality)
+		}
+
+		timings := make([]string, 0, len(e.phases))
+		for _, p := range e.phases {
+			frame.Meta.Stats = append(frame.Meta.Stats, data.QueryStat{
+				FieldConfig: data.FieldConfig{DisplayName: "Phase " + p.name, Unit: "ms"},
+				Value:       float64(p.duration.Microseconds()) / 1000,
+			})
+			timings = append(timings, fmt.Sprintf("%s %s", p.name, p.duration.Round(time.Microsecond)))
+		}
+		if len(timings) > 0 {
+			lines = append(lines, "Phases: "+strings.Join(timings, ", "))
+		}
+		if e.sql != "" {
+			lines = append(lines, "SQL: "+e.sql)
+		}
+		frame.Meta.ExecutedQueryString = strings.Join(lines, "\n")
+	}
+}
+
+// requestEntryIds returns the entry ids of the input of a call, the same ids the framers give
+// the frames of the entries
+func requestEntryIds(params any) []string {
+	v := reflect.ValueOf(params)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	if entries := v.Elem().FieldByName("Entries"); entries.IsValid() && entries.Kind() == reflect.Slice {
+		ids := make([]string, 0, entries.Len())
+		for i := range entries.Len() {
+			if id := stringField(entries.Index(i).Addr().Interface(), "EntryId"); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	assetId, propertyId := stringField(params, "AssetId"), stringField(params, "PropertyId")
+	switch alias := stringField(params, "PropertyAlias"); {
+	case assetId != "" && propertyId != "":
+		return []string{*util.GetEntryIdFromAssetProperty(assetId, propertyId)}
+	case alias != "":
+		return []string{*util.GetEntryIdFromPropertyAlias(alias)}
+	}
+	return nil
+}
+
+// requestResolution returns the resolution of an aggregates input, or the interval of an
+// interpolated input
+func requestResolution(params any) string {
+	switch p := params.(type) {
+	case *iotsitewise.GetAssetPropertyAggregatesInput:
+		return stringField(p, "Resolution")
+	case *iotsitewise.BatchGetAssetPropertyAggregatesInput:
+		if len(p.Entries) > 0 {
+			return stringField(&p.Entries[0], "Resolution")
+		}
+	case *iotsitewise.GetInterpolatedAssetPropertyValuesInput:
+		if p.IntervalInSeconds != nil {
+			return (time.Duration(*p.IntervalInSeconds) * time.Second).String()
+		}
+	}
+	return ""
+}
+
+// requestQuality returns the qualities a data input filters by
+func requestQuality(params any) string {
+	switch p := params.(type) {
+	case *iotsitewise.GetAssetPropertyValueHistoryInput:
+		return joinQualities(p.Qualities)
+	case *iotsitewise.GetAssetPropertyAggregatesInput:
+		return joinQualities(p.Qualities)
+	case *iotsitewise.GetInterpolatedAssetPropertyValuesInput:
+		return string(p.Quality)
+	case *iotsitewise.BatchGetAssetPropertyValueHistoryInput:
+		if len(p.Entries) > 0 {
+			return joinQualities(p.Entries[0].Qualities)
+		}
+	case *iotsitewise.BatchGetAssetPropertyAggregatesInput:
+		if len(p.Entries) > 0 {
+			return joinQualities(p.Entries[0].Qualities)
+		}
+	}
+	return ""
+}
+
+func joinQualities[Q ~string](qualities []Q) string {
+	names := make([]string, len(qualities))
+	for i, quality := range qualities {
+		names[i] = string(quality)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+func statValues(meta *data.FrameMeta) map[string]float64 {
+	values := map[string]float64{}
+	for _, stat := range meta.Stats {
+		values[stat.DisplayName] = stat.Value
+	}
+	return values
+}
+
+func TestExecutionRecordsTheCallsOfAQuery(t *testing.T) {
+	sw, _, _ := newThrottlingClient(t, 0, WithExecutionStats())
+	ctx, execution := WithExecution(context.Background())
+
+	input := historyInput()
+	input.Qualities = []iotsitewisetypes.Quality{iotsitewisetypes.QualityGood}
+	_, err := sw.GetAssetPropertyValueHistoryPageAggregation(ctx, input, 10, 100)
+	require.NoError(t, err)
+	execution.Phase("fetch")
+	execution.SetSQL("SELECT 1")
+
+	entryId := *util.GetEntryIdFromAssetProperty("asset", "property")
+	frames := data.Frames{
+		data.NewFrame("entry").SetMeta(&data.FrameMeta{Custom: models.SitewiseCustomMeta{EntryId: entryId, Resolution: "RAW"}}),
+		data.NewFrame("other"),
+	}
+	execution.Annotate(frames)
+
+	stats := statValues(frames[0].Meta)
+	require.Equal(t, float64(2), stats["GetAssetPropertyValueHistory calls"])
+	require.Equal(t, float64(2), stats["Pages fetched"])
+	require.Contains(t, stats, "Phase fetch")
+	require.Contains(t, frames[0].Meta.ExecutedQueryString, "Operations: GetAssetPropertyValueHistory x2")
+	require.Contains(t, frames[0].Meta.ExecutedQueryString, "Pages fetched: 2 for the entry "+entryId)
+	require.Contains(t, frames[0].Meta.ExecutedQueryString, "Resolution: RAW")
+	require.Contains(t, frames[0].Meta.ExecutedQueryString, "Quality: GOOD")
+	require.Contains(t, frames[0].Meta.ExecutedQueryString, "SQL: SELECT 1")
+	require.Contains(t, frames[1].Meta.ExecutedQueryString, "Pages fetched: 2 for the query")
+}
+
+func TestExecutionLeavesFramesOfQueriesWithoutCalls(t *testing.T) {
+	_, execution := WithExecution(context.Background())
+	execution.Phase("fetch")
+
+	frames := data.Frames{data.NewFrame("cached")}
+	execution.Annotate(frames)
+	require.Nil(t, frames[0].Meta)
+
+	// a context without an execution records nothing
+	ExecutionFrom(context.Background()).Phase("fetch")
+}
+
+func TestExecutionMergesASharedBatch(t *testing.T) {
+	_, shared := WithExecution(context.Background())
+	shared.call("BatchGetAssetPropertyAggregates", &iotsitewise.BatchGetAssetPropertyAggregatesInput{
+		Entries: []iotsitewisetypes.BatchGetAssetPropertyAggregatesEntry{
+			{EntryId: aws.String("a"), Resolution: aws.String("1m"), Qualities: []iotsitewisetypes.Quality{iotsitewisetypes.QualityUncertain}},
+			{EntryId: aws.String("b"), Resolution: aws.String("1m")},
+		},
+	})
+	shared.Phase("fetch")
+	shared.Phase("frame")
+	shared.Phase("fetch")
+	shared.Phase("frame")
+
+	_, execution := WithExecution(context.Background())
+	execution.Merge(shared)
+	frames := data.Frames{data.NewFrame("b").SetMeta(&data.FrameMeta{Custom: models.SitewiseCustomMeta{EntryId: "b"}})}
+	execution.Annotate(frames)
+
+	stats := statValues(frames[0].Meta)
+	require.Equal(t, float64(1), stats["Pages fetched"])
+	require.Contains(t, stats, "Phase batch fetch")
+	require.Contains(t, stats, "Phase batch frame")
+	require.Len(t, frames[0].Meta.Stats, 4)
+	require.Contains(t, frames[0].Meta.ExecutedQueryString, "shared with the other queries")
+	require.Contains(t, frames[0].Meta.ExecutedQueryString, "Resolution: 1m")
+	require.Contains(t, frames[0].Meta.ExecutedQueryString, "Quality: UNCERTAIN")
+}
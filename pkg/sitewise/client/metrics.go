@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/metrics"
+)
+
+// WithMetrics records the calls, latency, batch sizes, throttles and errors of a client
+func WithMetrics(labels metrics.Labels) func(*iotsitewise.Options) {
+	return func(o *iotsitewise.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			if err := stack.Initialize.Add(&callMetricsMiddleware{labels: labels}, middleware.After); err != nil {
+				return err
+			}
+			return stack.Finalize.Add(&attemptMetricsMiddleware{labels: labels}, middleware.After)
+		})
+	}
+}
+
+// callMetricsMiddleware records each call once, whatever the number of attempts
+type callMetricsMiddleware struct {
+	labels metrics.Labels
+}
+
+func (m *callMetricsMiddleware) ID() string {
+	return "SitewiseCallMetricsMiddleware"
+}
+
+func (m *callMetricsMiddleware) HandleInitialize(
+	ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+) (
+	out middleware.InitializeOutput, metadata middleware.Metadata, err error,
+) {
+	operation := awsmiddleware.GetOperationName(ctx)
+	m.labels.APICall(operation)
+	if entries, ok := batchEntries(in.Parameters); ok {
+		m.labels.BatchEntries(operation, entries)
+	}
+
+	start := time.Now()
+	out, metadata, err = next.HandleInitialize(ctx, in)
+	m.labels.APICallDuration(operation, time.Since(start).Seconds())
+	if err != nil {
+		m.labels.APIError(operation, errorCode(err))
+	}
+	return out, metadata, err
+}
+
+// attemptMetricsMiddleware records the throttled attempts of a call
+type attemptMetricsMiddleware struct {
+	labels metrics.Labels
+}
+
+func (m *attemptMetricsMiddleware) ID() string {
+	return "SitewiseAttemptMetricsMiddleware"
+}
+
+func (m *attemptMetricsMiddleware) HandleFinalize(
+	ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler,
+) (
+	out middleware.FinalizeOutput, metadata middleware.Metadata, err error,
+) {
+	out, metadata, err = next.HandleFinalize(ctx, in)
+	if IsThrottling(err) {
+		m.labels.APIThrottle(awsmiddleware.GetOperationName(ctx))
+	}
+	return out, metadata, err
+}
+
+func batchEntries(params any) (int, bool) {
+	switch p := params.(type) {
+	case *iotsitewise.BatchGetAssetPropertyValueInput:
+		return len(p.Entries), true
+	case *iotsitewise.BatchGetAssetPropertyValueHistoryInput:
+		return len(p.Entries), true
+	case *iotsitewise.BatchGetAssetPropertyAggregatesInput:
+		return len(p.Entries), true
+	default:
+		return 0, false
+	}
+}
+
+// errorCode returns the AWS error code of an error
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.ErrorCode()
+	case errors.Is(err, context.Canceled):
+		return "Canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "DeadlineExceeded"
+	default:
+		return "Unknown"
+	}
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	labels := metrics.Labels{DatasourceUID: "metrics-test", Region: "us-east-1"}
+	sw, _, _ := newThrottlingClient(t, 1, WithMetrics(labels))
+	sw.Metrics = labels
+
+	_, err := sw.GetAssetPropertyValueHistoryPageAggregation(context.Background(), historyInput(), 10, 100)
+	require.NoError(t, err)
+
+	const operation = "GetAssetPropertyValueHistory"
+	require.Equal(t, float64(3), testutil.ToFloat64(metrics.APICalls.WithLabelValues("metrics-test", "us-east-1", operation)))
+	require.Equal(t, float64(2), testutil.ToFloat64(metrics.PagesFetched.WithLabelValues("metrics-test", "us-east-1", operation)))
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.APIThrottles.WithLabelValues("metrics-test", "us-east-1", operation)))
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.APIErrors.WithLabelValues("metrics-test", "us-east-1", operation, "ThrottlingException")))
+}
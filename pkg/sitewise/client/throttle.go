@@ -0,0 +1,287 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Defaults for retrying throttled pages
+const (
+	DefaultThrottleMaxRetries = 3
+	DefaultThrottleBaseDelay  = 200 * time.Millisecond
+	DefaultThrottleMaxDelay   = 5 * time.Second
+)
+
+// IsThrottling reports whether SiteWise rejected a request because of its rate limits
+func IsThrottling(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "ThrottlingException", "TooManyRequestsException":
+			return true
+		}
+	}
+	var respErr *smithyhttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusTooManyRequests
+}
+
+// AdaptiveRateLimiter is a token bucket that halves its rate whenever a request is throttled
+// and recovers gradually with every successful request, up to its limit
+type AdaptiveRateLimiter struct {
+	mu     sync.Mutex
+	limit  float64
+	rate   float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func NewAdaptiveRateLimiter(limit float64) *AdaptiveRateLimiter {
+	return &AdaptiveRateLimiter{
+		limit:  limit,
+		rate:   limit,
+		tokens: max(limit, 1),
+		now:    time.Now,
+	}
+}
+
+// Wait blocks until a request may be sent
+func (l *AdaptiveRateLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		l.refill()
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *AdaptiveRateLimiter) refill() {
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, max(l.rate, 1))
+	}
+	l.last = now
+}
+
+// Throttled halves the rate, down to one request per second
+func (l *AdaptiveRateLimiter) Throttled() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.rate = max(l.rate/2, 1)
+	l.tokens = min(l.tokens, max(l.rate, 1))
+}
+
+// Succeeded raises the rate by a twentieth of the limit
+func (l *AdaptiveRateLimiter) Succeeded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = min(l.rate+l.limit/20, l.limit)
+}
+
+// Rate returns the current requests per second
+func (l *AdaptiveRateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// RateLimiters are the limiters of an account and region. Requests for property values and
+// requests for metadata have separate budgets, so that browsing assets keeps working while
+// dashboards load data.
+type RateLimiters struct {
+	DataPlane    *AdaptiveRateLimiter
+	ControlPlane *AdaptiveRateLimiter
+}
+
+var (
+	sharedRateLimitersMu sync.Mutex
+	sharedRateLimiters   = map[string]*RateLimiters{}
+)
+
+// SharedRateLimiters returns the limiters of an account and region, shared by every datasource
+// instance of the process that makes requests with the account, since SiteWise applies its
+// quotas per account and region. The limits of the datasource that first asks for them are kept.
+func SharedRateLimiters(account string, region string, dataPlaneLimit float64, controlPlaneLimit float64) *RateLimiters {
+	sharedRateLimitersMu.Lock()
+	defer sharedRateLimitersMu.Unlock()
+
+	key := account + "|" + region
+	limiters, ok := sharedRateLimiters[key]
+	if !ok {
+		limiters = NewRateLimiters(dataPlaneLimit, controlPlaneLimit)
+		sharedRateLimiters[key] = limiters
+	}
+	return limiters
+}
+
+func NewRateLimiters(dataPlaneLimit float64, controlPlaneLimit float64) *RateLimiters {
+	return &RateLimiters{
+		DataPlane:    NewAdaptiveRateLimiter(dataPlaneLimit),
+		ControlPlane: NewAdaptiveRateLimiter(controlPlaneLimit),
+	}
+}
+
+// For returns the limiter of an operation
+func (r *RateLimiters) For(operation string) *AdaptiveRateLimiter {
+	if strings.HasPrefix(operation, "Get") || strings.HasPrefix(operation, "BatchGet") || operation == "ExecuteQuery" {
+		return r.DataPlane
+	}
+	return r.ControlPlane
+}
+
+// WithRateLimiters limits every attempt of the requests of a client
+func WithRateLimiters(limiters *RateLimiters) func(*iotsitewise.Options) {
+	return func(o *iotsitewise.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			// after the retry middleware, so that retried attempts wait as well
+			return stack.Finalize.Add(&rateLimitMiddleware{limiters: limiters}, middleware.After)
+		})
+	}
+}
+
+type rateLimitMiddleware struct {
+	limiters *RateLimiters
+}
+
+func (m *rateLimitMiddleware) ID() string {
+	return "SitewiseRateLimitMiddleware"
+}
+
+func (m *rateLimitMiddleware) HandleFinalize(
+	ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler,
+) (
+	out middleware.FinalizeOutput, metadata middleware.Metadata, err error,
+) {
+	limiter := m.limiters.For(awsmiddleware.GetOperationName(ctx))
+	if err := limiter.Wait(ctx); err != nil {
+		return out, metadata, err
+	}
+
+	out, metadata, err = next.HandleFinalize(ctx, in)
+	if IsThrottling(err) {
+		limiter.Throttled()
+	} else if err == nil {
+		limiter.Succeeded()
+	}
+	return out, metadata, err
+}
+
+// ThrottleRetry is how throttled pages are retried while paginating. Zero values fall back
+// to the defaults.
+type ThrottleRetry struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// backoff returns a full jitter delay for a retry attempt
+func (r ThrottleRetry) backoff(attempt int) time.Duration {
+	base, maxDelay := r.BaseDelay, r.MaxDelay
+	if base <= 0 {
+		base = DefaultThrottleBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultThrottleMaxDelay
+	}
+	delay := min(base<<attempt, maxDelay)
+	return rand.N(delay) + 1
+}
+
+func (r ThrottleRetry) maxRetries() int {
+	if r.MaxRetries > 0 {
+		return r.MaxRetries
+	}
+	return DefaultThrottleMaxRetries
+}
+
+// nextPage requests a page, retrying it with jittered backoff while it is throttled. The retryer
+// of the SDK does not retry the throttled attempts of the page on top of that.
+func nextPage[T any](ctx context.Context, retry ThrottleRetry, fetch func(ctx context.Context, optFns ...func(*iotsitewise.Options)) (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		page, err := fetch(ctx, withoutThrottleRetries)
+		if err == nil || !IsThrottling(err) || attempt >= retry.maxRetries() {
+			return page, err
+		}
+
+		timer := time.NewTimer(retry.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return page, err
+		case <-timer.C:
+		}
+	}
+}
+
+// throttleRetryer is the retryer of the SDK, except that it leaves throttled requests to nextPage
+type throttleRetryer struct {
+	aws.RetryerV2
+}
+
+func (r throttleRetryer) IsErrorRetryable(err error) bool {
+	return !IsThrottling(err) && r.RetryerV2.IsErrorRetryable(err)
+}
+
+func withoutThrottleRetries(o *iotsitewise.Options) {
+	if retryer, ok := o.Retryer.(aws.RetryerV2); ok {
+		o.Retryer = throttleRetryer{retryer}
+	}
+}
+
+type partialResultsKey struct{}
+
+// PartialResults collects why the requests of a query returned partial data
+type PartialResults struct {
+	mu      sync.Mutex
+	notices []string
+}
+
+// WithPartialResults returns a context that collects the partial results of the requests made with it
+func WithPartialResults(ctx context.Context) (context.Context, *PartialResults) {
+	partial := &PartialResults{}
+	return context.WithValue(ctx, partialResultsKey{}, partial), partial
+}
+
+func (p *PartialResults) Notices() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.notices...)
+}
+
+// reportThrottledPage records that pagination stopped early because a page stayed throttled
+func reportThrottledPage(ctx context.Context, operation string, err error) {
+	partial, ok := ctx.Value(partialResultsKey{}).(*PartialResults)
+	if !ok {
+		return
+	}
+	partial.mu.Lock()
+	defer partial.mu.Unlock()
+	partial.notices = append(partial.notices, fmt.Sprintf("Results are incomplete: %s was throttled (%s), use the next token to load the rest", operation, err.Error()))
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewAdaptiveRateLimiter(10)
+	l.now = func() time.Time { return now }
+
+	l.Throttled()
+	require.Equal(t, float64(5), l.Rate())
+	l.Throttled()
+	l.Throttled()
+	l.Throttled()
+	l.Throttled()
+	require.Equal(t, float64(1), l.Rate())
+
+	for range 30 {
+		l.Succeeded()
+	}
+	require.Equal(t, float64(10), l.Rate())
+
+	limiters := NewRateLimiters(10, 5)
+	require.Same(t, limiters.DataPlane, limiters.For("BatchGetAssetPropertyValueHistory"))
+	require.Same(t, limiters.DataPlane, limiters.For("GetInterpolatedAssetPropertyValues"))
+	require.Same(t, limiters.DataPlane, limiters.For("ExecuteQuery"))
+	require.Same(t, limiters.ControlPlane, limiters.For("DescribeAsset"))
+	require.Same(t, limiters.ControlPlane, limiters.For("ListAssets"))
+}
+
+// newThrottlingClient returns a client of a server that returns a first page and then throttles
+// the requests for the next page throttledPages times
+func newThrottlingClient(t *testing.T, throttledPages int32, optFns ...func(*iotsitewise.Options)) (*SitewiseClient, *RateLimiters, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Query().Get("nextToken") == "":
+			_, _ = w.Write([]byte(`{"assetPropertyValueHistory":[{"value":{"doubleValue":1},"timestamp":{"timeInSeconds":1}}],"nextToken":"page-2"}`))
+		case call <= throttledPages+1:
+			w.Header().Set("X-Amzn-ErrorType", "ThrottlingException")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message":"Rate exceeded"}`))
+		default:
+			_, _ = w.Write([]byte(`{"assetPropertyValueHistory":[{"value":{"doubleValue":2},"timestamp":{"timeInSeconds":2}}]}`))
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	limiters := NewRateLimiters(1000, 1000)
+	return &SitewiseClient{
+		Client: newServerClient(ts.URL, append([]func(*iotsitewise.Options){WithRateLimiters(limiters)}, optFns...)...),
+		Retry:  ThrottleRetry{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	}, limiters, &calls
+}
+
+// newServerClient returns a SiteWise client of a test server, without the host prefixes of the
+// data plane APIs
+func newServerClient(url string, optFns ...func(*iotsitewise.Options)) *iotsitewise.Client {
+	optFns = append([]func(*iotsitewise.Options){func(o *iotsitewise.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("HostnameImmutable", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				return next.HandleInitialize(smithyhttp.SetHostnameImmutable(ctx, true), in)
+			}), middleware.Before)
+		})
+	}}, optFns...)
+	return iotsitewise.New(iotsitewise.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(url),
+		Credentials:      credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		RetryMaxAttempts: 1,
+	}, optFns...)
+}
+
+func historyInput() *iotsitewise.GetAssetPropertyValueHistoryInput {
+	return &iotsitewise.GetAssetPropertyValueHistoryInput{
+		AssetId:    aws.String("asset"),
+		PropertyId: aws.String("property"),
+	}
+}
+
+func TestPageAggregationRetriesThrottledPages(t *testing.T) {
+	sw, _, calls := newThrottlingClient(t, 2)
+	ctx, partial := WithPartialResults(context.Background())
+
+	resp, err := sw.GetAssetPropertyValueHistoryPageAggregation(ctx, historyInput(), 10, 100)
+	require.NoError(t, err)
+	require.Len(t, resp.AssetPropertyValueHistory, 2)
+	require.Nil(t, resp.NextToken)
+	require.Empty(t, partial.Notices())
+	require.Equal(t, int32(4), calls.Load())
+}
+
+func TestPageAggregationRetriesThrottledPagesWithoutTheSDKRetryer(t *testing.T) {
+	sw, _, calls := newThrottlingClient(t, 100, func(o *iotsitewise.Options) {
+		o.RetryMaxAttempts = 3
+	})
+
+	_, err := sw.GetAssetPropertyValueHistoryPageAggregation(context.Background(), historyInput(), 10, 100)
+	require.NoError(t, err)
+	// the first page, and the next page with its two retries
+	require.Equal(t, int32(4), calls.Load())
+}
+
+func TestPageAggregationReturnsPartialResultsWhenThrottled(t *testing.T) {
+	sw, limiters, _ := newThrottlingClient(t, 100)
+	ctx, partial := WithPartialResults(context.Background())
+
+	resp, err := sw.GetAssetPropertyValueHistoryPageAggregation(ctx, historyInput(), 10, 100)
+	require.NoError(t, err)
+	require.Len(t, resp.AssetPropertyValueHistory, 1)
+	require.Equal(t, "page-2", *resp.NextToken)
+	require.Len(t, partial.Notices(), 1)
+	require.Contains(t, partial.Notices()[0], "GetAssetPropertyValueHistory was throttled")
+	// the data plane slowed down, the control plane did not
+	require.Less(t, limiters.DataPlane.Rate(), float64(1000))
+	require.Equal(t, float64(1000), limiters.ControlPlane.Rate())
+}
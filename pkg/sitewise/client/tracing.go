@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"reflect"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// WithTracing starts a span for every call of a client, so that each page of a paginated
+// request shows up in the trace of its query
+func WithTracing() func(*iotsitewise.Options) {
+	return func(o *iotsitewise.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(&tracingMiddleware{}, middleware.After)
+		})
+	}
+}
+
+type tracingMiddleware struct{}
+
+func (m *tracingMiddleware) ID() string {
+	return "SitewiseTracingMiddleware"
+}
+
+func (m *tracingMiddleware) HandleInitialize(
+	ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+) (
+	out middleware.InitializeOutput, metadata middleware.Metadata, err error,
+) {
+	operation := awsmiddleware.GetOperationName(ctx)
+	attrs := []attribute.KeyValue{
+		attribute.String("sitewise.operation", operation),
+		attribute.Bool("sitewise.next_token", hasNextToken(in.Parameters)),
+	}
+	if entries, ok := batchEntries(in.Parameters); ok {
+		attrs = append(attrs, attribute.Int("sitewise.entries", entries))
+	}
+
+	ctx, span := util.StartSpan(ctx, "sitewise."+operation, attrs...)
+	defer func() { util.EndSpan(span, err) }()
+	return next.HandleInitialize(ctx, in)
+}
+
+// hasNextToken reports whether the input of a call continues a previous page
+func hasNextToken(params any) bool {
+	v := reflect.ValueOf(params)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	token := v.Elem().FieldByName("NextToken")
+	return token.IsValid() && token.Kind() == reflect.Pointer && !token.IsNil()
+}
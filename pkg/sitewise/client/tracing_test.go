@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingStartsASpanPerPage(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracing.InitDefaultTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test"))
+	t.Cleanup(func() { tracing.InitDefaultTracer(nil) })
+
+	sw, _, _ := newThrottlingClient(t, 0, WithTracing())
+	_, err := sw.GetAssetPropertyValueHistoryPageAggregation(context.Background(), historyInput(), 10, 100)
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	for i, span := range spans {
+		require.Equal(t, "sitewise.GetAssetPropertyValueHistory", span.Name())
+		require.Contains(t, span.Attributes(), attribute.Bool("sitewise.next_token", i > 0))
+	}
+}
@@ -3,11 +3,15 @@ package sitewise
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
 
 	"github.com/aws/smithy-go/middleware"
 	smithyhttp "github.com/aws/smithy-go/transport/http"
@@ -15,10 +19,14 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/proxy"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	dserrors "github.com/grafana/iot-sitewise-datasource/pkg/errors"
+	"github.com/grafana/iot-sitewise-datasource/pkg/metrics"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/resource"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/framer"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 
 	"github.com/pkg/errors"
 )
@@ -29,10 +37,24 @@ type clientGetterFunc func(ctx context.Context, region string) (client.SitewiseA
 type invokerFunc func(ctx context.Context, sw client.SitewiseAPIClient) (framer.Framer, error)
 
 type Datasource struct {
+	UID               string
 	Cfg               models.AWSSiteWiseDataSourceSetting
 	edgeAuthenticator *EdgeAuthenticator
 	proxyOptions      *proxy.Options
 	GetClient         clientGetterFunc
+
+	metadataCacheOnce sync.Once
+	metadataCache     *resource.MetadataCache
+
+	clientsMu  sync.Mutex
+	clients    map[string]pooledClient
+	httpClient *http.Client
+}
+
+// pooledClient is the client of a region, built with the edge credentials of a generation
+type pooledClient struct {
+	client     client.SitewiseAPIClient
+	generation uint64
 }
 
 type disableHostPrefixMiddleware struct{}
@@ -50,6 +72,68 @@ func (m *disableHostPrefixMiddleware) HandleInitialize(
 	return next.HandleInitialize(ctx, in)
 }
 
+// edgeAuthRetryMiddleware retries a request once with renewed credentials when the
+// edge gateway rejects the current ones
+type edgeAuthRetryMiddleware struct {
+	authenticator *EdgeAuthenticator
+}
+
+func (m *edgeAuthRetryMiddleware) ID() string {
+	return "EdgeAuthRetryMiddleware"
+}
+
+func (m *edgeAuthRetryMiddleware) HandleFinalize(
+	ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler,
+) (
+	out middleware.FinalizeOutput, metadata middleware.Metadata, err error,
+) {
+	generation := m.authenticator.Generation()
+	out, metadata, err = next.HandleFinalize(ctx, in)
+	if !isAuthFailure(err) {
+		return out, metadata, err
+	}
+
+	backend.Logger.Debug("edge gateway rejected the credentials, renewing them", "error", err.Error())
+	if refreshErr := m.authenticator.Refresh(ctx, generation); refreshErr != nil {
+		return out, metadata, err
+	}
+	if req, ok := in.Request.(*smithyhttp.Request); ok {
+		if rewindErr := req.RewindStream(); rewindErr != nil {
+			return out, metadata, err
+		}
+	}
+	return next.HandleFinalize(ctx, in)
+}
+
+func isAuthFailure(err error) bool {
+	var respErr *smithyhttp.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	code := respErr.HTTPStatusCode()
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+// edgeCredentialsProvider signs every request with the current credentials of the edge gateway
+type edgeCredentialsProvider struct {
+	authenticator *EdgeAuthenticator
+}
+
+func (p *edgeCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	authInfo, err := p.authenticator.GetAuthInfo(ctx)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	return aws.Credentials{
+		AccessKeyID:     authInfo.AccessKeyId,
+		SecretAccessKey: authInfo.SecretAccessKey,
+		SessionToken:    authInfo.SessionToken,
+		Source:          "SiteWiseEdgeAuthenticator",
+		CanExpire:       true,
+		Expires:         authInfo.SessionExpiryTime,
+	}, nil
+}
+
 func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSettings) (*Datasource, error) {
 	cfg := models.AWSSiteWiseDataSourceSetting{}
 
@@ -68,16 +152,18 @@ func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSetti
 		return nil, err
 	}
 	ds := &Datasource{
+		UID:          settings.UID,
 		Cfg:          cfg,
 		proxyOptions: proxyOptions,
 	}
 
 	if cfg.Region == models.EDGE_REGION && cfg.EdgeAuthMode != models.EDGE_AUTH_MODE_DEFAULT {
 		ds.edgeAuthenticator = &EdgeAuthenticator{
-			Settings: cfg,
+			Settings:      cfg,
+			RenewalMargin: cfg.GetEdgeAuthRenewalMargin(),
 		}
 
-		err := ds.Authenticate()
+		err := ds.Authenticate(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("error getting initial edge credentials (%s)", err.Error())
 		}
@@ -86,69 +172,173 @@ func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSetti
 	return ds, nil
 }
 
-func (ds *Datasource) Authenticate() error {
-	authInfo, err := ds.edgeAuthenticator.GetAuthInfo()
-	if err != nil {
-		return err
+// Authenticate makes sure the edge credentials are valid, renewing them if needed
+func (ds *Datasource) Authenticate(ctx context.Context) error {
+	_, err := ds.edgeAuthenticator.GetAuthInfo(ctx)
+	return err
+}
+
+// EdgeTokenState returns the state of the edge credentials, if the datasource authenticates with the edge gateway
+func (ds *Datasource) EdgeTokenState() (EdgeTokenState, bool) {
+	if ds.edgeAuthenticator == nil {
+		return EdgeTokenState{}, false
+	}
+	return ds.edgeAuthenticator.State(), true
+}
+
+// resolveRegion returns the region of the datasource settings for queries with the default region
+func (ds *Datasource) resolveRegion(region string) string {
+	if region == "" || region == "default" {
+		return ds.Cfg.Region
 	}
-	if authInfo == nil {
-		return nil
+	return region
+}
+
+// getMetadataCache returns the metadata cache of the datasource instance
+func (ds *Datasource) getMetadataCache() *resource.MetadataCache {
+	ds.metadataCacheOnce.Do(func() {
+		ds.metadataCache = resource.NewMetadataCache(ds.Cfg.GetMetadataCacheTTL(), ds.Cfg.GetMetadataCacheMaxEntries())
+		ds.metadataCache.DatasourceUID = ds.UID
+	})
+	return ds.metadataCache
+}
+
+// regionCache returns the view of the metadata cache for the region of a query
+func (ds *Datasource) regionCache(region string) resource.Cache {
+	return ds.getMetadataCache().Region(ds.resolveRegion(region))
+}
+
+// InvalidateMetadataCache drops the cached metadata of a region, or of every region if region is empty
+func (ds *Datasource) InvalidateMetadataCache(region string) {
+	if region != "" {
+		region = ds.resolveRegion(region)
 	}
-	ds.Cfg.AuthType = awsds.AuthTypeKeys
-	ds.Cfg.AccessKey = authInfo.AccessKeyId
-	ds.Cfg.SecretKey = authInfo.SecretAccessKey
-	ds.Cfg.SessionToken = authInfo.SessionToken
-	return nil
+	ds.getMetadataCache().Invalidate(region)
 }
 
 func (ds *Datasource) getClient(ctx context.Context, region string) (client.SitewiseAPIClient, error) {
-	if region == "" || region == "default" {
-		if ds.Cfg.Region == "" {
-			return nil, errors.New("region is not set in datasource settings")
-		}
-		region = ds.Cfg.Region
+	region = ds.resolveRegion(region)
+	if region == "" {
+		return nil, errors.New("region is not set in datasource settings")
 	}
 
+	sw, err := ds.newClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	// property aliases are resolved through the metadata cache
+	return resource.NewTimeSeriesCachingClient(sw, ds.regionCache(region)), nil
+}
+
+// newClient returns the pooled client of a region. Clients are reused across queries and share
+// one HTTP transport; a client is only rebuilt once the edge credentials are renewed. Assumed
+// role credentials are refreshed by the credentials cache of the client itself.
+func (ds *Datasource) newClient(ctx context.Context, region string) (client.SitewiseAPIClient, error) {
 	if ds.GetClient != nil {
 		return ds.GetClient(ctx, region)
 	}
-	if err := ds.Authenticate(); err != nil {
+	authInfo, err := ds.edgeAuthenticator.GetAuthInfo(ctx)
+	if err != nil {
 		return nil, err
 	}
-	httpclient, err := client.GetHTTPClient(ds.Cfg)
+	generation := ds.edgeAuthenticator.Generation()
+
+	ds.clientsMu.Lock()
+	defer ds.clientsMu.Unlock()
+
+	if pooled, ok := ds.clients[region]; ok && pooled.generation == generation {
+		return pooled.client, nil
+	}
+
+	sw, err := ds.buildClient(ctx, region, authInfo)
 	if err != nil {
 		return nil, err
 	}
+	if ds.clients == nil {
+		ds.clients = map[string]pooledClient{}
+	}
+	ds.clients[region] = pooledClient{client: sw, generation: generation}
+	return sw, nil
+}
+
+// buildClient creates the client of a region. It must be called with clientsMu held.
+func (ds *Datasource) buildClient(ctx context.Context, region string, authInfo *models.AuthInfo) (client.SitewiseAPIClient, error) {
+	cfg := ds.Cfg
+	if authInfo != nil {
+		cfg.AuthType = awsds.AuthTypeKeys
+		cfg.AccessKey = authInfo.AccessKeyId
+		cfg.SecretKey = authInfo.SecretAccessKey
+		cfg.SessionToken = authInfo.SessionToken
+	}
+	if ds.httpClient == nil {
+		httpclient, err := client.GetHTTPClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		ds.httpClient = httpclient
+	}
 
 	awsCfg, err := awsauth.NewConfigProvider().GetConfig(ctx, awsauth.Settings{
-		LegacyAuthType:     ds.Cfg.AuthType,
-		AccessKey:          ds.Cfg.AccessKey,
-		SecretKey:          ds.Cfg.SecretKey,
-		SessionToken:       ds.Cfg.SessionToken,
+		LegacyAuthType:     cfg.AuthType,
+		AccessKey:          cfg.AccessKey,
+		SecretKey:          cfg.SecretKey,
+		SessionToken:       cfg.SessionToken,
 		Region:             region,
-		CredentialsProfile: ds.Cfg.Profile,
-		AssumeRoleARN:      ds.Cfg.AssumeRoleARN,
-		Endpoint:           ds.Cfg.Endpoint,
-		ExternalID:         ds.Cfg.ExternalID,
+		CredentialsProfile: cfg.Profile,
+		AssumeRoleARN:      cfg.AssumeRoleARN,
+		Endpoint:           cfg.Endpoint,
+		ExternalID:         cfg.ExternalID,
 		UserAgent:          awsds.GetUserAgentString("grafana-iot-sitewise-datasource"),
-		HTTPClient:         httpclient,
+		HTTPClient:         ds.httpClient,
 		ProxyOptions:       ds.proxyOptions,
 	})
 
 	if err != nil {
 		return nil, err
 	}
+	if ds.edgeAuthenticator != nil {
+		// read the credentials on every request, so that renewed credentials are used right away
+		awsCfg.Credentials = &edgeCredentialsProvider{authenticator: ds.edgeAuthenticator}
+	}
+
+	// the limiters outlive the clients of a region, so that a rebuilt client keeps the adapted rate,
+	// and are shared with the other datasources of the account
+	limiters := client.SharedRateLimiters(ds.rateLimitAccount(), region, ds.Cfg.GetDataPlaneRateLimit(), ds.Cfg.GetControlPlaneRateLimit())
 
-	return &client.SitewiseClient{Client: iotsitewise.NewFromConfig(awsCfg, func(o *iotsitewise.Options) {
+	labels := metrics.Labels{DatasourceUID: ds.UID, Region: region}
+	sw := iotsitewise.NewFromConfig(awsCfg, client.WithRateLimiters(limiters), client.WithMetrics(labels), client.WithTracing(), client.WithErrorResources(), client.WithExecutionStats(), func(o *iotsitewise.Options) {
 		if ds.Cfg.Region == models.EDGE_REGION {
 			o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
 				return stack.Initialize.Add(&disableHostPrefixMiddleware{}, middleware.Before)
 			})
 		}
-	})}, nil
+		if ds.edgeAuthenticator != nil {
+			o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+				return stack.Finalize.Add(&edgeAuthRetryMiddleware{authenticator: ds.edgeAuthenticator}, middleware.Before)
+			})
+		}
+	})
+	return &client.SitewiseClient{
+		Client:  sw,
+		Retry:   client.ThrottleRetry{MaxRetries: ds.Cfg.GetThrottleMaxRetries()},
+		Metrics: labels,
+	}, nil
 }
 
-func (ds *Datasource) invoke(ctx context.Context, _ *backend.QueryDataRequest, baseQuery *models.BaseQuery, invoker invokerFunc) (data.Frames, error) {
+// rateLimitAccount identifies the account and role the requests of the datasource are made with.
+// Edge gateways are identified by their endpoint.
+func (ds *Datasource) rateLimitAccount() string {
+	cfg := ds.Cfg
+	if cfg.Region == models.EDGE_REGION {
+		return cfg.Endpoint
+	}
+	return strings.Join([]string{cfg.AuthType.String(), cfg.Profile, cfg.AccessKey, cfg.AssumeRoleARN, cfg.ExternalID, cfg.Endpoint}, "|")
+}
+
+func (ds *Datasource) invoke(ctx context.Context, _ *backend.QueryDataRequest, baseQuery *models.BaseQuery, invoker invokerFunc) (frames data.Frames, err error) {
+	ctx, span := util.StartSpan(ctx, "Datasource.invoke", util.QueryAttributes(*baseQuery)...)
+	defer func() { util.EndSpan(span, err) }()
+
 	sw, err := ds.getClient(ctx, baseQuery.AwsRegion)
 	if err != nil {
 		return nil, err
@@ -159,34 +349,47 @@ func (ds *Datasource) invoke(ctx context.Context, _ *backend.QueryDataRequest, b
 		return nil, err
 	}
 
-	return frameResponse(ctx, *baseQuery, fr, sw)
+	return ds.frameResponse(ctx, *baseQuery, fr, sw)
 }
 
-func (ds *Datasource) HealthCheck(ctx context.Context, req *backend.CheckHealthRequest) error {
-
-	sw, err := ds.getClient(ctx, ds.Cfg.Region)
-	if err != nil {
-		return errors.Wrap(err, "unable to load settings")
-	}
+func (ds *Datasource) HandleInterpolatedPropertyValueQuery(ctx context.Context, _ *backend.QueryDataRequest, query *models.AssetPropertyValueQuery) (frames data.Frames, err error) {
+	ctx, span := util.StartSpan(ctx, "Datasource.HandleInterpolatedPropertyValueQuery", util.PropertyValueQueryAttributes(*query)...)
+	defer func() { util.EndSpan(span, err) }()
 
-	// todo: expand health check to test permission boundaries
-	_, err = sw.ListAssetModels(ctx, &iotsitewise.ListAssetModelsInput{MaxResults: aws.Int32(1)})
-	return errors.Wrap(err, "unable to test ListAssetModels")
-}
-
-func (ds *Datasource) HandleInterpolatedPropertyValueQuery(ctx context.Context, _ *backend.QueryDataRequest, query *models.AssetPropertyValueQuery) (data.Frames, error) {
 	sw, err := ds.getClient(ctx, query.AwsRegion)
 	if err != nil {
 		return nil, err
 	}
-	modifiedQuery, fr, err := api.GetInterpolatedAssetPropertyValues(ctx, sw, *query)
-	if err != nil {
-		return nil, err
+	return fetchPerQuality(ctx, query, func(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error) {
+		modifiedQuery, fr, err := api.GetInterpolatedAssetPropertyValues(ctx, sw, *query, ds.propertyDataTypes(sw))
+		if err != nil {
+			return nil, err
+		}
+		return ds.frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+	})
+}
+
+// propertyDataTypes looks up the data types of the entries of a query through the metadata cache,
+// which the framer then reads the same properties from
+func (ds *Datasource) propertyDataTypes(sw client.SitewiseAPIClient) api.PropertyDataTypes {
+	return func(ctx context.Context, query models.AssetPropertyValueQuery) (map[string]iotsitewisetypes.PropertyDataType, error) {
+		cp := resource.NewCachingResourceProvider(resource.NewSitewiseResources(sw), ds.regionCache(query.AwsRegion))
+		properties, err := resource.NewQueryResourceProvider(cp, query.BaseQuery).Properties(ctx)
+		if err != nil {
+			return nil, err
+		}
+		dataTypes := make(map[string]iotsitewisetypes.PropertyDataType, len(properties))
+		for entryId, property := range properties {
+			dataTypes[entryId] = util.GetPropertyDataType(property)
+		}
+		return dataTypes, nil
 	}
-	return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
 }
 
-func (ds *Datasource) HandleGetAssetPropertyValueHistoryQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error) {
+func (ds *Datasource) HandleGetAssetPropertyValueHistoryQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (frames data.Frames, err error) {
+	ctx, span := util.StartSpan(ctx, "Datasource.HandleGetAssetPropertyValueHistoryQuery", util.PropertyValueQueryAttributes(*query)...)
+	defer func() { util.EndSpan(span, err) }()
+
 	sw, err := ds.getClient(ctx, query.AwsRegion)
 	if err != nil {
 		return nil, err
@@ -199,7 +402,7 @@ func (ds *Datasource) HandleGetAssetPropertyValueHistoryQuery(ctx context.Contex
 			return nil, err
 		}
 
-		return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+		return ds.frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
 	}
 
 	modifiedQuery, fr, err := api.BatchGetAssetPropertyValues(ctx, sw, *query)
@@ -207,10 +410,42 @@ func (ds *Datasource) HandleGetAssetPropertyValueHistoryQuery(ctx context.Contex
 		return nil, err
 	}
 
-	return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+	return ds.frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
 }
 
-func (ds *Datasource) HandleGetAssetPropertyAggregateQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error) {
+func (ds *Datasource) HandleGetAssetPropertyAggregateQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (frames data.Frames, err error) {
+	ctx, span := util.StartSpan(ctx, "Datasource.HandleGetAssetPropertyAggregateQuery", util.PropertyValueQueryAttributes(*query)...)
+	defer func() { util.EndSpan(span, err) }()
+
+	sw, err := ds.getClient(ctx, query.AwsRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchPerQuality(ctx, query, func(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error) {
+		// Batch API is not available at the edge
+		if query.AwsRegion == EDGE_REGION {
+			modifiedQuery, fr, err := api.GetAssetPropertyValuesForTimeRange(ctx, sw, *query)
+			if err != nil {
+				return nil, err
+			}
+
+			return ds.frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+		}
+
+		modifiedQuery, fr, err := api.BatchGetAssetPropertyValuesForTimeRange(ctx, sw, *query)
+		if err != nil {
+			return nil, err
+		}
+
+		return ds.frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+	})
+}
+
+func (ds *Datasource) HandleGetAssetPropertyValueQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (frames data.Frames, err error) {
+	ctx, span := util.StartSpan(ctx, "Datasource.HandleGetAssetPropertyValueQuery", util.PropertyValueQueryAttributes(*query)...)
+	defer func() { util.EndSpan(span, err) }()
+
 	sw, err := ds.getClient(ctx, query.AwsRegion)
 	if err != nil {
 		return nil, err
@@ -218,44 +453,71 @@ func (ds *Datasource) HandleGetAssetPropertyAggregateQuery(ctx context.Context,
 
 	// Batch API is not available at the edge
 	if query.AwsRegion == EDGE_REGION {
-		modifiedQuery, fr, err := api.GetAssetPropertyValuesForTimeRange(ctx, sw, *query)
+		modifiedQuery, fr, err := api.GetAssetPropertyValue(ctx, sw, *query)
 		if err != nil {
 			return nil, err
 		}
 
-		return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+		return ds.frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
 	}
 
-	modifiedQuery, fr, err := api.BatchGetAssetPropertyValuesForTimeRange(ctx, sw, *query)
+	modifiedQuery, fr, err := api.BatchGetAssetPropertyValue(ctx, sw, *query)
 	if err != nil {
 		return nil, err
 	}
 
-	return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+	return ds.frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
 }
 
-func (ds *Datasource) HandleGetAssetPropertyValueQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error) {
+// HandleDryRunQuery returns the calls a property query would make and their estimated cost,
+// without fetching its data
+func (ds *Datasource) HandleDryRunQuery(ctx context.Context, query *models.AssetPropertyValueQuery) (frames data.Frames, err error) {
+	ctx, span := util.StartSpan(ctx, "Datasource.HandleDryRunQuery", util.PropertyValueQueryAttributes(*query)...)
+	defer func() { util.EndSpan(span, err) }()
+
 	sw, err := ds.getClient(ctx, query.AwsRegion)
 	if err != nil {
 		return nil, err
 	}
 
-	// Batch API is not available at the edge
+	plan, err := api.DryRunAssetPropertyValueQuery(ctx, sw, *query, query.AwsRegion == EDGE_REGION)
+	if err != nil {
+		return nil, err
+	}
+	return ds.frameResponse(ctx, query.BaseQuery, plan, sw)
+}
+
+// ResolveAssetPropertyEntries resolves the assets, properties and aliases of a query
+// into the entries used by the batch APIs.
+func (ds *Datasource) ResolveAssetPropertyEntries(ctx context.Context, query *models.AssetPropertyValueQuery) ([]models.AssetPropertyEntry, error) {
+	sw, err := ds.getClient(ctx, query.AwsRegion)
+	if err != nil {
+		return nil, err
+	}
+	return api.ResolveAssetPropertyEntries(ctx, sw, *query)
+}
+
+// HandleGetLatestAssetPropertyValues fetches the latest values of the already resolved
+// AssetPropertyEntries of a query. Each frame carries the EntryId of its entry.
+func (ds *Datasource) HandleGetLatestAssetPropertyValues(ctx context.Context, query *models.AssetPropertyValueQuery) (frames data.Frames, err error) {
+	ctx, span := util.StartSpan(ctx, "Datasource.HandleGetLatestAssetPropertyValues", util.PropertyValueQueryAttributes(*query)...)
+	defer func() { util.EndSpan(span, err) }()
+
 	if query.AwsRegion == EDGE_REGION {
-		modifiedQuery, fr, err := api.GetAssetPropertyValue(ctx, sw, *query)
-		if err != nil {
-			return nil, err
-		}
+		return nil, dserrors.Validationf("batch API is not available at the edge")
+	}
 
-		return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+	sw, err := ds.getClient(ctx, query.AwsRegion)
+	if err != nil {
+		return nil, err
 	}
 
-	modifiedQuery, fr, err := api.BatchGetAssetPropertyValue(ctx, sw, *query)
+	fr, err := api.BatchGetAssetPropertyValueForEntries(ctx, sw, *query)
 	if err != nil {
 		return nil, err
 	}
 
-	return frameResponse(ctx, modifiedQuery.BaseQuery, fr, sw)
+	return ds.frameResponse(ctx, query.BaseQuery, fr, sw)
 }
 
 func (ds *Datasource) HandleListAssetModelsQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.ListAssetModelsQuery) (data.Frames, error) {
@@ -288,6 +550,12 @@ func (ds *Datasource) HandleDescribeAssetQuery(ctx context.Context, req *backend
 	})
 }
 
+func (ds *Datasource) HandleDescribeAssetPropertyQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.DescribeAssetPropertyQuery) (data.Frames, error) {
+	return ds.invoke(ctx, req, &query.BaseQuery, func(ctx context.Context, sw client.SitewiseAPIClient) (framer.Framer, error) {
+		return api.DescribeAssetProperties(ctx, sw, *query)
+	})
+}
+
 func (ds *Datasource) HandleDescribeAssetModelQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.DescribeAssetModelQuery) (data.Frames, error) {
 	return ds.invoke(ctx, req, &query.BaseQuery, func(ctx context.Context, sw client.SitewiseAPIClient) (framer.Framer, error) {
 		return api.DescribeAssetModel(ctx, sw, *query)
@@ -302,6 +570,9 @@ func (ds *Datasource) HandleListAssetPropertiesQuery(ctx context.Context, req *b
 
 func (ds *Datasource) HandleExecuteQuery(ctx context.Context, req *backend.QueryDataRequest, query *models.ExecuteQuery) (data.Frames, error) {
 	return ds.invoke(ctx, req, &query.BaseQuery, func(ctx context.Context, sw client.SitewiseAPIClient) (framer.Framer, error) {
+		if query.DryRun {
+			return api.DryRunExecuteQuery(*query), nil
+		}
 		return api.ExecuteQuery(ctx, sw, *query)
 	})
 }
@@ -0,0 +1,74 @@
+package sitewise
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-aws-sdk/pkg/awsds"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+)
+
+func TestClientPool(t *testing.T) {
+	// a custom CA bundle can't be applied to the HTTP client of the datasource
+	t.Setenv("AWS_CA_BUNDLE", "")
+
+	t.Run("clients are reused per region", func(t *testing.T) {
+		cfg := models.AWSSiteWiseDataSourceSetting{}
+		cfg.AuthType = awsds.AuthTypeKeys
+		cfg.AccessKey = "key"
+		cfg.SecretKey = "secret"
+		cfg.Region = "us-east-1"
+		ds := &Datasource{Cfg: cfg}
+
+		first, err := ds.newClient(context.Background(), "us-east-1")
+		require.NoError(t, err)
+		second, err := ds.newClient(context.Background(), "us-east-1")
+		require.NoError(t, err)
+		require.Same(t, first, second)
+
+		other, err := ds.newClient(context.Background(), "eu-west-1")
+		require.NoError(t, err)
+		require.NotSame(t, first, other)
+	})
+
+	t.Run("edge clients are rebuilt when the credentials are renewed", func(t *testing.T) {
+		settings, _ := newCountingAuthServer(t, time.Hour)
+		settings.Region = models.EDGE_REGION
+		a := &EdgeAuthenticator{Settings: settings, RenewalMargin: time.Minute}
+		ds := &Datasource{Cfg: settings, edgeAuthenticator: a}
+
+		first, err := ds.newClient(context.Background(), models.EDGE_REGION)
+		require.NoError(t, err)
+		httpClient := ds.httpClient
+		second, err := ds.newClient(context.Background(), models.EDGE_REGION)
+		require.NoError(t, err)
+		require.Same(t, first, second)
+
+		require.NoError(t, a.Refresh(context.Background(), a.Generation()))
+		renewed, err := ds.newClient(context.Background(), models.EDGE_REGION)
+		require.NoError(t, err)
+		require.NotSame(t, first, renewed)
+		// the transport and its connections are kept
+		require.Same(t, httpClient, ds.httpClient)
+	})
+}
+
+func TestRateLimitersAreSharedByAccount(t *testing.T) {
+	cfg := models.AWSSiteWiseDataSourceSetting{}
+	cfg.AuthType = awsds.AuthTypeKeys
+	cfg.AccessKey = "shared-key"
+	first, second := &Datasource{UID: "first", Cfg: cfg}, &Datasource{UID: "second", Cfg: cfg}
+	cfg.AccessKey = "other-key"
+	other := &Datasource{UID: "other", Cfg: cfg}
+
+	limiters := func(ds *Datasource, region string) *client.RateLimiters {
+		return client.SharedRateLimiters(ds.rateLimitAccount(), region, 10, 10)
+	}
+	require.Same(t, limiters(first, "us-east-1"), limiters(second, "us-east-1"))
+	require.NotSame(t, limiters(first, "us-east-1"), limiters(first, "eu-west-1"))
+	require.NotSame(t, limiters(first, "us-east-1"), limiters(other, "us-east-1"))
+}
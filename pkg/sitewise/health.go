@@ -0,0 +1,265 @@
+package sitewise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// The outcome of calling an API during the health check
+const (
+	APIAllowed     = "allowed"
+	APIDenied      = "denied"
+	APIUnsupported = "unsupported"
+	APIFailed      = "failed"
+)
+
+// CertificateExpiryWarning is how long before the edge certificate expires the health check warns about it
+const CertificateExpiryWarning = 30 * 24 * time.Hour
+
+// healthCheckId identifies resources that do not exist, the APIs answer with a not found error
+// once the caller is authorized
+const healthCheckId = "00000000-0000-0000-0000-000000000000"
+
+// APICheck is the outcome of calling an API during the health check
+type APICheck struct {
+	API    string `json:"api"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CertificateCheck describes the certificate of an edge gateway
+type CertificateCheck struct {
+	Subject   string    `json:"subject,omitempty"`
+	Issuer    string    `json:"issuer,omitempty"`
+	NotBefore time.Time `json:"notBefore,omitempty"`
+	NotAfter  time.Time `json:"notAfter,omitempty"`
+	Valid     bool      `json:"valid"`
+	// ExpiresInDays is negative once the certificate expired
+	ExpiresInDays int    `json:"expiresInDays"`
+	Error         string `json:"error,omitempty"`
+}
+
+// HealthReport lists the APIs the plugin uses by whether the credentials of the datasource may call them
+type HealthReport struct {
+	APIs        []APICheck        `json:"apis"`
+	Allowed     []string          `json:"allowed"`
+	Denied      []string          `json:"denied"`
+	Unsupported []string          `json:"unsupported"`
+	Failed      []string          `json:"failed,omitempty"`
+	Certificate *CertificateCheck `json:"certificate,omitempty"`
+}
+
+// Warnings describes the problems that do not fail the health check
+func (r *HealthReport) Warnings() []string {
+	var warnings []string
+	if len(r.Denied) > 0 {
+		warnings = append(warnings, "access is denied to "+strings.Join(r.Denied, ", "))
+	}
+	if len(r.Failed) > 0 {
+		warnings = append(warnings, "unable to test "+strings.Join(r.Failed, ", "))
+	}
+	if c := r.Certificate; c != nil && c.Valid && time.Until(c.NotAfter) < CertificateExpiryWarning {
+		warnings = append(warnings, fmt.Sprintf("the edge certificate expires in %d days", c.ExpiresInDays))
+	}
+	return warnings
+}
+
+type apiProbe struct {
+	api string
+	// edge is false for the APIs an edge gateway does not serve
+	edge bool
+	call func(ctx context.Context, sw client.SitewiseAPIClient) error
+}
+
+// apiProbes call each API the plugin uses with the smallest request possible
+var apiProbes = []apiProbe{
+	{api: "ListAssetModels", edge: true, call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.ListAssetModels(ctx, &iotsitewise.ListAssetModelsInput{MaxResults: aws.Int32(1)})
+		return err
+	}},
+	{api: "ListAssets", edge: true, call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.ListAssets(ctx, &iotsitewise.ListAssetsInput{
+			Filter:     iotsitewisetypes.ListAssetsFilterTopLevel,
+			MaxResults: aws.Int32(1),
+		})
+		return err
+	}},
+	{api: "DescribeAsset", edge: true, call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.DescribeAsset(ctx, &iotsitewise.DescribeAssetInput{AssetId: aws.String(healthCheckId)})
+		return err
+	}},
+	{api: "DescribeTimeSeries", edge: true, call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.DescribeTimeSeries(ctx, &iotsitewise.DescribeTimeSeriesInput{Alias: aws.String("/grafana/health-check")})
+		return err
+	}},
+	{api: "BatchGetAssetPropertyValue", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.BatchGetAssetPropertyValue(ctx, &iotsitewise.BatchGetAssetPropertyValueInput{
+			Entries: []iotsitewisetypes.BatchGetAssetPropertyValueEntry{{
+				EntryId:    aws.String("health"),
+				AssetId:    aws.String(healthCheckId),
+				PropertyId: aws.String(healthCheckId),
+			}},
+		})
+		return err
+	}},
+	{api: "GetInterpolatedAssetPropertyValues", edge: true, call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		now := time.Now()
+		_, err := sw.GetInterpolatedAssetPropertyValues(ctx, &iotsitewise.GetInterpolatedAssetPropertyValuesInput{
+			AssetId:            aws.String(healthCheckId),
+			PropertyId:         aws.String(healthCheckId),
+			StartTimeInSeconds: aws.Int64(now.Add(-time.Hour).Unix()),
+			EndTimeInSeconds:   aws.Int64(now.Unix()),
+			IntervalInSeconds:  aws.Int64(3600),
+			Quality:            iotsitewisetypes.QualityGood,
+			Type:               aws.String("LINEAR_INTERPOLATION"),
+			MaxResults:         aws.Int32(1),
+		})
+		return err
+	}},
+	{api: "ExecuteQuery", call: func(ctx context.Context, sw client.SitewiseAPIClient) error {
+		_, err := sw.ExecuteQuery(ctx, &iotsitewise.ExecuteQueryInput{
+			QueryStatement: aws.String("SELECT asset_id FROM asset LIMIT 1"),
+			MaxResults:     aws.Int32(1),
+		})
+		return err
+	}},
+}
+
+// HealthCheck calls each API the plugin uses and reports which of them the credentials may call.
+// It fails when the datasource cannot list asset models, or the edge certificate is not valid.
+func (ds *Datasource) HealthCheck(ctx context.Context, req *backend.CheckHealthRequest) (report *HealthReport, err error) {
+	ctx, span := util.StartSpan(ctx, "Datasource.HealthCheck")
+	defer func() { util.EndSpan(span, err) }()
+
+	report = &HealthReport{Allowed: []string{}, Denied: []string{}, Unsupported: []string{}}
+	edge := ds.Cfg.Region == EDGE_REGION
+	if edge {
+		report.Certificate = checkCertificate(ds.Cfg.Cert, time.Now())
+		if !report.Certificate.Valid {
+			return report, fmt.Errorf("invalid edge certificate: %s", report.Certificate.Error)
+		}
+	}
+
+	// the metadata cache is bypassed, so that every check reaches the API
+	sw, err := ds.newClient(ctx, ds.Cfg.Region)
+	if err != nil {
+		return report, fmt.Errorf("unable to load settings: %w", err)
+	}
+
+	report.APIs = make([]APICheck, len(apiProbes))
+	var wg sync.WaitGroup
+	for i, probe := range apiProbes {
+		if edge && !probe.edge {
+			report.APIs[i] = APICheck{API: probe.api, Status: APIUnsupported}
+			continue
+		}
+		wg.Go(func() {
+			report.APIs[i] = checkAPI(probe.api, probe.call(ctx, sw))
+		})
+	}
+	wg.Wait()
+
+	for _, check := range report.APIs {
+		switch check.Status {
+		case APIAllowed:
+			report.Allowed = append(report.Allowed, check.API)
+		case APIDenied:
+			report.Denied = append(report.Denied, check.API)
+		case APIUnsupported:
+			report.Unsupported = append(report.Unsupported, check.API)
+		default:
+			report.Failed = append(report.Failed, check.API)
+		}
+	}
+
+	// listing asset models is the baseline of the datasource
+	if check := report.APIs[0]; check.Status != APIAllowed {
+		return report, fmt.Errorf("unable to test ListAssetModels: %s", check.Error)
+	}
+	return report, nil
+}
+
+// checkAPI tells from the error of a call whether the caller may call the API. Errors about the
+// request itself, such as a missing resource, are only returned once the caller is authorized.
+func checkAPI(api string, err error) APICheck {
+	check := APICheck{API: api, Status: APIAllowed}
+	if err == nil {
+		return check
+	}
+	check.Error = err.Error()
+
+	if client.IsThrottling(err) {
+		check.Status = APIFailed
+		return check
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "AccessDeniedException":
+			check.Status = APIDenied
+			return check
+		case "UnknownOperationException":
+			check.Status = APIUnsupported
+			return check
+		case "ResourceNotFoundException", "InvalidRequestException", "ValidationException":
+			return check
+		}
+	}
+
+	var respErr *smithyhttp.ResponseError
+	if !errors.As(err, &respErr) {
+		check.Status = APIFailed
+		return check
+	}
+	switch status := respErr.HTTPStatusCode(); {
+	case status == http.StatusForbidden || status == http.StatusUnauthorized:
+		check.Status = APIDenied
+	case status == http.StatusNotImplemented:
+		check.Status = APIUnsupported
+	case status >= 400 && status < 500:
+		check.Status = APIAllowed
+	default:
+		check.Status = APIFailed
+	}
+	return check
+}
+
+// checkCertificate reports the validity period of the edge certificate
+func checkCertificate(pem string, now time.Time) *CertificateCheck {
+	cert, err := client.ParseCertificate(pem)
+	if err != nil {
+		return &CertificateCheck{Error: err.Error()}
+	}
+
+	check := &CertificateCheck{
+		Subject:       cert.Subject.String(),
+		Issuer:        cert.Issuer.String(),
+		NotBefore:     cert.NotBefore,
+		NotAfter:      cert.NotAfter,
+		ExpiresInDays: int(cert.NotAfter.Sub(now).Hours() / 24),
+	}
+	switch {
+	case now.Before(cert.NotBefore):
+		check.Error = fmt.Sprintf("the certificate is not valid before %s", cert.NotBefore.Format(time.RFC3339))
+	case !now.Before(cert.NotAfter):
+		check.Error = fmt.Sprintf("the certificate expired on %s", cert.NotAfter.Format(time.RFC3339))
+	default:
+		check.Valid = true
+	}
+	return check
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/resource"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -54,6 +56,9 @@ func ParseJSONFields(
 	frames data.Frames,
 	resources resource.ResourceLookup,
 ) data.Frames {
+	ctx, span := util.StartSpan(ctx, "sitewise.ParseJSONFields", attribute.Int("sitewise.frames", len(frames)))
+	defer span.End()
+
 	newFrames := data.Frames{}
 
 	for _, frame := range frames {
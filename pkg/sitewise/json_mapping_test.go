@@ -3,6 +3,7 @@ package sitewise
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
@@ -11,13 +12,12 @@ import (
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/resource"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client/mocks"
-	"github.com/patrickmn/go-cache"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
 func newTestResourceLookup(mockClient *mocks.SitewiseAPIClient) resource.ResourceLookup {
-	c := cache.New(cache.DefaultExpiration, cache.NoExpiration)
+	c := resource.NewMetadataCache(time.Minute, 100).Region("us-east-1")
 	cp := resource.NewCachingResourceProvider(resource.NewSitewiseResources(mockClient), c)
 	return resource.NewQueryResourceProvider(cp, models.BaseQuery{})
 }
@@ -0,0 +1,76 @@
+package sitewise
+
+import (
+	"context"
+
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/framer/fields"
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api"
+)
+
+// fetchPerQuality runs fetch once for each quality of a query that is fetched with an API that
+// filters by a single quality, and runs it once for the other queries. The frames of each quality
+// are labelled with it, and their EntryId is qualified with it so that the next tokens of a page
+// continue each quality on its own.
+func fetchPerQuality(ctx context.Context, query *models.AssetPropertyValueQuery, fetch func(ctx context.Context, query *models.AssetPropertyValueQuery) (data.Frames, error)) (data.Frames, error) {
+	if !api.FetchesPerQuality(*query) {
+		return fetch(ctx, query)
+	}
+
+	qualities := query.GetQualities()
+	results := make([]data.Frames, len(qualities))
+	eg, ectx := errgroup.WithContext(ctx)
+	for i, quality := range qualities {
+		qualityQuery, ok := api.QueryForQuality(*query, quality)
+		if !ok {
+			continue
+		}
+		eg.Go(func() error {
+			frames, err := fetch(ectx, &qualityQuery)
+			if err != nil {
+				return err
+			}
+			for _, frame := range frames {
+				tagQuality(frame, quality)
+			}
+			results[i] = frames
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	frames := data.Frames{}
+	for _, r := range results {
+		frames = append(frames, r...)
+	}
+	return frames, nil
+}
+
+func tagQuality(frame *data.Frame, quality iotsitewisetypes.Quality) {
+	for _, field := range frame.Fields {
+		if field.Type().Time() {
+			continue
+		}
+		if field.Labels == nil {
+			field.Labels = data.Labels{}
+		}
+		field.Labels[fields.Quality] = string(quality)
+	}
+
+	if frame.Meta == nil {
+		return
+	}
+	if meta, ok := frame.Meta.Custom.(models.SitewiseCustomMeta); ok {
+		if meta.EntryId != "" {
+			meta.EntryId = models.QualityEntryId(meta.EntryId, quality)
+		}
+		meta.Quality = string(quality)
+		frame.Meta.Custom = meta
+	}
+}
@@ -0,0 +1,265 @@
+package sitewise
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotsitewise"
+	iotsitewisetypes "github.com/aws/aws-sdk-go-v2/service/iotsitewise/types"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+	"github.com/grafana/iot-sitewise-datasource/pkg/resource"
+	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/api"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
+)
+
+// The methods in this file back the resource API. Listings are paginated with the SiteWise
+// next token, single resources are served from the metadata cache.
+
+func (ds *Datasource) cachingResources(ctx context.Context, region string) (resource.ResourceLookup, error) {
+	sw, err := ds.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	cp := resource.NewCachingResourceProvider(resource.NewSitewiseResources(sw), ds.regionCache(region))
+	return resource.NewQueryResourceProvider(cp, models.BaseQuery{AwsRegion: region}), nil
+}
+
+func (ds *Datasource) ListAssetsResource(ctx context.Context, query models.ListAssetsQuery) (*models.ResourcePage[models.AssetSummary], error) {
+	sw, err := ds.getClient(ctx, query.AwsRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := api.ListAssets(ctx, sw, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ResourcePage[models.AssetSummary]{
+		Items:     toAssetSummaries(resp.AssetSummaries),
+		NextToken: util.Dereference(resp.NextToken),
+	}, nil
+}
+
+// ListAssociatedAssetsResource returns all the associated assets in a single page without a next
+// token, since ListAssociatedAssets loads every page, and the children of every hierarchy with
+// LoadAllChildren.
+func (ds *Datasource) ListAssociatedAssetsResource(ctx context.Context, query models.ListAssociatedAssetsQuery) (*models.ResourcePage[models.AssetSummary], error) {
+	sw, err := ds.getClient(ctx, query.AwsRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := api.ListAssociatedAssets(ctx, sw, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ResourcePage[models.AssetSummary]{Items: toAssociatedAssetSummaries(resp.AssetSummaries)}, nil
+}
+
+func (ds *Datasource) DescribeAssetResource(ctx context.Context, region string, assetId string) (*models.AssetDetails, error) {
+	resources, err := ds.cachingResources(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	asset, err := resources.LookupAsset(ctx, assetId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAssetDetails(asset), nil
+}
+
+func (ds *Datasource) ListAssetModelsResource(ctx context.Context, query models.ListAssetModelsQuery) (*models.ResourcePage[models.AssetModelSummary], error) {
+	sw, err := ds.getClient(ctx, query.AwsRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := api.ListAssetModels(ctx, sw, query)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.AssetModelSummary, 0, len(resp.AssetModelSummaries))
+	for _, m := range resp.AssetModelSummaries {
+		items = append(items, models.AssetModelSummary{
+			Id:          util.Dereference(m.Id),
+			Name:        util.Dereference(m.Name),
+			Arn:         util.Dereference(m.Arn),
+			Description: util.Dereference(m.Description),
+		})
+	}
+
+	return &models.ResourcePage[models.AssetModelSummary]{
+		Items:     items,
+		NextToken: util.Dereference(resp.NextToken),
+	}, nil
+}
+
+func (ds *Datasource) DescribeAssetModelResource(ctx context.Context, region string, modelId string) (*models.AssetModelSummary, error) {
+	resources, err := ds.cachingResources(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := resources.LookupAssetModel(ctx, modelId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AssetModelSummary{
+		Id:          util.Dereference(model.AssetModelId),
+		Name:        util.Dereference(model.AssetModelName),
+		Arn:         util.Dereference(model.AssetModelArn),
+		Description: util.Dereference(model.AssetModelDescription),
+	}, nil
+}
+
+// ListPropertiesResource lists the properties of an asset, including the properties of its composite models
+func (ds *Datasource) ListPropertiesResource(ctx context.Context, region string, assetId string) (*models.ResourcePage[models.PropertySummary], error) {
+	asset, err := ds.DescribeAssetResource(ctx, region, assetId)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ResourcePage[models.PropertySummary]{Items: asset.Properties}, nil
+}
+
+// DescribePropertyResource describes a single property by asset and property id, or by alias
+func (ds *Datasource) DescribePropertyResource(ctx context.Context, region string, assetId string, propertyId string, propertyAlias string) (*models.PropertySummary, error) {
+	resources, err := ds.cachingResources(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	property, err := resources.LookupAssetProperty(ctx, assetId, propertyId, propertyAlias)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.PropertySummary{
+		Name:     util.GetPropertyName(property),
+		DataType: string(util.GetPropertyDataType(property)),
+		Unit:     util.GetPropertyUnit(property),
+	}
+	if util.IsAssetProperty(property) {
+		summary.Id = util.Dereference(property.AssetProperty.Id)
+		summary.Alias = util.Dereference(property.AssetProperty.Alias)
+	} else if util.IsComponentProperty(property) {
+		summary.Id = util.Dereference(property.CompositeModel.AssetProperty.Id)
+		summary.Alias = util.Dereference(property.CompositeModel.AssetProperty.Alias)
+		summary.CompositeModelId = util.Dereference(property.CompositeModel.Id)
+		summary.CompositeModelName = util.Dereference(property.CompositeModel.Name)
+	}
+	return summary, nil
+}
+
+func (ds *Datasource) ListTimeSeriesResource(ctx context.Context, query models.ListTimeSeriesQuery) (*models.ResourcePage[models.TimeSeriesSummary], error) {
+	sw, err := ds.getClient(ctx, query.AwsRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := api.ListTimeSeries(ctx, sw, query)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.TimeSeriesSummary, 0, len(resp.TimeSeriesSummaries))
+	for _, ts := range resp.TimeSeriesSummaries {
+		items = append(items, models.TimeSeriesSummary{
+			Id:         util.Dereference(ts.TimeSeriesId),
+			Alias:      util.Dereference(ts.Alias),
+			AssetId:    util.Dereference(ts.AssetId),
+			PropertyId: util.Dereference(ts.PropertyId),
+			DataType:   string(ts.DataType),
+		})
+	}
+
+	return &models.ResourcePage[models.TimeSeriesSummary]{
+		Items:     items,
+		NextToken: util.Dereference(resp.NextToken),
+	}, nil
+}
+
+func toAssetHierarchies(hierarchies []iotsitewisetypes.AssetHierarchy) []models.AssetHierarchy {
+	out := make([]models.AssetHierarchy, 0, len(hierarchies))
+	for _, h := range hierarchies {
+		out = append(out, models.AssetHierarchy{
+			Id:   util.Dereference(h.Id),
+			Name: util.Dereference(h.Name),
+		})
+	}
+	return out
+}
+
+func toAssetSummaries(assets []iotsitewisetypes.AssetSummary) []models.AssetSummary {
+	out := make([]models.AssetSummary, 0, len(assets))
+	for _, a := range assets {
+		out = append(out, models.AssetSummary{
+			Id:          util.Dereference(a.Id),
+			Name:        util.Dereference(a.Name),
+			Arn:         util.Dereference(a.Arn),
+			ModelId:     util.Dereference(a.AssetModelId),
+			Description: util.Dereference(a.Description),
+			Hierarchies: toAssetHierarchies(a.Hierarchies),
+		})
+	}
+	return out
+}
+
+// toAssociatedAssetSummaries is toAssetSummaries for ListAssociatedAssets, which returns its own
+// summary type with the same fields
+func toAssociatedAssetSummaries(assets []iotsitewisetypes.AssociatedAssetsSummary) []models.AssetSummary {
+	out := make([]models.AssetSummary, 0, len(assets))
+	for _, a := range assets {
+		out = append(out, models.AssetSummary{
+			Id:          util.Dereference(a.Id),
+			Name:        util.Dereference(a.Name),
+			Arn:         util.Dereference(a.Arn),
+			ModelId:     util.Dereference(a.AssetModelId),
+			Description: util.Dereference(a.Description),
+			Hierarchies: toAssetHierarchies(a.Hierarchies),
+		})
+	}
+	return out
+}
+
+func toPropertySummary(p iotsitewisetypes.AssetProperty) models.PropertySummary {
+	return models.PropertySummary{
+		Id:       util.Dereference(p.Id),
+		Name:     util.Dereference(p.Name),
+		Alias:    util.Dereference(p.Alias),
+		DataType: string(p.DataType),
+		Unit:     util.Dereference(p.Unit),
+	}
+}
+
+func toAssetDetails(asset *iotsitewise.DescribeAssetOutput) *models.AssetDetails {
+	properties := make([]models.PropertySummary, 0, len(asset.AssetProperties))
+	for _, p := range asset.AssetProperties {
+		properties = append(properties, toPropertySummary(p))
+	}
+	for _, cm := range asset.AssetCompositeModels {
+		for _, p := range cm.Properties {
+			summary := toPropertySummary(p)
+			summary.CompositeModelId = util.Dereference(cm.Id)
+			summary.CompositeModelName = util.Dereference(cm.Name)
+			properties = append(properties, summary)
+		}
+	}
+
+	return &models.AssetDetails{
+		AssetSummary: models.AssetSummary{
+			Id:          util.Dereference(asset.AssetId),
+			Name:        util.Dereference(asset.AssetName),
+			Arn:         util.Dereference(asset.AssetArn),
+			ModelId:     util.Dereference(asset.AssetModelId),
+			Description: util.Dereference(asset.AssetDescription),
+			Hierarchies: toAssetHierarchies(asset.AssetHierarchies),
+		},
+		Properties: properties,
+	}
+}
@@ -2,34 +2,28 @@ package sitewise
 
 import (
 	"context"
-	"time"
-
-	"github.com/patrickmn/go-cache"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/iot-sitewise-datasource/pkg/models"
 	"github.com/grafana/iot-sitewise-datasource/pkg/resource"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/client"
 	"github.com/grafana/iot-sitewise-datasource/pkg/sitewise/framer"
+	"github.com/grafana/iot-sitewise-datasource/pkg/util"
 )
 
-// cacheDuration is a constant that defines how long to keep cached elements before they are refreshed
-const cacheDuration = time.Minute * 5
-
-// cacheCleanupInterval is the interval at which the internal cache is cleaned / garbage collected
-const cacheCleanupInterval = time.Minute * 10
+// frameResponse turns the responses of a query into frames. Everything up to framing is timed as
+// the fetch phase of the query.
+func (ds *Datasource) frameResponse(ctx context.Context, query models.BaseQuery, data framer.Framer, sw client.SitewiseAPIClient) (data.Frames, error) {
+	execution := client.ExecutionFrom(ctx)
+	execution.Phase("fetch")
+	defer execution.Phase("frame")
 
-var GetCache = func() func() *cache.Cache {
-	var gCache = cache.New(cacheDuration, cacheCleanupInterval) // max size not supported
-	return func() *cache.Cache {
-		return gCache
-	}
-}()
-
-func frameResponse(ctx context.Context, query models.BaseQuery, data framer.Framer, sw client.SitewiseAPIClient) (data.Frames, error) {
-	cp := resource.NewCachingResourceProvider(resource.NewSitewiseResources(sw), GetCache())
+	cp := resource.NewCachingResourceProvider(resource.NewSitewiseResources(sw), ds.regionCache(query.AwsRegion))
 	rp := resource.NewQueryResourceProvider(cp, query)
-	frames, err := data.Frames(ctx, rp)
+
+	frameCtx, span := util.StartSpan(ctx, "framer.Frames", util.QueryAttributes(query)...)
+	frames, err := data.Frames(frameCtx, rp)
+	util.EndSpan(span, err)
 	if err != nil {
 		return nil, err
 	}
@@ -155,3 +155,12 @@ func GetIoTSitewiseTimeSeries(t *testing.T, path string) iotsitewise.DescribeTim
 	}
 	return timeSeries
 }
+
+func GetIoTSitewiseAssetPropertyDescription(t *testing.T, path string) iotsitewise.DescribeAssetPropertyOutput {
+	property := iotsitewise.DescribeAssetPropertyOutput{}
+	err := UnmarshalFileContents(path, &property)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return property
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"context"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/iot-sitewise-datasource/pkg/models"
+)
+
+// StartSpan starts a span with the tracer provided by the plugin SDK
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.DefaultTracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records the error of a span, if any, and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		_ = tracing.Error(span, err)
+	}
+	span.End()
+}
+
+// QueryAttributes returns the span attributes of a query
+func QueryAttributes(query models.BaseQuery) []attribute.KeyValue {
+	entries := len(query.AssetPropertyEntries)
+	if entries == 0 {
+		entries = len(query.AssetIds)*len(query.PropertyIds) + len(query.PropertyAliases)
+	}
+	return []attribute.KeyValue{
+		attribute.String("sitewise.query_type", query.QueryType),
+		attribute.String("sitewise.region", query.AwsRegion),
+		attribute.Int("sitewise.entries", entries),
+		attribute.Bool("sitewise.next_token", query.NextToken != "" || len(query.NextTokens) > 0),
+	}
+}
+
+// PropertyValueQueryAttributes returns the span attributes of a property value query
+func PropertyValueQueryAttributes(query models.AssetPropertyValueQuery) []attribute.KeyValue {
+	return append(QueryAttributes(query.BaseQuery), attribute.String("sitewise.resolution", query.Resolution))
+}
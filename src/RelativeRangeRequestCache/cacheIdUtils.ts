@@ -51,6 +51,14 @@ function generateSiteWiseQueryCacheId(query: SitewiseQueriesUnion): QueryCacheId
     aggregates,
     timeSeriesType,
     aliasPrefix,
+    fetchAll,
+    dryRun,
+    interpolationType,
+    intervalWindow,
+    qualities,
+    lastObservationLookback,
+    lastObservationAtRangeStart,
+    downsample,
   } = query;
 
   /*
@@ -82,5 +90,13 @@ function generateSiteWiseQueryCacheId(query: SitewiseQueriesUnion): QueryCacheId
     aggregates,
     timeSeriesType,
     aliasPrefix,
+    fetchAll,
+    dryRun,
+    interpolationType,
+    intervalWindow,
+    qualities,
+    lastObservationLookback,
+    lastObservationAtRangeStart,
+    downsample,
   ]);
 }
@@ -1,5 +1,5 @@
 import { DataFrame } from '@grafana/data';
-import { AssetPropertyAggregatesQuery, AssetPropertyValueHistoryQuery, ListAssetsQuery, ListAssociatedAssetsQuery, ListTimeSeriesQuery, QueryType, SitewiseQuery } from 'types';
+import { AssetPropertyAggregatesQuery, AssetPropertyInterpolatedQuery, AssetPropertyValueHistoryQuery, ListAssetsQuery, ListAssociatedAssetsQuery, ListTimeSeriesQuery, QueryType, SitewiseQuery } from 'types';
 
 const TIME_SERIES_QUERY_TYPES = new Set<QueryType>([
   QueryType.PropertyAggregate,
@@ -30,6 +30,9 @@ export interface CachedQueryInfo {
 export type SitewiseQueriesUnion = SitewiseQuery
   & Partial<Pick<AssetPropertyAggregatesQuery, 'aggregates'>>
   & Partial<Pick<AssetPropertyValueHistoryQuery, 'timeOrdering'>>
+  & Partial<Pick<AssetPropertyValueHistoryQuery, 'downsample'>>
+  & Partial<Pick<AssetPropertyInterpolatedQuery, 'interpolationType'>>
+  & Partial<Pick<AssetPropertyInterpolatedQuery, 'intervalWindow'>>
   & Partial<Pick<ListAssociatedAssetsQuery, 'loadAllChildren'>>
   & Partial<Pick<ListAssociatedAssetsQuery, 'hierarchyId'>>
   & Partial<Pick<ListAssetsQuery, 'modelId'>>
@@ -10,7 +10,14 @@ import {
 } from '@grafana/data';
 import { DataSourceWithBackend, getTemplateSrv } from '@grafana/runtime';
 import { SitewiseCache } from 'sitewiseCache';
-import { isListAssetsQuery, isPropertyQueryType, SitewiseOptions, SitewiseQuery, SiteWiseResolution } from './types';
+import {
+  isAssetPropertyValueQuery,
+  isListAssetsQuery,
+  isPropertyQueryType,
+  SitewiseOptions,
+  SitewiseQuery,
+  SiteWiseResolution,
+} from './types';
 import { lastValueFrom, Observable } from 'rxjs';
 import { tap } from 'rxjs/operators';
 import { frameToMetricFindValues } from 'utils';
@@ -193,6 +200,12 @@ export class DataSource extends DataSourceWithBackend<SitewiseQuery, SitewiseOpt
 
   query(request: DataQueryRequest<SitewiseQuery>): Observable<DataQueryResponse> {
     const interpolatedRequest = this.constructVariableInterpolatedRequest(request);
+
+    // Streaming queries never complete, so they cannot be paginated or cached
+    if (request.targets.some((target) => isAssetPropertyValueQuery(target) && target.streaming)) {
+      return super.query(request);
+    }
+
     const cachedInfo = request.range != null ? this.relativeRangeCache.get(interpolatedRequest) : undefined;
 
     return new SitewiseQueryPaginator({
@@ -3,6 +3,7 @@ import { EditorField } from '@grafana/plugin-ui';
 import { Select } from '@grafana/ui';
 import React, { useCallback } from 'react';
 import {
+  SiteWiseDownsample,
   SiteWiseTimeOrder,
   SiteWiseQuality,
   SiteWiseResponseFormat,
@@ -16,6 +17,7 @@ const QUALITY_OPTIONS = [
   { value: SiteWiseQuality.GOOD, label: 'GOOD' },
   { value: SiteWiseQuality.BAD, label: 'BAD' },
   { value: SiteWiseQuality.UNCERTAIN, label: 'UNCERTAIN' },
+  { value: SiteWiseQuality.ANY, label: 'ANY' },
 ] satisfies Array<SelectableValue<SiteWiseQuality>>;
 
 const ORDERING_OPTIONS = [
@@ -23,6 +25,12 @@ const ORDERING_OPTIONS = [
   { value: SiteWiseTimeOrder.DESCENDING, label: 'DESCENDING' },
 ] satisfies Array<SelectableValue<SiteWiseTimeOrder>>;
 
+const DOWNSAMPLE_OPTIONS = [
+  { value: undefined, label: 'None', description: 'Return every value' },
+  { value: SiteWiseDownsample.LTTB, label: 'LTTB', description: 'Keep the shape of the series' },
+  { value: SiteWiseDownsample.MinMax, label: 'Min/max', description: 'Keep the minimum and maximum of every bucket' },
+] satisfies Array<SelectableValue<SiteWiseDownsample | undefined>>;
+
 export const FORMAT_OPTIONS = [
   { label: 'Table', value: SiteWiseResponseFormat.Table },
   { label: 'Time series', value: SiteWiseResponseFormat.TimeSeries },
@@ -50,6 +58,13 @@ export const QualityAndOrderRow = ({ onChange, query }: SitewiseQueryEditorProps
     [onChange, query]
   );
 
+  const onDownsampleChange = useCallback(
+    (sel: SelectableValue<SiteWiseDownsample | undefined>) => {
+      onChange({ ...query, downsample: sel.value } as AssetPropertyValueHistoryQuery);
+    },
+    [onChange, query]
+  );
+
   return (
     <>
       <EditorField label="Quality" width={15} htmlFor="quality">
@@ -82,6 +97,27 @@ export const QualityAndOrderRow = ({ onChange, query }: SitewiseQueryEditorProps
         </EditorField>
       )}
 
+      {query.queryType === QueryType.PropertyValueHistory && (
+        <EditorField
+          label="Downsample"
+          width={12}
+          htmlFor="downsample"
+          tooltip="Downsample the raw values to about the max data points of the query, keeping their timestamps"
+        >
+          <Select
+            id="downsample"
+            aria-label="Downsample"
+            options={DOWNSAMPLE_OPTIONS}
+            value={
+              DOWNSAMPLE_OPTIONS.find((v) => v.value === (query as AssetPropertyValueHistoryQuery).downsample) ??
+              DOWNSAMPLE_OPTIONS[0]
+            }
+            onChange={onDownsampleChange}
+            menuPlacement="auto"
+          />
+        </EditorField>
+      )}
+
       <EditorField label="Format" width={10} htmlFor="format">
         <Select
           id="format"
@@ -1,5 +1,11 @@
-import { SitewiseQuery, shouldShowL4eOptions, shouldShowLastObserved, shouldShowQualityAndOrderComponent } from 'types';
-import { CollapsableSection, Switch, useTheme2 } from '@grafana/ui';
+import {
+  SitewiseQuery,
+  isAssetPropertyValueQuery,
+  shouldShowL4eOptions,
+  shouldShowLastObserved,
+  shouldShowQualityAndOrderComponent,
+} from 'types';
+import { CollapsableSection, Input, Switch, useTheme2 } from '@grafana/ui';
 import React from 'react';
 import { EditorField, EditorFieldGroup } from '@grafana/plugin-ui';
 import { css } from '@emotion/css';
@@ -50,6 +56,47 @@ export function QueryOptions({
               <Switch value={query.lastObservation} onChange={onLastObservationChange} />
             </EditorField>
           )}
+          {shouldShowLastObserved(query.queryType) && !query.propertyAliases?.length && showProp && query.lastObservation && (
+            <>
+              <EditorField
+                label="Lookback"
+                htmlFor="lookback"
+                tooltip="How far before the time range to look for the last observed value. Defaults to 1 year."
+              >
+                <Input
+                  id="lookback"
+                  width={10}
+                  placeholder="1y"
+                  defaultValue={query.lastObservationLookback}
+                  onBlur={(e) => onChange({ ...query, lastObservationLookback: e.currentTarget.value || undefined })}
+                />
+              </EditorField>
+              <EditorField
+                label="Value at Range Start"
+                htmlFor="rangeStart"
+                tooltip="Show the last observed value at the start of the time range, the value the property had then."
+              >
+                <Switch
+                  id="rangeStart"
+                  value={query.lastObservationAtRangeStart}
+                  onChange={() => onChange({ ...query, lastObservationAtRangeStart: !query.lastObservationAtRangeStart })}
+                />
+              </EditorField>
+            </>
+          )}
+          {isAssetPropertyValueQuery(query) && (
+            <EditorField
+              label="Stream Live Updates"
+              htmlFor="streaming"
+              tooltip="Push changed values to the panel over Grafana Live instead of waiting for the next refresh."
+            >
+              <Switch
+                id="streaming"
+                value={query.streaming}
+                onChange={() => onChange({ ...query, streaming: !query.streaming })}
+              />
+            </EditorField>
+          )}
           {shouldShowL4eOptions(query.queryType) && !query.propertyAliases?.length && showProp && (
             <EditorField
               label="Format L4E Anomaly Result"
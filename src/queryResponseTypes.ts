@@ -38,3 +38,19 @@ export interface DescribeAssetResult {
   hierarchies: string; // string
   properties: string; // string
 }
+
+// Mapped from DataFrame result
+export interface DescribeAssetPropertyResult {
+  asset_id: string; // string
+  asset_name: string; // string
+  id: string; // string
+  name: string; // string
+  alias: string; // string
+  dataType: string; // string
+  unit: string; // string
+  notification_state: string; // string
+  notification_topic: string; // string
+  composite_model_id: string; // string
+  composite_model_name: string; // string
+  path: string; // string
+}
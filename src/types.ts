@@ -12,6 +12,7 @@ export enum QueryType {
   ListAssociatedAssets = 'ListAssociatedAssets',
   ListAssetProperties = 'ListAssetProperties',
   DescribeAsset = 'DescribeAsset',
+  DescribeAssetProperty = 'DescribeAssetProperty',
   PropertyValue = 'PropertyValue',
   PropertyValueHistory = 'PropertyValueHistory',
   PropertyAggregate = 'PropertyAggregate',
@@ -32,6 +33,12 @@ export enum SiteWiseResponseFormat {
   TimeSeries = 'timeseries',
 }
 
+// Algorithms that downsample the raw values of PropertyValueHistory queries to maxDataPoints
+export enum SiteWiseDownsample {
+  LTTB = 'LTTB',
+  MinMax = 'MINMAX',
+}
+
 export enum SiteWiseTimeOrder {
   ASCENDING = 'ASCENDING',
   DESCENDING = 'DESCENDING',
@@ -85,10 +92,20 @@ export interface SitewiseQuery extends DataQuery {
   // One or more properties to fetch data
   propertyAliases?: string[];
   quality?: SiteWiseQuality;
+  // Several qualities to fetch, takes precedence over quality
+  qualities?: SiteWiseQuality[];
   resolution?: SiteWiseResolution;
   lastObservation?: boolean;
+  // How far before the time range lastObservation looks for the previous observation, such as 30d
+  lastObservationLookback?: string;
+  // Stamp the previous observation at the start of the time range, the value the property had then
+  lastObservationAtRangeStart?: boolean;
   flattenL4e?: boolean;
   maxPageAggregations?: number;
+  // Follow every next token in the backend, within the datasource's fetchAll budget
+  fetchAll?: boolean;
+  // Return the planned API calls and their estimated cost instead of the data
+  dryRun?: boolean;
   clientCache?: boolean;
 }
 
@@ -141,6 +158,12 @@ export interface AssetPropertyValueQuery extends SitewiseQuery {
   queryType: QueryType.PropertyValue;
 
   flattenL4e?: boolean;
+  // Subscribe to live updates of the latest values over Grafana Live
+  streaming?: boolean;
+}
+
+export function isAssetPropertyValueQuery(q?: SitewiseQuery): q is AssetPropertyValueQuery {
+  return q?.queryType === QueryType.PropertyValue;
 }
 
 /**
@@ -151,6 +174,8 @@ export interface AssetPropertyValueHistoryQuery extends SitewiseQuery {
 
   timeOrdering?: SiteWiseTimeOrder;
   flattenL4e?: boolean;
+  // Downsample the raw values to about maxDataPoints per series, unset returns every value
+  downsample?: SiteWiseDownsample;
 }
 
 export function isAssetPropertyValueHistoryQuery(q?: SitewiseQuery): q is AssetPropertyValueHistoryQuery {
@@ -176,8 +201,19 @@ export function isAssetPropertyAggregatesQuery(q?: SitewiseQuery): q is AssetPro
 /**
  * {@link https://docs.aws.amazon.com/iot-sitewise/latest/APIReference/API_GetInterpolatedAssetPropertyValues.html}
  */
+export enum InterpolationType {
+  LINEAR = 'LINEAR',
+  // Last observation carried forward, for step-like signals and string or boolean properties
+  LOCF = 'LOCF',
+}
+
 export interface AssetPropertyInterpolatedQuery extends SitewiseQuery {
   queryType: QueryType.PropertyInterpolated;
+
+  // Unset picks LOCF for string and boolean properties and LINEAR for the others
+  interpolationType?: InterpolationType;
+  // Window of data points around each interval for LINEAR interpolation, such as '1h'
+  intervalWindow?: string;
 }
 
 export function isAssetPropertyInterpolatedQuery(q?: SitewiseQuery): q is AssetPropertyInterpolatedQuery {
@@ -256,6 +292,28 @@ export interface SitewiseCustomMetadata {
   entryId?: string;
   resolution?: string;
   aggregates?: string[];
+  interpolation?: string;
+  // Quality of the frames of a query that is fetched once per quality
+  quality?: SiteWiseQuality;
+  // Algorithm the raw values were downsampled with, and the number of values per value kept
+  downsample?: SiteWiseDownsample;
+  downsampleRatio?: number;
+  // Set when the fetchAll budget was spent before the last page of the entry
+  truncated?: boolean;
+  entryStatus?: 'error' | 'skipped';
+  errorCode?: string;
+  summary?: SitewiseEntrySummary;
+}
+
+/**
+ * Outcome of the entries of a query, set on the first frame when any entry returned no data
+ */
+export interface SitewiseEntrySummary {
+  entries: number;
+  succeeded: number;
+  errored: number;
+  skipped: number;
+  partial: boolean;
 }
 
 /**
@@ -265,6 +323,27 @@ export interface SitewiseOptions extends AwsAuthDataSourceJsonData {
   // nothing for now
   edgeAuthMode?: string;
   edgeAuthUser?: string;
+  // Seconds before expiry that the edge credentials are renewed
+  edgeAuthRenewalMarginSec?: number;
+  // Budget for queries with fetchAll, a round follows the next tokens of every entry once
+  fetchAllMaxRounds?: number;
+  fetchAllMaxPoints?: number;
+  fetchAllTimeoutSec?: number;
+  // Number of queries of a request that run at the same time
+  maxConcurrentQueries?: number;
+  // Limits of every query, unset for no limit
+  maxEntriesPerQuery?: number;
+  maxRawRangeHours?: number;
+  maxPagesPerEntry?: number;
+  maxPointsPerResponse?: number;
+  queryTimeoutSec?: number;
+  // Lifetime and size of the metadata cache of the datasource
+  metadataCacheTTLSec?: number;
+  metadataCacheMaxEntries?: number;
+  // Requests per second for property values and metadata, and retries of throttled pages
+  dataPlaneRateLimit?: number;
+  controlPlaneRateLimit?: number;
+  throttleMaxRetries?: number;
 }
 
 export interface SitewiseSecureJsonData extends AwsAuthDataSourceSecureJsonData {
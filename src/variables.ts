@@ -61,6 +61,7 @@ export class SitewiseVariableSupport extends CustomVariableSupport<DataSource, S
       case QueryType.PropertyValueHistory:
       case QueryType.PropertyInterpolated:
       case QueryType.PropertyAggregate:
+      case QueryType.DescribeAssetProperty:
         return Boolean(query.assetIds?.length && query.propertyIds?.length);
       case QueryType.ListAssets:
         const listAssetsQuery = query as ListAssetsQuery;